@@ -0,0 +1,61 @@
+// Команда auditverify проходит цепочку auth_audit_log, сообщает о первом разрыве
+// и проверяет подписи контрольных точек. Код выхода 1, если проверка не прошла.
+//
+// Использует тот же .env, что и сервис: DATABASE_URL и открытые ключи аудита из
+// AUDIT_VERIFY_KEY_PATHS (или AUDIT_SIGNING_KEY_PATH). Ключи JWT не нужны.
+package main
+
+import (
+	"context"
+	"fmt"
+	"hh/config"
+	"hh/internal/repository"
+	"hh/internal/service"
+	"hh/internal/token"
+	"log"
+	"os"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Ошибка загрузки конфига", err)
+	}
+
+	db, err := repository.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatal("Ошибка подключения к БД", err)
+	}
+	defer db.Close()
+
+	verificationKeys, err := token.LoadAuditVerificationKeys(cfg)
+	if err != nil {
+		log.Fatal("Ошибка загрузки ключей аудита", err)
+	}
+	if len(verificationKeys) == 0 {
+		log.Fatal("Не заданы ключи аудита: AUDIT_VERIFY_KEY_PATHS или AUDIT_SIGNING_KEY_PATH")
+	}
+
+	auditService := service.NewAuditService(repository.NewAuditRepository(db), nil, verificationKeys)
+
+	result, err := auditService.Verify(context.Background())
+	if err != nil {
+		log.Fatal("Ошибка проверки аудита", err)
+	}
+
+	fmt.Printf("проверено строк: %d\n", result.Checked)
+	if result.Break != nil {
+		fmt.Printf("цепочка разорвана на seq %d (id %s): %s\n", result.Break.Seq, result.Break.ID, result.Break.Reason)
+	}
+
+	fmt.Printf("контрольных точек: %d\n", result.Checkpoints)
+	for _, checkpointErr := range result.CheckpointErrors {
+		fmt.Printf("контрольная точка %s\n", checkpointErr)
+	}
+
+	if !result.OK() {
+		os.Exit(1)
+	}
+
+	fmt.Println("OK")
+}
@@ -1,14 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"hh/config"
+	"hh/internal/geoip"
 	"hh/internal/handler"
+	"hh/internal/mail"
 	"hh/internal/middleware"
+	"hh/internal/password"
+	"hh/internal/proxyproto"
 	"hh/internal/repository"
 	"hh/internal/service"
 	"hh/internal/token"
+	"hh/internal/webhook"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "hh/docs"
 
@@ -30,13 +43,15 @@ import (
 // @description Введите токен с префиксом `Bearer`, например, «Bearer abcdef12345».
 
 func main() {
+	// по SIGINT и SIGTERM сервер дожидается начатых запросов, а затем и отправки писем
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	cfg, err := config.Load()
-	sigingKey := cfg.JWTSecret
-	httpClient := http.DefaultClient
-	httpClient = &http.Client{}
 	if err != nil {
 		log.Fatal("Ошибка загрузки конфига", err)
 	}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
 
 	db, err := repository.NewPostgresDB(cfg)
 	if err != nil {
@@ -44,28 +59,209 @@ func main() {
 	}
 	defer db.Close()
 
-	tokenRepo := repository.NewTokenRepository(db, httpClient, cfg)
+	tokenRepo := repository.NewTokenRepository(db)
+
+	keyRing, err := token.LoadKeyRing(cfg)
+	if err != nil {
+		log.Fatal("Ошибка загрузки ключей подписи", err)
+	}
 
-	tokenManager, err := token.NewManager(sigingKey)
+	tokenManager, err := token.NewManager(keyRing)
 	if err != nil {
 		log.Fatal("Ошибка инициализации tokenManager", err)
 	}
 
-	tokenService := service.NewTokenService(tokenManager, tokenRepo)
+	clientRepo := repository.NewClientRepository(db)
 
-	authMiddleware := middleware.NewMiddleware(tokenRepo)
+	bindingPolicy, err := service.ParseBindingPolicy(cfg.BindingPolicy)
+	if err != nil {
+		log.Fatal("Ошибка разбора политики привязки", err)
+	}
 
-	authHandler := handler.NewAuthHandler(tokenService)
+	var geoResolver geoip.Resolver
+	if cfg.GeoIPPath != "" {
+		geoTable, err := geoip.LoadCSV(cfg.GeoIPPath)
+		if err != nil {
+			log.Fatal("Ошибка загрузки GeoIP таблицы", err)
+		}
+		geoResolver = geoTable
+	}
+
+	tokenService := service.NewTokenService(tokenManager, tokenRepo, clientRepo, cfg, bindingPolicy, geoResolver)
+	clientService := service.NewClientService(clientRepo, cfg)
+
+	authMiddleware := middleware.NewMiddleware(tokenRepo, tokenManager)
+
+	var rateLimitStore middleware.RateLimitStore
+	switch cfg.RateLimitStore {
+	case "memory":
+		rateLimitStore = middleware.NewMemoryRateLimitStore()
+	case "postgres":
+		rateLimitStore = repository.NewRateLimitRepository(db)
+	default:
+		log.Fatal("Неизвестный RATE_LIMIT_STORE: ", cfg.RateLimitStore)
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore, tokenRepo, tokenManager, cfg)
+
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookService := service.NewWebhookService(webhookRepo)
+
+	if err := webhookService.EnsureLegacySubscription(context.Background(), cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookFormat); err != nil {
+		log.Fatal("Ошибка создания подписки WEBHOOK_URL", err)
+	}
+
+	dispatcher := webhook.NewDispatcher(webhookRepo, httpClient, cfg)
+	go dispatcher.Run(context.Background())
+
+	auditSigningKey, err := token.LoadAuditSigningKey(cfg)
+	if err != nil {
+		log.Fatal("Ошибка загрузки ключа подписи аудита", err)
+	}
+
+	auditService := service.NewAuditService(repository.NewAuditRepository(db), auditSigningKey, nil)
+	go auditService.RunChainWriter(context.Background(), cfg.AuditChainInterval)
+	go auditService.RunCheckpoints(context.Background(), cfg.AuditCheckpointInterval)
+
+	passwordHasher, err := password.NewHasher(password.Params{
+		Memory:      cfg.PasswordMemoryKiB,
+		Iterations:  cfg.PasswordIterations,
+		Parallelism: cfg.PasswordParallelism,
+	})
+	if err != nil {
+		log.Fatal("Ошибка настройки хэширования паролей", err)
+	}
+
+	var mailer mail.Mailer
+	switch cfg.Mailer {
+	case "console":
+		out := os.Stdout
+		if cfg.MailFile != "" {
+			out, err = os.OpenFile(cfg.MailFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+			if err != nil {
+				log.Fatal("Ошибка открытия MAIL_FILE", err)
+			}
+			defer out.Close()
+		}
+		mailer = mail.NewWriterMailer(out, cfg.MailFrom)
+	case "smtp":
+		mailer = mail.NewSMTPMailer(cfg.SMTPAddr, cfg.MailFrom, cfg.SMTPUsername, cfg.SMTPPassword)
+	case "memory":
+		mailer = mail.NewMemoryMailer()
+	default:
+		log.Fatal("Неизвестный MAILER: ", cfg.Mailer)
+	}
+
+	userService, err := service.NewUserService(repository.NewUserRepository(db), tokenService, tokenManager, passwordHasher, mailer, cfg)
+	if err != nil {
+		log.Fatal("Ошибка инициализации userService", err)
+	}
+
+	// очередь писем сброса останавливается после сервера: пока идут запросы, в неё ещё пишут
+	passwordResetsCtx, stopPasswordResets := context.WithCancel(context.Background())
+	passwordResetsDone := make(chan struct{})
+	go func() {
+		defer close(passwordResetsDone)
+		userService.RunPasswordResets(passwordResetsCtx)
+	}()
+
+	authHandler := handler.NewAuthHandler(tokenService, clientService, auditService)
+	userHandler := handler.NewUserHandler(userService, auditService)
+	adminHandler := handler.NewAdminHandler(tokenService, clientService, webhookService, auditService, userService)
+
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal("Ошибка разбора TRUSTED_PROXIES", err)
+	}
+	clientIPResolver, err := middleware.NewClientIPResolver(trustedProxies, cfg.ClientIPHeaders)
+	if err != nil {
+		log.Fatal("Ошибка разбора CLIENT_IP_HEADERS", err)
+	}
 
 	r := gin.Default()
+	// адрес клиента определяет clientIPResolver, собственному разбору заголовков gin не доверяем
+	if err := r.SetTrustedProxies(nil); err != nil {
+		log.Fatal(err)
+	}
+	r.Use(clientIPResolver.Handler())
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	r.GET("/tokens", authHandler.GenerateTokens)
-	r.POST("/refresh", authHandler.RefreshTokens)
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
+	r.POST("/tokens", rateLimiter.Tokens(), authHandler.ClientAuth(), rateLimiter.TokensClient(), authHandler.GenerateTokens)
+	r.POST("/refresh", rateLimiter.Refresh(), authHandler.RefreshTokens)
+	r.POST("/login", rateLimiter.Login(), userHandler.Login)
+	r.POST("/login/mfa", rateLimiter.MFA(), userHandler.LoginMFA)
+	r.POST("/register", rateLimiter.PerIP("register"), userHandler.Register)
+	r.POST("/verify-email", rateLimiter.PerIP("verify-email-confirm"), userHandler.VerifyEmail)
+	r.POST("/verify-email/resend", rateLimiter.PerIP("verify-email"), userHandler.ResendVerification)
+	r.POST("/password/forgot", rateLimiter.PerIP("password-forgot"), userHandler.ForgotPassword)
+	r.POST("/password/reset", rateLimiter.PerIP("password-reset"), userHandler.ResetPassword)
+	r.POST("/introspect", authHandler.Introspect)
+	r.POST("/revoke", authHandler.Revoke)
 
 	r.GET("/me", middleware.AuthMiddleware(authMiddleware), authHandler.GetGUID)
 	r.POST("/logout", middleware.AuthMiddleware(authMiddleware), authHandler.Logout)
 
-	r.Run(":8082")
+	sessions := r.Group("/sessions", middleware.AuthMiddleware(authMiddleware))
+	sessions.GET("", authHandler.ListSessions)
+	sessions.DELETE("/:id", authHandler.RevokeSession)
+	sessions.POST("/revoke-others", authHandler.RevokeOtherSessions)
+
+	mfa := r.Group("/mfa/totp", middleware.AuthMiddleware(authMiddleware))
+	mfa.POST("/enroll", userHandler.EnrollTOTP)
+	mfa.POST("/confirm", rateLimiter.MFA(), userHandler.ConfirmTOTP)
+	mfa.POST("/disable", rateLimiter.MFA(), userHandler.DisableTOTP)
+
+	r.POST("/webauthn/login/options", rateLimiter.PerIP("passkey-options"), userHandler.PasskeyLoginOptions)
+	r.POST("/webauthn/login", rateLimiter.PerIP("passkey-login"), userHandler.LoginPasskey)
+
+	webauthn := r.Group("/webauthn", middleware.AuthMiddleware(authMiddleware))
+	webauthn.POST("/register/options", userHandler.PasskeyRegistrationOptions)
+	webauthn.POST("/register", userHandler.RegisterPasskey)
+	webauthn.GET("/credentials", userHandler.ListPasskeys)
+	webauthn.DELETE("/credentials/:id", userHandler.DeletePasskey)
+
+	admin := r.Group("/admin", middleware.AdminMiddleware(cfg.AdminToken))
+	admin.POST("/clients", adminHandler.RegisterClient)
+	admin.GET("/webhooks/dead", adminHandler.ListDeadWebhooks)
+	admin.POST("/webhooks/:id/replay", adminHandler.ReplayWebhook)
+	admin.GET("/webhook-subscriptions", adminHandler.ListWebhookSubscriptions)
+	admin.POST("/webhook-subscriptions", adminHandler.CreateWebhookSubscription)
+	admin.GET("/webhook-subscriptions/:id", adminHandler.GetWebhookSubscription)
+	admin.PUT("/webhook-subscriptions/:id", adminHandler.UpdateWebhookSubscription)
+	admin.DELETE("/webhook-subscriptions/:id", adminHandler.DeleteWebhookSubscription)
+	admin.GET("/audit", adminHandler.ListAudit)
+	admin.POST("/users", adminHandler.CreateUser)
+	admin.GET("/users/:user_id/sessions", adminHandler.ListUserSessions)
+	admin.DELETE("/users/:user_id/sessions/:id", adminHandler.RevokeUserSession)
+
+	listener, err := net.Listen("tcp", ":8082")
+	if err != nil {
+		log.Fatal("Ошибка запуска сервера", err)
+	}
+	if cfg.ProxyProtocol {
+		listener = proxyproto.NewListener(listener, func(addr netip.Addr) bool {
+			return middleware.IsTrusted(trustedProxies, addr)
+		}, cfg.ProxyHeaderTimeout)
+	}
+
+	server := &http.Server{Handler: r}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Ошибка сервера", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Ошибка остановки сервера", err)
+	}
+
+	// письма сброса, принятые до остановки, досылаются
+	stopPasswordResets()
+	<-passwordResetsDone
 }
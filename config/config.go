@@ -1,15 +1,82 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DatabaseURL string
-	JWTSecret   string
-	WebhookURL  string
+	DatabaseURL             string
+	JWTSecret               string
+	JWTSigningAlg           string
+	JWTPrivateKeyPath       string
+	JWTKeyID                string
+	JWTPreviousSecrets      []PreviousKey
+	JWTPreviousKeyPaths     []PreviousKey
+	JWTKeyRetireAfter       time.Duration
+	AccessTokenTTL          time.Duration
+	RefreshIdleTimeout      time.Duration
+	SessionMaxLifetime      time.Duration
+	AdminToken              string
+	PublicURL               string
+	WebhookURL              string
+	WebhookSecret           string
+	WebhookFormat           string
+	WebhookMaxAttempts      int
+	WebhookRetryBase        time.Duration
+	WebhookRetryMax         time.Duration
+	WebhookPollInterval     time.Duration
+	AuditCheckpointInterval time.Duration
+	AuditChainInterval      time.Duration
+	AuditSigningKeyPath     string
+	AuditVerifyKeyPaths     []string
+	RateLimitStore          string
+	RateLimitIP             int
+	RateLimitUser           int
+	RateLimitClient         int
+	RefreshLockoutThreshold int
+	RefreshLockoutWindow    time.Duration
+	RefreshLockoutDuration  time.Duration
+	BindingPolicy           []string
+	GeoIPPath               string
+	TrustedProxies          []string
+	ClientIPHeaders         []string
+	ProxyProtocol           bool
+	ProxyHeaderTimeout      time.Duration
+	PasswordMemoryKiB       int
+	PasswordIterations      int
+	PasswordParallelism     int
+	Mailer                  string
+	MailFrom                string
+	MailFile                string
+	SMTPAddr                string
+	SMTPUsername            string
+	SMTPPassword            string
+	EmailVerificationTTL    time.Duration
+	EmailVerificationURL    string
+	PasswordResetTTL        time.Duration
+	PasswordResetURL        string
+	MFAEncryptionKey        string
+	MFAIssuer               string
+	MFATokenTTL             time.Duration
+	WebAuthnRPID            string
+	WebAuthnRPName          string
+	WebAuthnOrigins         []string
+	WebAuthnTimeout         time.Duration
+	WebAuthnReauthMaxAge    time.Duration
+}
+
+// PreviousKey — элемент JWT_PREVIOUS_SECRETS или JWT_PREVIOUS_KEY_PATHS: секрет или путь к ключу
+// и момент, после которого ключ больше не проверяет подписи.
+type PreviousKey struct {
+	Value    string
+	RetireAt time.Time
 }
 
 func Load() (*Config, error) {
@@ -17,9 +84,339 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	accessTokenTTL, err := getDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshIdleTimeout, err := getDuration("REFRESH_IDLE_TIMEOUT", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionMaxLifetime, err := getDuration("SESSION_MAX_LIFETIME", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	// ключ меняется только через конфиг, одинаково на всех инстансах: новый ключ становится
+	// JWT_PRIVATE_KEY_PATH или JWT_SECRET, старый переезжает в JWT_PREVIOUS_* с моментом ротации.
+	// Старый ключ должен проверять подпись, пока живы выпущенные им access токены
+	keyRetireAfter, err := getDuration("JWT_KEY_RETIRE_AFTER", accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtPreviousSecrets, err := getPreviousKeys("JWT_PREVIOUS_SECRETS", keyRetireAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtPreviousKeyPaths, err := getPreviousKeys("JWT_PREVIOUS_KEY_PATHS", keyRetireAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookMaxAttempts, err := getInt("WEBHOOK_MAX_ATTEMPTS", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookRetryBase, err := getDuration("WEBHOOK_RETRY_BASE", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookRetryMax, err := getDuration("WEBHOOK_RETRY_MAX", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookPollInterval, err := getPositiveDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	auditCheckpointInterval, err := getPositiveDuration("AUDIT_CHECKPOINT_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	// как часто очередь аудита переносится в цепочку
+	auditChainInterval, err := getPositiveDuration("AUDIT_CHAIN_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	// лимиты задаются числом запросов в минуту, 0 отключает лимит
+	rateLimitIP, err := getInt("RATE_LIMIT_IP_PER_MINUTE", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitUser, err := getInt("RATE_LIMIT_USER_PER_MINUTE", 20)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitClient, err := getInt("RATE_LIMIT_CLIENT_PER_MINUTE", 600)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshLockoutThreshold, err := getInt("REFRESH_LOCKOUT_THRESHOLD", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshLockoutWindow, err := getDuration("REFRESH_LOCKOUT_WINDOW", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshLockoutDuration, err := getDuration("REFRESH_LOCKOUT_DURATION", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyProtocol, err := getBool("PROXY_PROTOCOL", false)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyHeaderTimeout, err := getDuration("PROXY_HEADER_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	// без TRUSTED_PROXIES заголовки с адресом клиента игнорируются
+	clientIPHeaders := getList("CLIENT_IP_HEADERS")
+	if clientIPHeaders == nil {
+		clientIPHeaders = []string{"X-Forwarded-For"}
+	}
+
+	// параметры Argon2id; после изменения хэши пересчитываются при следующем входе
+	passwordMemoryKiB, err := getInt("PASSWORD_ARGON2_MEMORY_KIB", 64*1024)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordIterations, err := getInt("PASSWORD_ARGON2_ITERATIONS", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordParallelism, err := getInt("PASSWORD_ARGON2_PARALLELISM", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	emailVerificationTTL, err := getDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordResetTTL, err := getDuration("PASSWORD_RESET_TTL", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaTokenTTL, err := getDuration("MFA_TOKEN_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	webAuthnTimeout, err := getDuration("WEBAUTHN_TIMEOUT", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	// passkey регистрируется только в сессии, куда пользователь вошёл не раньше этого срока:
+	// украденный access или refresh токен не должен давать постоянный доступ
+	webAuthnReauthMaxAge, err := getDuration("WEBAUTHN_REAUTH_MAX_AGE", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	publicURL := getString("PUBLIC_URL", "http://localhost:8082")
+
+	parsedPublicURL, err := url.Parse(publicURL)
+	if err != nil {
+		return nil, fmt.Errorf("PUBLIC_URL: %w", err)
+	}
+
+	// RP ID — домен, к которому привязаны passkey; после смены старые ключи перестают подходить
+	webAuthnRPID := getString("WEBAUTHN_RP_ID", parsedPublicURL.Hostname())
+
+	webAuthnOrigins := getList("WEBAUTHN_ORIGINS")
+	if webAuthnOrigins == nil {
+		webAuthnOrigins = []string{parsedPublicURL.Scheme + "://" + parsedPublicURL.Host}
+	}
+
+	mfaIssuer := getString("MFA_ISSUER", "hh auth")
+
 	return &Config{
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		JWTSecret:   os.Getenv("JWT_SECRET"),
-		WebhookURL:  os.Getenv("WEBHOOK_URL"),
+		DatabaseURL:             os.Getenv("DATABASE_URL"),
+		JWTSecret:               os.Getenv("JWT_SECRET"),
+		JWTSigningAlg:           os.Getenv("JWT_SIGNING_ALG"),
+		JWTPrivateKeyPath:       os.Getenv("JWT_PRIVATE_KEY_PATH"),
+		JWTKeyID:                os.Getenv("JWT_KEY_ID"),
+		JWTPreviousSecrets:      jwtPreviousSecrets,
+		JWTPreviousKeyPaths:     jwtPreviousKeyPaths,
+		JWTKeyRetireAfter:       keyRetireAfter,
+		AccessTokenTTL:          accessTokenTTL,
+		RefreshIdleTimeout:      refreshIdleTimeout,
+		SessionMaxLifetime:      sessionMaxLifetime,
+		AdminToken:              os.Getenv("ADMIN_TOKEN"),
+		PublicURL:               publicURL,
+		WebhookURL:              os.Getenv("WEBHOOK_URL"),
+		WebhookSecret:           os.Getenv("WEBHOOK_SECRET"),
+		WebhookFormat:           getString("WEBHOOK_FORMAT", "legacy"),
+		WebhookMaxAttempts:      webhookMaxAttempts,
+		WebhookRetryBase:        webhookRetryBase,
+		WebhookRetryMax:         webhookRetryMax,
+		WebhookPollInterval:     webhookPollInterval,
+		AuditCheckpointInterval: auditCheckpointInterval,
+		AuditChainInterval:      auditChainInterval,
+		// контрольные точки аудита подписываются своим асимметричным ключом, а не ключами JWT:
+		// те ротируются и выводятся, а старые точки должны проверяться годами. Ключ обязателен,
+		// без него сервис не стартует
+		AuditSigningKeyPath:     os.Getenv("AUDIT_SIGNING_KEY_PATH"),
+		AuditVerifyKeyPaths:     getList("AUDIT_VERIFY_KEY_PATHS"),
+		RateLimitStore:          getString("RATE_LIMIT_STORE", "memory"),
+		RateLimitIP:             rateLimitIP,
+		RateLimitUser:           rateLimitUser,
+		RateLimitClient:         rateLimitClient,
+		RefreshLockoutThreshold: refreshLockoutThreshold,
+		RefreshLockoutWindow:    refreshLockoutWindow,
+		RefreshLockoutDuration:  refreshLockoutDuration,
+		BindingPolicy:           getList("BINDING_POLICY"),
+		GeoIPPath:               os.Getenv("GEOIP_CSV_PATH"),
+		TrustedProxies:          getList("TRUSTED_PROXIES"),
+		ClientIPHeaders:         clientIPHeaders,
+		ProxyProtocol:           proxyProtocol,
+		ProxyHeaderTimeout:      proxyHeaderTimeout,
+		PasswordMemoryKiB:       passwordMemoryKiB,
+		PasswordIterations:      passwordIterations,
+		PasswordParallelism:     passwordParallelism,
+		Mailer:                  getString("MAILER", "console"),
+		MailFrom:                getString("MAIL_FROM", "no-reply@localhost"),
+		MailFile:                os.Getenv("MAIL_FILE"),
+		SMTPAddr:                os.Getenv("SMTP_ADDR"),
+		SMTPUsername:            os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:            os.Getenv("SMTP_PASSWORD"),
+		EmailVerificationTTL:    emailVerificationTTL,
+		// страница фронтенда, которая принимает token из query и отправляет его в POST /verify-email:
+		// GET по ссылке ничего не меняет, её могут открыть сканеры ссылок в почте
+		EmailVerificationURL: getString("EMAIL_VERIFICATION_URL", publicURL+"/verify-email"),
+		PasswordResetTTL:     passwordResetTTL,
+		// страница фронтенда, которая принимает token из query и отправляет его в POST /password/reset
+		PasswordResetURL: getString("PASSWORD_RESET_URL", publicURL+"/password/reset"),
+		// ключ AES-256 в base64 для TOTP-секретов; без него подключить TOTP нельзя
+		MFAEncryptionKey:     os.Getenv("MFA_ENCRYPTION_KEY"),
+		MFAIssuer:            mfaIssuer,
+		MFATokenTTL:          mfaTokenTTL,
+		WebAuthnRPID:         webAuthnRPID,
+		WebAuthnRPName:       getString("WEBAUTHN_RP_NAME", mfaIssuer),
+		WebAuthnOrigins:      webAuthnOrigins,
+		WebAuthnTimeout:      webAuthnTimeout,
+		WebAuthnReauthMaxAge: webAuthnReauthMaxAge,
 	}, nil
 }
+
+func getString(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return def
+}
+
+func getInt(key string, def int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return n, nil
+}
+
+func getBool(key string, def bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return b, nil
+}
+
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return d, nil
+}
+
+// getPositiveDuration — getDuration для интервалов тикеров: time.NewTicker паникует на нуле.
+func getPositiveDuration(key string, def time.Duration) (time.Duration, error) {
+	d, err := getDuration(key, def)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s: интервал должен быть больше нуля", key)
+	}
+
+	return d, nil
+}
+
+func getList(key string) []string {
+	var items []string
+
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// getPreviousKeys разбирает список "значение@момент ротации в RFC 3339". Срок вывода ключа
+// считается от момента ротации, а не от старта процесса, поэтому перезапуск его не продлевает.
+func getPreviousKeys(key string, retireAfter time.Duration) ([]PreviousKey, error) {
+	var keys []PreviousKey
+
+	for _, item := range getList(key) {
+		i := strings.LastIndex(item, "@")
+		if i <= 0 {
+			return nil, fmt.Errorf("%s: у ключа не указан момент ротации, ожидается значение@%s", key, time.RFC3339)
+		}
+
+		rotatedAt, err := time.Parse(time.RFC3339, item[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s: момент ротации: %w", key, err)
+		}
+
+		keys = append(keys, PreviousKey{Value: item[:i], RetireAt: rotatedAt.Add(retireAfter)})
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetPreviousKeys(t *testing.T) {
+	rotatedAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    []PreviousKey
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"secret", "old@2026-10-18T12:00:00Z", []PreviousKey{{Value: "old", RetireAt: rotatedAt.Add(time.Hour)}}, false},
+		// секрет может содержать @, момент ротации — всё после последнего
+		{"secret with at sign", "p@ss@2026-10-18T15:00:00+03:00", []PreviousKey{{Value: "p@ss", RetireAt: rotatedAt.Add(time.Hour)}}, false},
+		{"several keys", "/keys/a.pem@2026-10-18T12:00:00Z, /keys/b.pem@2026-10-18T11:00:00Z", []PreviousKey{
+			{Value: "/keys/a.pem", RetireAt: rotatedAt.Add(time.Hour)},
+			{Value: "/keys/b.pem", RetireAt: rotatedAt},
+		}, false},
+		{"no rotation time", "old", nil, true},
+		{"empty value", "@2026-10-18T12:00:00Z", nil, true},
+		{"bad rotation time", "old@yesterday", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JWT_PREVIOUS_SECRETS", tt.value)
+
+			got, err := getPreviousKeys("JWT_PREVIOUS_SECRETS", time.Hour)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getPreviousKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("getPreviousKeys() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Value != tt.want[i].Value || !got[i].RetireAt.Equal(tt.want[i].RetireAt) {
+					t.Errorf("getPreviousKeys()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Package geoip определяет автономную систему и страну IP-адреса по локальной таблице диапазонов.
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Info struct {
+	ASN     uint32
+	Country string
+}
+
+type Resolver interface {
+	Lookup(ip string) (Info, bool)
+}
+
+type ipRange struct {
+	first netip.Addr
+	last  netip.Addr
+	info  Info
+}
+
+// Table — таблица непересекающихся диапазонов, поиск бинарный.
+type Table struct {
+	ranges []ipRange
+}
+
+// LoadCSV читает файл со строками "cidr,asn,country", например "5.255.255.0/24,AS13238,RU".
+// ASN можно указывать с префиксом AS или без, пустые поля допустимы, строки с # пропускаются.
+// Диапазоны не должны пересекаться: так устроены выгрузки вроде ip2asn.
+func LoadCSV(path string) (*Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	table := &Table{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		r, err := parseRange(record)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		table.ranges = append(table.ranges, r)
+	}
+
+	sort.Slice(table.ranges, func(i, j int) bool {
+		return table.ranges[i].first.Less(table.ranges[j].first)
+	})
+
+	for i := 1; i < len(table.ranges); i++ {
+		if table.ranges[i].first.Compare(table.ranges[i-1].last) <= 0 {
+			return nil, fmt.Errorf("%s: диапазоны %s и %s пересекаются", path, table.ranges[i-1].first, table.ranges[i].first)
+		}
+	}
+
+	return table, nil
+}
+
+func parseRange(record []string) (ipRange, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+	if err != nil {
+		return ipRange{}, err
+	}
+	prefix = prefix.Masked()
+
+	var info Info
+	if asn := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(record[1])), "AS"); asn != "" {
+		n, err := strconv.ParseUint(asn, 10, 32)
+		if err != nil {
+			return ipRange{}, fmt.Errorf("невалидный ASN %q", record[1])
+		}
+		info.ASN = uint32(n)
+	}
+	info.Country = strings.ToUpper(strings.TrimSpace(record[2]))
+
+	return ipRange{first: prefix.Addr(), last: lastAddr(prefix), info: info}, nil
+}
+
+// lastAddr — последний адрес префикса: все биты хоста выставлены в 1.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 0x80 >> (bit % 8)
+	}
+
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+func (t *Table) Lookup(ip string) (Info, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Info{}, false
+	}
+	addr = addr.Unmap()
+
+	i := sort.Search(len(t.ranges), func(i int) bool {
+		return addr.Less(t.ranges[i].first)
+	})
+	if i == 0 {
+		return Info{}, false
+	}
+
+	r := t.ranges[i-1]
+	if addr.Compare(r.last) > 0 {
+		return Info{}, false
+	}
+
+	return r.info, true
+}
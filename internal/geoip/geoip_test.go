@@ -0,0 +1,79 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCSV(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "geoip.csv")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadCSVAndLookup(t *testing.T) {
+	table, err := LoadCSV(writeCSV(t, `# cidr,asn,country
+5.255.255.0/24,AS13238,ru
+77.88.0.0/18, 13238, RU
+2a02:6b8::/32,AS13238,RU
+8.8.8.0/24,,US
+1.1.1.0/24,AS13335,
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip     string
+		want   Info
+		wantOK bool
+	}{
+		{"5.255.255.0", Info{ASN: 13238, Country: "RU"}, true},
+		{"5.255.255.255", Info{ASN: 13238, Country: "RU"}, true},
+		{"5.255.254.255", Info{}, false},
+		{"77.88.63.1", Info{ASN: 13238, Country: "RU"}, true},
+		{"77.88.64.1", Info{}, false},
+		{"::ffff:77.88.1.1", Info{ASN: 13238, Country: "RU"}, true},
+		{"2a02:6b8:ffff::1", Info{ASN: 13238, Country: "RU"}, true},
+		{"2a02:6b9::1", Info{}, false},
+		{"8.8.8.8", Info{Country: "US"}, true},
+		{"1.1.1.1", Info{ASN: 13335}, true},
+		{"0.0.0.1", Info{}, false},
+		{"not-an-ip", Info{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := table.Lookup(tt.ip)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("Lookup(%q) = %+v, %v, want %+v, %v", tt.ip, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLoadCSVRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"bad cidr", "5.255.255.0/33,AS13238,RU\n"},
+		{"bad asn", "5.255.255.0/24,ASX,RU\n"},
+		{"missing field", "5.255.255.0/24,AS13238\n"},
+		{"overlapping ranges", "77.88.0.0/18,13238,RU\n77.88.1.0/24,13238,RU\n"},
+	}
+
+	for _, tt := range tests {
+		if _, err := LoadCSV(writeCSV(t, tt.data)); err == nil {
+			t.Errorf("%s: LoadCSV() accepted invalid table", tt.name)
+		}
+	}
+
+	if _, err := LoadCSV(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("LoadCSV() accepted a missing file")
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"hh/internal/model"
+	"hh/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RegisterClientResponse struct {
+	Client       *model.Client `json:"client"`
+	ClientSecret string        `json:"client_secret,omitempty"`
+}
+
+type AdminHandler struct {
+	authService    *service.TokenService
+	clientService  *service.ClientService
+	webhookService *service.WebhookService
+	auditService   *service.AuditService
+	userService    *service.UserService
+}
+
+func NewAdminHandler(authService *service.TokenService, clientService *service.ClientService, webhookService *service.WebhookService, auditService *service.AuditService, userService *service.UserService) *AdminHandler {
+	return &AdminHandler{
+		authService:    authService,
+		clientService:  clientService,
+		webhookService: webhookService,
+		auditService:   auditService,
+		userService:    userService,
+	}
+}
+
+// ListUserSessions godoc
+// @Summary List user sessions (support)
+// @Description Активные сессии произвольного пользователя для службы поддержки
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param user_id path string true "User ID (GUID)"
+// @Success 200 {object} SessionsResponse "Активные сессии"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/users/{user_id}/sessions [get]
+func (h *AdminHandler) ListUserSessions(c *gin.Context) {
+	sessions, err := h.authService.ListSessions(c.Request.Context(), c.Param("user_id"), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SessionsResponse{Sessions: sessions})
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke user session (support)
+// @Description Отзывает сессию произвольного пользователя
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param user_id path string true "User ID (GUID)"
+// @Param id path string true "Session ID"
+// @Success 200 {object} LogoutResponse "Сессия отозвана"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 404 {object} ErrorResponse "Сессия не найдена"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/users/{user_id}/sessions/{id} [delete]
+func (h *AdminHandler) RevokeUserSession(c *gin.Context) {
+	err := h.authService.RevokeSession(c.Request.Context(), c.Param("user_id"), c.Param("id"), model.ReasonAdminRequest, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// RegisterClient godoc
+// @Summary Register client
+// @Description Регистрирует клиента, которому можно выпускать токены. Если public_key не передан, генерируется client_secret: он возвращается один раз
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param request body model.ClientRegistration true "Параметры клиента"
+// @Success 201 {object} RegisterClientResponse "Клиент создан"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/clients [post]
+func (h *AdminHandler) RegisterClient(c *gin.Context) {
+	var request model.ClientRegistration
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, secret, err := h.clientService.Register(c.Request.Context(), request)
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": oauthErr.Description})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register client"})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), adminAudit(c, model.AuditActionClientRegistration, client.ID, "created"))
+
+	c.JSON(http.StatusCreated, RegisterClientResponse{Client: client, ClientSecret: secret})
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"hh/internal/model"
+	"hh/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AuditResponse struct {
+	Entries []model.AuditEntry `json:"entries"`
+}
+
+// requestAudit заполняет IP и User-Agent записи аудита из запроса.
+func requestAudit(c *gin.Context, action, actorType, actorID, outcome string) model.AuditEntry {
+	return model.AuditEntry{
+		Action:    action,
+		ActorType: actorType,
+		ActorID:   actorID,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Outcome:   outcome,
+	}
+}
+
+// adminAudit — запись об успешном действии администратора над target.
+func adminAudit(c *gin.Context, action, target, reason string) model.AuditEntry {
+	entry := requestAudit(c, action, model.AuditActorAdmin, "", model.AuditOutcomeSuccess)
+	entry.Target = target
+	entry.Reason = reason
+
+	return entry
+}
+
+// auditReason — код ошибки OAuth или server_error для остальных ошибок.
+func auditReason(err error) string {
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr.Code
+	}
+
+	return "server_error"
+}
+
+func subjectID(userID string) *uuid.UUID {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+
+	return &id
+}
+
+// ListAudit godoc
+// @Summary Query authentication audit log
+// @Description Записи auth_audit_log от новых к старым. Все фильтры необязательные, from/to в RFC 3339
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param action query string false "Действие (login, refresh, client_authentication, ...)"
+// @Param actor_type query string false "user, client, admin или anonymous"
+// @Param actor_id query string false "ID инициатора"
+// @Param subject_id query string false "User ID (GUID)"
+// @Param session_id query string false "Session ID"
+// @Param client_id query string false "Client ID"
+// @Param outcome query string false "success или failure"
+// @Param from query string false "Не раньше (RFC 3339)"
+// @Param to query string false "Раньше (RFC 3339)"
+// @Param limit query int false "Размер страницы (до 100)" default(100)
+// @Param offset query int false "Смещение" default(0)
+// @Success 200 {object} AuditResponse "Записи аудита"
+// @Failure 400 {object} ErrorResponse "Неверный фильтр"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/audit [get]
+func (h *AdminHandler) ListAudit(c *gin.Context) {
+	var query model.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.auditService.List(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuditResponse{Entries: entries})
+}
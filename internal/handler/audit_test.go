@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/service"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// В аудит попадает только код ошибки: текст внутренних ошибок может содержать лишнее.
+func TestAuditReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"oauth error", &service.OAuthError{Code: "invalid_client", Description: "клиент не найден"}, "invalid_client"},
+		{"wrapped oauth error", fmt.Errorf("authenticate: %w", &service.OAuthError{Code: "invalid_scope"}), "invalid_scope"},
+		{"internal error", errors.New("pq: connection refused"), "server_error"},
+	}
+
+	for _, tt := range tests {
+		if got := auditReason(tt.err); got != tt.want {
+			t.Errorf("%s: auditReason() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubjectID(t *testing.T) {
+	id := uuid.New()
+
+	if got := subjectID(id.String()); got == nil || *got != id {
+		t.Errorf("subjectID(%q) = %v", id, got)
+	}
+	for _, userID := range []string{"", "user", "billing"} {
+		if got := subjectID(userID); got != nil {
+			t.Errorf("subjectID(%q) = %v, want nil", userID, got)
+		}
+	}
+}
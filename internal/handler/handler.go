@@ -2,8 +2,10 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"hh/internal/model"
 	"hh/internal/service"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -18,59 +20,93 @@ type LogoutResponse struct {
 	Message string `json:"message" example:"описание ответа"`
 }
 
+type MessageResponse struct {
+	Message string `json:"message" example:"описание ответа"`
+}
+
 type GetGUIDResponse struct {
 	UserID string `json:"user_id" example:"описание ответа"`
 }
 
 type AuthHandler struct {
-	authService *service.TokenService
+	authService   *service.TokenService
+	clientService *service.ClientService
+	auditService  *service.AuditService
 }
 
-func NewAuthHandler(authService *service.TokenService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *service.TokenService, clientService *service.ClientService, auditService *service.AuditService) *AuthHandler {
+	return &AuthHandler{authService: authService, clientService: clientService, auditService: auditService}
 }
 
 // GenerateTokens godoc
 // @Summary Generate access and refresh tokens
-// @Description Создаёт пару токенов для пользователя по user_id
+// @Description Создаёт пару токенов для пользователя по user_id. Клиент аутентифицируется через client_secret_basic, client_secret_post или private_key_jwt и должен иметь право выпускать токены для этого пользователя
 // @Tags auth
-// @Accept json
+// @Accept x-www-form-urlencoded
 // @Produce json
-// @Param user_id query string true "User ID (GUID)" default(626e470d-47b5-4be5-ab27-92b06167ac63)
+// @Param user_id formData string true "User ID (GUID)" default(626e470d-47b5-4be5-ab27-92b06167ac63)
+// @Param scope formData string false "Запрашиваемый scope через пробел"
+// @Param client_id formData string false "Client ID (client_secret_post)"
+// @Param client_secret formData string false "Client secret (client_secret_post)"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT клиента (private_key_jwt) с iat и сроком жизни не больше 5 минут"
 // @Success 200 {object} model.TokenPair "Успешный ответ с токенами"
-// @Failure 400 {object} ErrorResponse "user_id отсутствует в запросе"
-// @Failure 500 {object} ErrorResponse "внутренняя ошибка сервера"
-// @Router /tokens [get]
+// @Failure 400 {object} OAuthErrorResponse "invalid_request, unauthorized_client или invalid_scope"
+// @Failure 401 {object} OAuthErrorResponse "invalid_client"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} OAuthErrorResponse "внутренняя ошибка сервера"
+// @Router /tokens [post]
 func (h *AuthHandler) GenerateTokens(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+	// клиента аутентифицирует ClientAuth, до лимитов по клиенту
+	client := c.MustGet("client").(*model.Client)
+
+	var request model.UserTokenRequest
+	if err := c.ShouldBind(&request); err != nil {
+		writeOAuthError(c, &service.OAuthError{Code: "invalid_request", Description: "user_id is required"})
 		return
 	}
 
-	userAgent := c.GetHeader("User-Agent")
-	ip := c.ClientIP()
+	scope, err := h.clientService.AuthorizeSubject(client, request.UserID, request.Scope)
+	if err != nil {
+		entry := requestAudit(c, model.AuditActionSubjectAuthorization, model.AuditActorClient, client.ID, model.AuditOutcomeFailure)
+		entry.SubjectID = subjectID(request.UserID)
+		entry.ClientID = client.ID
+		entry.Reason = auditReason(err)
+		h.auditService.Record(c.Request.Context(), entry)
 
-	sessionID := uuid.New().String()
+		writeOAuthError(c, err)
+		return
+	}
 
-	tokenPair, err := h.authService.GetTokens(context.Background(), userID, userAgent, sessionID, ip)
+	tokenPair, err := h.authService.GetTokens(c.Request.Context(), model.SessionParams{
+		UserID:    request.UserID,
+		SessionID: uuid.New().String(),
+		UserAgent: c.GetHeader("User-Agent"),
+		IPAddress: c.ClientIP(),
+		ClientID:  client.ID,
+		Scope:     scope,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error:": err.Error()})
+		writeOAuthError(c, err)
+		return
 	}
 
+	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusOK, tokenPair)
 }
 
 // RefreshTokens godoc
 // @Summary Refresh access and refresh tokens
-// @Description Обновляет пару токенов с проверкой старых refresh и access токенов
+// @Description Обновляет пару токенов по refresh токену. access_token нужен только для refresh token старого формата и должен относиться к той же сессии
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body model.RefreshRequest true "Токены для обновления"
 // @Success 200 {object} model.RefreshRequest "Новые токены"
-// @Failure 401 {object} ErrorResponse "Невалидный или отозванный токен"
+// @Failure 401 {object} ErrorResponse "Неизвестный refresh token или неверный verifier"
+// @Failure 403 {object} ErrorResponse "Refresh token уже использован или истёк, сессия отозвана или требует повторного входа"
 // @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов или временная блокировка после неудачных попыток, см. Retry-After"
 // @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
 // @Router /refresh [post]
 func (h *AuthHandler) RefreshTokens(c *gin.Context) {
@@ -86,13 +122,37 @@ func (h *AuthHandler) RefreshTokens(c *gin.Context) {
 
 	tokenPair, err := h.authService.RefreshTokens(context.Background(), request.AccessToken, request.RefreshToken, userAgent, ip)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		status := refreshErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			// текст внутренней ошибки остаётся в логе, клиенту он не нужен
+			log.Println("refresh:", err)
+			c.JSON(status, gin.H{"error": "failed to refresh tokens"})
+			return
+		}
+
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, tokenPair)
 }
 
+// refreshErrorStatus отделяет неверные учётные данные (401, их считает блокировка /refresh)
+// от настоящего, но уже непригодного токена (403) и внутренних ошибок (500).
+func refreshErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidRefreshToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrRefreshTokenUsed),
+		errors.Is(err, service.ErrRefreshTokenExpired),
+		errors.Is(err, service.ErrSessionRevoked),
+		errors.Is(err, service.ErrReauthenticationRequired):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // GetGUID godoc
 // @Summary Get current user GUID
 // @Description Возвращает GUID текущего авторизованного пользователя
@@ -124,7 +184,7 @@ func (h *AuthHandler) GetGUID(c *gin.Context) {
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	err := h.authService.Logout(c.Request.Context(), userID)
+	err := h.authService.Logout(c.Request.Context(), userID, c.GetString("session_id"), c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to deauthorize"})
 		return
@@ -132,3 +192,15 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	c.JSON(200, gin.H{"message": "logout successful"})
 }
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Публичные ключи для проверки подписи access токенов
+// @Tags auth
+// @Produce json
+// @Success 200 {object} token.JWKS "Набор публичных ключей"
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"hh/internal/model"
+	"hh/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginMFA godoc
+// @Summary Complete login with second factor
+// @Description Второй шаг входа: обменивает mfa_token из POST /login и код TOTP или recovery code на пару токенов. Каждый код и mfa_token принимаются один раз, после пяти неверных кодов mfa_token сгорает
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.MFALoginRequest true "mfa_token и код"
+// @Success 200 {object} model.TokenPair "Токены новой сессии"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "mfa_token недействителен или неверный код"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов или временная блокировка после неверных кодов, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "TOTP не настроен на сервере"
+// @Router /login/mfa [post]
+func (h *UserHandler) LoginMFA(c *gin.Context) {
+	var request model.MFALoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenPair, err := h.userService.CompleteMFALogin(c.Request.Context(), request.MFAToken, request.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrInvalidMFAToken) || errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrTOTPNotEnabled) {
+		entry := requestAudit(c, model.AuditActionMFALogin, model.AuditActorAnonymous, "", model.AuditOutcomeFailure)
+		entry.Reason = model.ReasonInvalidMFACode
+		h.auditService.Record(c.Request.Context(), entry)
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrMFADisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, tokenPair)
+}
+
+// EnrollTOTP godoc
+// @Summary Enroll TOTP
+// @Description Создаёт секрет TOTP и возвращает otpauth:// URI для QR-кода. Второй фактор включается только после POST /mfa/totp/confirm, до этого секрет можно перевыпустить
+// @Tags mfa
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Success 200 {object} model.TOTPEnrollment "Секрет и otpauth:// URI"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 404 {object} ErrorResponse "Сессия принадлежит не пользователю с паролем"
+// @Failure 409 {object} ErrorResponse "TOTP уже подключён"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "TOTP не настроен на сервере"
+// @Security ApiKeyAuth
+// @Router /mfa/totp/enroll [post]
+func (h *UserHandler) EnrollTOTP(c *gin.Context) {
+	enrollment, err := h.userService.EnrollTOTP(c.Request.Context(), c.GetString("user_id"))
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrTOTPAlreadyEnabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrMFADisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enroll totp"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm TOTP
+// @Description Включает TOTP по первому коду из приложения и возвращает одноразовые recovery codes. Коды показываются только в этом ответе
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Param request body model.MFACodeRequest true "Код из приложения"
+// @Success 200 {object} model.RecoveryCodesResponse "TOTP подключён"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 403 {object} ErrorResponse "Неверный код"
+// @Failure 404 {object} ErrorResponse "Сессия принадлежит не пользователю с паролем"
+// @Failure 409 {object} ErrorResponse "TOTP уже подключён или не был начат через enroll"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов или временная блокировка после неверных кодов, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "TOTP не настроен на сервере"
+// @Security ApiKeyAuth
+// @Router /mfa/totp/confirm [post]
+func (h *UserHandler) ConfirmTOTP(c *gin.Context) {
+	var request model.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.userService.ConfirmTOTP(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"), request.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if !h.mfaError(c, err) {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary Disable TOTP
+// @Description Отключает TOTP и удаляет recovery codes. Нужен действующий код из приложения или recovery code
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Param request body model.MFACodeRequest true "Код из приложения или recovery code"
+// @Success 200 {object} MessageResponse "TOTP отключён"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 403 {object} ErrorResponse "Неверный код"
+// @Failure 404 {object} ErrorResponse "Сессия принадлежит не пользователю с паролем"
+// @Failure 409 {object} ErrorResponse "TOTP не подключён"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов или временная блокировка после неверных кодов, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "TOTP не настроен на сервере"
+// @Security ApiKeyAuth
+// @Router /mfa/totp/disable [post]
+func (h *UserHandler) DisableTOTP(c *gin.Context) {
+	var request model.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.userService.DisableTOTP(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"), request.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if !h.mfaError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "TOTP отключён"})
+}
+
+// mfaError отвечает на ошибку confirm и disable и возвращает true, если ошибки нет.
+// Неверный код — 403, а не 401: access token при этом валиден.
+func (h *UserHandler) mfaError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrInvalidMFACode):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled), errors.Is(err, service.ErrTOTPNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrMFADisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update totp"})
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"hh/internal/model"
+	"hh/internal/service"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error" example:"invalid_client"`
+	ErrorDescription string `json:"error_description,omitempty" example:"неверные учётные данные клиента"`
+}
+
+func writeOAuthError(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, OAuthErrorResponse{Error: "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+		if _, _, ok := c.Request.BasicAuth(); ok {
+			c.Header("WWW-Authenticate", `Basic realm="auth-service"`)
+		}
+	}
+
+	c.JSON(status, OAuthErrorResponse{Error: oauthErr.Code, ErrorDescription: oauthErr.Description})
+}
+
+func clientCredentials(c *gin.Context) model.ClientCredentials {
+	creds := model.ClientCredentials{
+		ClientID:            c.PostForm("client_id"),
+		ClientSecret:        c.PostForm("client_secret"),
+		ClientAssertionType: c.PostForm("client_assertion_type"),
+		ClientAssertion:     c.PostForm("client_assertion"),
+	}
+
+	// client_secret_basic: RFC 6749 2.3.1 требует form-urlencoding логина и пароля
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		if decoded, err := url.QueryUnescape(id); err == nil {
+			creds.ClientID = decoded
+		}
+		if decoded, err := url.QueryUnescape(secret); err == nil {
+			creds.ClientSecret = decoded
+		}
+	}
+
+	return creds
+}
+
+func (h *AuthHandler) authenticateClient(c *gin.Context) (*model.Client, bool) {
+	creds := clientCredentials(c)
+
+	client, err := h.clientService.Authenticate(c.Request.Context(), creds)
+	if err != nil {
+		entry := requestAudit(c, model.AuditActionClientAuthentication, model.AuditActorAnonymous, creds.ClientID, model.AuditOutcomeFailure)
+		entry.ClientID = creds.ClientID
+		entry.Reason = auditReason(err)
+		h.auditService.Record(c.Request.Context(), entry)
+
+		writeOAuthError(c, err)
+		return nil, false
+	}
+
+	return client, true
+}
+
+// ClientAuth аутентифицирует клиента до хендлера, чтобы лимиты по клиенту считались
+// по проверенному client_id. Клиент кладётся в контекст как "client" и "client_id".
+func (h *AuthHandler) ClientAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client, ok := h.authenticateClient(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		c.Set("client", client)
+		c.Set("client_id", client.ID)
+		c.Next()
+	}
+}
+
+// Introspect godoc
+// @Summary Token introspection (RFC 7662)
+// @Description Проверяет access или refresh токен. Вызывающий клиент должен аутентифицироваться. Для неактивного токена возвращается только active=false
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Проверяемый токен"
+// @Param token_type_hint formData string false "access_token или refresh_token"
+// @Param client_id formData string false "Client ID (client_secret_post)"
+// @Param client_secret formData string false "Client secret (client_secret_post)"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT клиента (private_key_jwt) с iat и сроком жизни не больше 5 минут"
+// @Success 200 {object} model.IntrospectionResponse "Состояние токена"
+// @Failure 400 {object} OAuthErrorResponse "invalid_request"
+// @Failure 401 {object} OAuthErrorResponse "invalid_client"
+// @Failure 500 {object} OAuthErrorResponse "внутренняя ошибка сервера"
+// @Router /introspect [post]
+func (h *AuthHandler) Introspect(c *gin.Context) {
+	if _, ok := h.authenticateClient(c); !ok {
+		return
+	}
+
+	tokenString := c.PostForm("token")
+	if tokenString == "" {
+		writeOAuthError(c, &service.OAuthError{Code: "invalid_request", Description: "token is required"})
+		return
+	}
+
+	response, err := h.authService.Introspect(c.Request.Context(), tokenString, c.PostForm("token_type_hint"))
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc
+// @Summary Token revocation (RFC 7009)
+// @Description Отзывает сессию, к которой относится refresh или access токен. Ответ 200 возвращается и для неизвестных или уже отозванных токенов
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Отзываемый токен"
+// @Param token_type_hint formData string false "access_token или refresh_token"
+// @Param client_id formData string false "Client ID (client_secret_post)"
+// @Param client_secret formData string false "Client secret (client_secret_post)"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+// @Param client_assertion formData string false "JWT клиента (private_key_jwt) с iat и сроком жизни не больше 5 минут"
+// @Success 200 "Токен отозван или уже недействителен"
+// @Failure 400 {object} OAuthErrorResponse "invalid_request"
+// @Failure 401 {object} OAuthErrorResponse "invalid_client"
+// @Failure 500 {object} OAuthErrorResponse "внутренняя ошибка сервера"
+// @Router /revoke [post]
+func (h *AuthHandler) Revoke(c *gin.Context) {
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	tokenString := c.PostForm("token")
+	if tokenString == "" {
+		writeOAuthError(c, &service.OAuthError{Code: "invalid_request", Description: "token is required"})
+		return
+	}
+
+	if err := h.authService.Revoke(c.Request.Context(), client, tokenString, c.PostForm("token_type_hint"), c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
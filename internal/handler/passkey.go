@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"errors"
+	"hh/internal/model"
+	"hh/internal/service"
+	"hh/internal/webauthn"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasskeyRegistrationOptionsResponse struct {
+	PublicKey webauthn.CreationOptions `json:"publicKey"`
+	// ChallengeToken возвращается в POST /webauthn/register вместе с ответом аутентификатора
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type PasskeyLoginOptionsResponse struct {
+	PublicKey webauthn.RequestOptions `json:"publicKey"`
+	// ChallengeToken возвращается в POST /webauthn/login вместе с ответом аутентификатора
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type PasskeyRegistrationRequest struct {
+	ChallengeToken string                         `json:"challenge_token" binding:"required"`
+	Name           string                         `json:"name" binding:"max=64" example:"MacBook"`
+	Credential     webauthn.AttestationCredential `json:"credential" binding:"required"`
+}
+
+type PasskeyLoginRequest struct {
+	ChallengeToken string                       `json:"challenge_token" binding:"required"`
+	Credential     webauthn.AssertionCredential `json:"credential" binding:"required"`
+}
+
+type PasskeysResponse struct {
+	Passkeys []model.Passkey `json:"passkeys"`
+}
+
+// PasskeyRegistrationOptions godoc
+// @Summary Passkey registration options
+// @Description Возвращает параметры для navigator.credentials.create() и challenge_token. Ключ должен быть discoverable и проверять пользователя, аттестация не запрашивается. Нужна сессия, в которую пользователь вошёл не раньше WEBAUTHN_REAUTH_MAX_AGE назад
+// @Tags passkeys
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Success 200 {object} PasskeyRegistrationOptionsResponse "Параметры регистрации"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 403 {object} ErrorResponse "Вход в сессию был слишком давно, нужен повторный вход"
+// @Failure 404 {object} ErrorResponse "Сессия принадлежит не пользователю с паролем"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /webauthn/register/options [post]
+func (h *UserHandler) PasskeyRegistrationOptions(c *gin.Context) {
+	options, challengeToken, err := h.userService.BeginPasskeyRegistration(c.Request.Context(), c.GetString("user_id"), c.GetTime("auth_time"))
+	if errors.Is(err, service.ErrReauthRequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start passkey registration"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, PasskeyRegistrationOptionsResponse{PublicKey: *options, ChallengeToken: challengeToken})
+}
+
+// RegisterPasskey godoc
+// @Summary Register passkey
+// @Description Проверяет ответ navigator.credentials.create() и сохраняет passkey пользователя. Нужна сессия, в которую пользователь вошёл не раньше WEBAUTHN_REAUTH_MAX_AGE назад
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Param request body PasskeyRegistrationRequest true "challenge_token и ответ аутентификатора"
+// @Success 201 {object} model.Passkey "Passkey сохранён"
+// @Failure 400 {object} ErrorResponse "Неверный запрос или ответ аутентификатора не прошёл проверку"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 403 {object} ErrorResponse "Вход в сессию был слишком давно, нужен повторный вход"
+// @Failure 404 {object} ErrorResponse "Сессия принадлежит не пользователю с паролем"
+// @Failure 409 {object} ErrorResponse "Passkey уже зарегистрирован"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /webauthn/register [post]
+func (h *UserHandler) RegisterPasskey(c *gin.Context) {
+	var request PasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passkey, err := h.userService.FinishPasskeyRegistration(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"), c.GetTime("auth_time"), request.ChallengeToken, request.Name, request.Credential, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrReauthRequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidPasskey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrPasskeyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register passkey"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, passkey)
+}
+
+// PasskeyLoginOptions godoc
+// @Summary Passkey login options
+// @Description Возвращает параметры для navigator.credentials.get() и challenge_token. Логин не нужен: пользователя определяет выбранный passkey
+// @Tags auth
+// @Produce json
+// @Success 200 {object} PasskeyLoginOptionsResponse "Параметры входа"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /webauthn/login/options [post]
+func (h *UserHandler) PasskeyLoginOptions(c *gin.Context) {
+	options, challengeToken, err := h.userService.BeginPasskeyLogin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start passkey login"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, PasskeyLoginOptionsResponse{PublicKey: *options, ChallengeToken: challengeToken})
+}
+
+// LoginPasskey godoc
+// @Summary Passkey login
+// @Description Проверяет ответ navigator.credentials.get() и открывает новую сессию. В access token amr = ["hwk"]
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body PasskeyLoginRequest true "challenge_token и ответ аутентификатора"
+// @Success 200 {object} model.TokenPair "Токены новой сессии"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Passkey не прошёл проверку"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /webauthn/login [post]
+func (h *UserHandler) LoginPasskey(c *gin.Context) {
+	var request PasskeyLoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenPair, err := h.userService.FinishPasskeyLogin(c.Request.Context(), request.ChallengeToken, request.Credential, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrInvalidPasskey) || errors.Is(err, service.ErrPasskeyCloned) {
+		entry := requestAudit(c, model.AuditActionPasskeyLogin, model.AuditActorAnonymous, "", model.AuditOutcomeFailure)
+		entry.Target = request.Credential.ID
+		entry.Reason = model.ReasonInvalidPasskey
+		if errors.Is(err, service.ErrPasskeyCloned) {
+			entry.Reason = model.ReasonSignCountRollback
+		}
+		h.auditService.Record(c.Request.Context(), entry)
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, tokenPair)
+}
+
+// ListPasskeys godoc
+// @Summary List passkeys
+// @Description Возвращает passkey текущего пользователя
+// @Tags passkeys
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Success 200 {object} PasskeysResponse "Passkey пользователя"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 404 {object} ErrorResponse "Сессия принадлежит не пользователю с паролем"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /webauthn/credentials [get]
+func (h *UserHandler) ListPasskeys(c *gin.Context) {
+	passkeys, err := h.userService.ListPasskeys(c.Request.Context(), c.GetString("user_id"))
+	if errors.Is(err, service.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list passkeys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PasskeysResponse{Passkeys: passkeys})
+}
+
+// DeletePasskey godoc
+// @Summary Delete passkey
+// @Description Удаляет passkey текущего пользователя. Открытые им сессии не отзываются
+// @Tags passkeys
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Param id path string true "Passkey ID"
+// @Success 200 {object} MessageResponse "Passkey удалён"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 404 {object} ErrorResponse "Passkey не найден"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /webauthn/credentials/{id} [delete]
+func (h *UserHandler) DeletePasskey(c *gin.Context) {
+	err := h.userService.DeletePasskey(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"), c.Param("id"), c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrPasskeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete passkey"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "passkey удалён"})
+}
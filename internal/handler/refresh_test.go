@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/service"
+	"net/http"
+	"testing"
+)
+
+func TestRefreshErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"invalid token", service.ErrInvalidRefreshToken, http.StatusUnauthorized},
+		{"legacy token without access token", fmt.Errorf("%w: для refresh token старого формата нужен access token", service.ErrInvalidRefreshToken), http.StatusUnauthorized},
+		{"used token", service.ErrRefreshTokenUsed, http.StatusForbidden},
+		{"expired token", service.ErrRefreshTokenExpired, http.StatusForbidden},
+		{"binding revoke", fmt.Errorf("%w: ip_change", service.ErrSessionRevoked), http.StatusForbidden},
+		{"binding reauth", fmt.Errorf("%w: user_agent_change", service.ErrReauthenticationRequired), http.StatusForbidden},
+		{"database error", errors.New("ошибка при получении refresh token: connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := refreshErrorStatus(tt.err); got != tt.want {
+			t.Errorf("%s: refreshErrorStatus() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"hh/internal/model"
+	"hh/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionsResponse struct {
+	Sessions []model.Session `json:"sessions"`
+}
+
+type RevokeOthersResponse struct {
+	Revoked int64 `json:"revoked" example:"2"`
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description Возвращает активные сессии (устройства) текущего пользователя
+// @Tags sessions
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Success 200 {object} SessionsResponse "Активные сессии"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.authService.ListSessions(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SessionsResponse{Sessions: sessions})
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Отзывает одну сессию текущего пользователя
+// @Tags sessions
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Param id path string true "Session ID"
+// @Success 200 {object} LogoutResponse "Сессия отозвана"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 404 {object} ErrorResponse "Сессия не найдена"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	err := h.authService.RevokeSession(c.Request.Context(), c.GetString("user_id"), c.Param("id"), model.ReasonUserRequest, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Отзывает все сессии пользователя, кроме текущей
+// @Tags sessions
+// @Produce json
+// @Param Authorization header string true "Access token" default(Bearer <token>)
+// @Success 200 {object} RevokeOthersResponse "Количество отозванных сессий"
+// @Failure 401 {object} ErrorResponse "Неавторизованный доступ или неверный токен"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /sessions/revoke-others [post]
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	revoked, err := h.authService.RevokeOtherSessions(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"), c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RevokeOthersResponse{Revoked: revoked})
+}
@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"errors"
+	"hh/internal/model"
+	"hh/internal/service"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	userService  *service.UserService
+	auditService *service.AuditService
+}
+
+func NewUserHandler(userService *service.UserService, auditService *service.AuditService) *UserHandler {
+	return &UserHandler{userService: userService, auditService: auditService}
+}
+
+// Login godoc
+// @Summary Password login
+// @Description Проверяет email или username и пароль и открывает новую сессию. На неизвестный логин и неверный пароль ответ одинаковый. Если у пользователя подключён TOTP, вместо токенов возвращается model.MFAChallenge с mfa_required=true: токены выдаёт POST /login/mfa
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.LoginRequest true "Логин и пароль"
+// @Success 200 {object} model.TokenPair "Токены новой сессии или model.MFAChallenge"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Неверный логин или пароль"
+// @Failure 403 {object} ErrorResponse "Email не подтверждён"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов или временная блокировка после неудачных попыток, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /login [post]
+func (h *UserHandler) Login(c *gin.Context) {
+	var request model.LoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenPair, challenge, err := h.userService.Login(c.Request.Context(), request.Login, request.Password, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		entry := requestAudit(c, model.AuditActionPasswordLogin, model.AuditActorAnonymous, "", model.AuditOutcomeFailure)
+		entry.Target = request.Login
+		entry.Reason = model.ReasonInvalidCredentials
+		h.auditService.Record(c.Request.Context(), entry)
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		entry := requestAudit(c, model.AuditActionPasswordLogin, model.AuditActorAnonymous, "", model.AuditOutcomeFailure)
+		entry.Target = request.Login
+		entry.Reason = model.ReasonEmailNotVerified
+		h.auditService.Record(c.Request.Context(), entry)
+
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to login"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenPair)
+}
+
+// Register godoc
+// @Summary Register user
+// @Description Создаёт пользователя с неподтверждённым email и отправляет на него ссылку подтверждения. Войти можно только после подтверждения
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.RegisterRequest true "Email, username и пароль"
+// @Success 201 {object} model.User "Пользователь создан, письмо отправлено"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 409 {object} ErrorResponse "Email или username уже заняты"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /register [post]
+func (h *UserHandler) Register(c *gin.Context) {
+	var request model.RegisterRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.Register(c.Request.Context(), request)
+	if errors.Is(err, service.ErrUserExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register"})
+		return
+	}
+
+	entry := requestAudit(c, model.AuditActionUserRegistration, model.AuditActorUser, user.ID.String(), model.AuditOutcomeSuccess)
+	entry.SubjectID = &user.ID
+	h.auditService.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// VerifyEmail godoc
+// @Summary Verify email
+// @Description Подтверждает email по токену из одноразовой ссылки в письме. Ссылка ведёт на страницу EMAIL_VERIFICATION_URL, которая отправляет token сюда
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.VerifyEmailRequest true "Токен из ссылки"
+// @Success 200 {object} MessageResponse "Email подтверждён"
+// @Failure 400 {object} ErrorResponse "Неверный запрос, ссылка недействительна, истекла или уже использована"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /verify-email [post]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var request model.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.VerifyEmail(c.Request.Context(), request.Token)
+	if errors.Is(err, service.ErrInvalidVerificationToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+		return
+	}
+
+	entry := requestAudit(c, model.AuditActionEmailVerification, model.AuditActorUser, user.ID.String(), model.AuditOutcomeSuccess)
+	entry.SubjectID = &user.ID
+	h.auditService.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "email подтверждён"})
+}
+
+// ResendVerification godoc
+// @Summary Resend verification email
+// @Description Отправляет новую ссылку подтверждения. Ответ одинаковый для любого адреса
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.ResendVerificationRequest true "Email"
+// @Success 202 {object} MessageResponse "Если адрес зарегистрирован и не подтверждён, письмо отправлено"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов, см. Retry-After"
+// @Router /verify-email/resend [post]
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var request model.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// ошибка не отдаётся клиенту: по ней было бы видно, что адрес существует
+	if err := h.userService.ResendVerification(c.Request.Context(), request.Email); err != nil {
+		log.Println("resend verification:", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "если адрес зарегистрирован и не подтверждён, письмо отправлено"})
+}
+
+// ForgotPassword godoc
+// @Summary Request password reset
+// @Description Отправляет одноразовую ссылку сброса пароля. Ответ одинаковый для любого адреса
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.ForgotPasswordRequest true "Email"
+// @Success 202 {object} MessageResponse "Если адрес зарегистрирован, письмо отправлено"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов, см. Retry-After"
+// @Router /password/forgot [post]
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var request model.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.userService.ForgotPassword(c.Request.Context(), request.Email)
+
+	entry := requestAudit(c, model.AuditActionPasswordForgot, model.AuditActorAnonymous, "", model.AuditOutcomeSuccess)
+	entry.Target = request.Email
+	h.auditService.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "если адрес зарегистрирован, письмо со ссылкой отправлено"})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Задаёт новый пароль по токену из письма, отзывает все сессии пользователя и удаляет его passkey
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.ResetPasswordRequest true "Токен и новый пароль"
+// @Success 200 {object} MessageResponse "Пароль изменён"
+// @Failure 400 {object} ErrorResponse "Неверный запрос или недействительный токен"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /password/reset [post]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var request model.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.userService.ResetPassword(c.Request.Context(), request.Token, request.Password, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, service.ErrInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "пароль изменён"})
+}
+
+// CreateUser godoc
+// @Summary Create user
+// @Description Создаёт пользователя с паролем. Нужен email или username
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param request body model.CreateUserRequest true "Параметры пользователя"
+// @Success 201 {object} model.User "Пользователь создан"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 409 {object} ErrorResponse "Email или username уже заняты"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/users [post]
+func (h *AdminHandler) CreateUser(c *gin.Context) {
+	var request model.CreateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.CreateUser(c.Request.Context(), request)
+	if errors.Is(err, service.ErrInvalidUser) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrUserExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), adminAudit(c, model.AuditActionUserCreation, user.ID.String(), "created"))
+
+	c.JSON(http.StatusCreated, user)
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"errors"
+	"hh/internal/model"
+	"hh/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookEventsResponse struct {
+	Events []model.WebhookOutboxEvent `json:"events"`
+}
+
+// ListDeadWebhooks godoc
+// @Summary List dead-lettered webhook events
+// @Description События, которые не удалось доставить за WEBHOOK_MAX_ATTEMPTS попыток
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param limit query int false "Размер страницы (до 100)" default(100)
+// @Param offset query int false "Смещение" default(0)
+// @Success 200 {object} WebhookEventsResponse "Dead события"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/dead [get]
+func (h *AdminHandler) ListDeadWebhooks(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	events, err := h.webhookService.ListDead(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhook events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookEventsResponse{Events: events})
+}
+
+// ReplayWebhook godoc
+// @Summary Replay dead-lettered webhook event
+// @Description Возвращает dead событие в очередь со сброшенным счётчиком попыток
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param id path string true "Event ID"
+// @Success 200 {object} LogoutResponse "Событие поставлено в очередь"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 404 {object} ErrorResponse "Событие не найдено"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id}/replay [post]
+func (h *AdminHandler) ReplayWebhook(c *gin.Context) {
+	err := h.webhookService.Replay(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrWebhookEventNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to replay webhook event"})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), adminAudit(c, model.AuditActionWebhookReplay, c.Param("id"), "replayed"))
+
+	c.JSON(http.StatusOK, gin.H{"message": "event queued"})
+}
+
+type WebhookSubscriptionsResponse struct {
+	Subscriptions []model.WebhookSubscription `json:"subscriptions"`
+}
+
+type CreateWebhookSubscriptionResponse struct {
+	Subscription *model.WebhookSubscription `json:"subscription"`
+	Secret       string                     `json:"secret"`
+}
+
+// ListWebhookSubscriptions godoc
+// @Summary List webhook subscriptions
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Success 200 {object} WebhookSubscriptionsResponse "Подписки"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/webhook-subscriptions [get]
+func (h *AdminHandler) ListWebhookSubscriptions(c *gin.Context) {
+	subs, err := h.webhookService.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhook subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookSubscriptionsResponse{Subscriptions: subs})
+}
+
+// CreateWebhookSubscription godoc
+// @Summary Create webhook subscription
+// @Description Подписывает URL на события. Пустой event_types означает все события. Если secret не передан, он генерируется и возвращается один раз
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param request body model.WebhookSubscriptionRequest true "Параметры подписки"
+// @Success 201 {object} CreateWebhookSubscriptionResponse "Подписка создана"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/webhook-subscriptions [post]
+func (h *AdminHandler) CreateWebhookSubscription(c *gin.Context) {
+	var request model.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, secret, err := h.webhookService.CreateSubscription(c.Request.Context(), request)
+	if errors.Is(err, service.ErrInvalidWebhookSubscription) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook subscription"})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), adminAudit(c, model.AuditActionWebhookSubscription, sub.ID.String(), "created"))
+
+	c.JSON(http.StatusCreated, CreateWebhookSubscriptionResponse{Subscription: sub, Secret: secret})
+}
+
+// GetWebhookSubscription godoc
+// @Summary Get webhook subscription
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} model.WebhookSubscription "Подписка"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 404 {object} ErrorResponse "Подписка не найдена"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/webhook-subscriptions/{id} [get]
+func (h *AdminHandler) GetWebhookSubscription(c *gin.Context) {
+	sub, err := h.webhookService.GetSubscription(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrWebhookSubscriptionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get webhook subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// UpdateWebhookSubscription godoc
+// @Summary Update webhook subscription
+// @Description Заменяет url и event_types подписки. Пустой secret оставляет прежний, отсутствующий active не меняет флаг
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param id path string true "Subscription ID"
+// @Param request body model.WebhookSubscriptionRequest true "Параметры подписки"
+// @Success 200 {object} model.WebhookSubscription "Подписка обновлена"
+// @Failure 400 {object} ErrorResponse "Неверный запрос"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 404 {object} ErrorResponse "Подписка не найдена"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/webhook-subscriptions/{id} [put]
+func (h *AdminHandler) UpdateWebhookSubscription(c *gin.Context) {
+	var request model.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.webhookService.UpdateSubscription(c.Request.Context(), c.Param("id"), request)
+	if errors.Is(err, service.ErrInvalidWebhookSubscription) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrWebhookSubscriptionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook subscription"})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), adminAudit(c, model.AuditActionWebhookSubscription, sub.ID.String(), "updated"))
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhookSubscription godoc
+// @Summary Delete webhook subscription
+// @Description Удаляет подписку вместе с её недоставленными событиями
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Admin token" default(Bearer <token>)
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} LogoutResponse "Подписка удалена"
+// @Failure 401 {object} ErrorResponse "Невалидный admin токен"
+// @Failure 404 {object} ErrorResponse "Подписка не найдена"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /admin/webhook-subscriptions/{id} [delete]
+func (h *AdminHandler) DeleteWebhookSubscription(c *gin.Context) {
+	err := h.webhookService.DeleteSubscription(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrWebhookSubscriptionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete webhook subscription"})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), adminAudit(c, model.AuditActionWebhookSubscription, c.Param("id"), "deleted"))
+
+	c.JSON(http.StatusOK, gin.H{"message": "subscription deleted"})
+}
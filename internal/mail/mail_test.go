@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	msg := Message{To: "alice@example.com", Subject: "Подтверждение email", Body: "строка 1\nстрока 2\n"}
+
+	got := string(render("auth@example.com", msg))
+
+	header, body, ok := strings.Cut(got, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no header/body separator in %q", got)
+	}
+
+	for _, line := range []string{
+		"From: auth@example.com",
+		"To: alice@example.com",
+		"Subject: =?utf-8?q?",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Date: ",
+	} {
+		if !strings.Contains(header, "\r\n"+line) && !strings.HasPrefix(header, line) {
+			t.Errorf("header has no %q:\n%s", line, header)
+		}
+	}
+	// в заголовках только ASCII, тема закодирована
+	if strings.Contains(header, "Подтверждение") {
+		t.Errorf("subject is not encoded: %s", header)
+	}
+	if body != "строка 1\r\nстрока 2\r\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+
+	for _, to := range []string{"alice@example.com", "bob@example.com"} {
+		if err := mailer.Send(context.Background(), Message{To: to}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages := mailer.Messages()
+	if len(messages) != 2 || messages[0].To != "alice@example.com" || messages[1].To != "bob@example.com" {
+		t.Fatalf("Messages() = %+v", messages)
+	}
+
+	// возвращается копия
+	messages[0].To = "mallory@example.com"
+	if mailer.Messages()[0].To != "alice@example.com" {
+		t.Error("Messages() exposed internal slice")
+	}
+}
+
+func TestWriterMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewWriterMailer(&buf, "auth@example.com")
+
+	if err := mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi", Body: "link"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "To: alice@example.com\r\n") || !strings.HasSuffix(buf.String(), "link\r\n\r\n") {
+		t.Errorf("written mail = %q", buf.String())
+	}
+}
@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+	HeaderForwarded     = "Forwarded"
+)
+
+// ParseTrustedProxies разбирает TRUSTED_PROXIES: CIDR или отдельные адреса.
+func ParseTrustedProxies(items []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(items))
+
+	for _, item := range items {
+		if prefix, err := netip.ParsePrefix(item); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: невалидный адрес %q", item)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// IsTrusted сообщает, входит ли addr в один из доверенных префиксов.
+func IsTrusted(trusted []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIPResolver определяет адрес клиента за доверенными прокси. Заголовки читаются,
+// только если соединение пришло от доверенного прокси, и разбираются справа налево:
+// адресом клиента считается первый адрес не из TRUSTED_PROXIES.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+// NewClientIPResolver принимает заголовки в порядке приоритета. Указывать стоит только те,
+// которые прокси перезаписывает или дописывает: остальные клиент может подделать.
+func NewClientIPResolver(trusted []netip.Prefix, headers []string) (*ClientIPResolver, error) {
+	canonical := make([]string, 0, len(headers))
+
+	for _, header := range headers {
+		name := http.CanonicalHeaderKey(header)
+		switch name {
+		case HeaderXForwardedFor, HeaderForwarded, http.CanonicalHeaderKey(HeaderXRealIP):
+			canonical = append(canonical, name)
+		default:
+			return nil, fmt.Errorf("CLIENT_IP_HEADERS: неподдерживаемый заголовок %q", header)
+		}
+	}
+
+	return &ClientIPResolver{trusted: trusted, headers: canonical}, nil
+}
+
+// Handler подменяет RemoteAddr запроса адресом клиента, поэтому c.ClientIP() дальше по
+// цепочке возвращает его. Встроенный разбор заголовков gin нужно отключить через
+// SetTrustedProxies(nil).
+func (r *ClientIPResolver) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		host, port, err := net.SplitHostPort(c.Request.RemoteAddr)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		if ip := r.Resolve(host, c.Request.Header); ip != host {
+			c.Request.RemoteAddr = net.JoinHostPort(ip, port)
+		}
+
+		c.Next()
+	}
+}
+
+// Resolve возвращает адрес клиента для соединения от remoteIP.
+func (r *ClientIPResolver) Resolve(remoteIP string, header http.Header) string {
+	remote, err := netip.ParseAddr(remoteIP)
+	if err != nil || !IsTrusted(r.trusted, remote) {
+		return remoteIP
+	}
+
+	for _, name := range r.headers {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		var hops []string
+		switch name {
+		case HeaderForwarded:
+			hops = forwardedFor(values)
+		case HeaderXForwardedFor:
+			hops = splitList(values)
+		default:
+			hops = []string{strings.TrimSpace(values[len(values)-1])}
+		}
+
+		if ip, ok := r.firstUntrusted(hops); ok {
+			return ip
+		}
+	}
+
+	return remoteIP
+}
+
+// firstUntrusted идёт по цепочке справа налево и пропускает доверенные прокси. Если встретился
+// мусор, адресом клиента считается последний разобранный хоп: левее него верить нечему.
+func (r *ClientIPResolver) firstUntrusted(hops []string) (string, bool) {
+	var last netip.Addr
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHop(hops[i])
+		if !ok {
+			break
+		}
+		last = addr
+		if !IsTrusted(r.trusted, addr) {
+			return addr.String(), true
+		}
+	}
+
+	if !last.IsValid() {
+		return "", false
+	}
+
+	return last.String(), true
+}
+
+// parseHop принимает адрес с портом или без, IPv6 может быть в квадратных скобках.
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.TrimSpace(hop)
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		items = append(items, strings.Split(value, ",")...)
+	}
+
+	return items
+}
+
+// forwardedFor достаёт параметры for из заголовков Forwarded (RFC 7239) в порядке хопов.
+// Для хопа без for и для obfuscated/unknown значений возвращается пустая строка, на ней
+// разбор цепочки останавливается.
+func forwardedFor(values []string) []string {
+	var hops []string
+
+	for _, element := range splitList(values) {
+		hop := ""
+		for _, pair := range strings.Split(element, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(name, "for") {
+				hop = strings.Trim(value, `"`)
+			}
+		}
+		hops = append(hops, hop)
+	}
+
+	return hops
+}
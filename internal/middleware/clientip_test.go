@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		items   []string
+		want    []string
+		wantErr bool
+	}{
+		{[]string{"10.0.0.0/8", "192.168.1.5"}, []string{"10.0.0.0/8", "192.168.1.5/32"}, false},
+		{[]string{"10.1.2.3/8"}, []string{"10.0.0.0/8"}, false},
+		{[]string{"::ffff:10.0.0.1"}, []string{"10.0.0.1/32"}, false},
+		{[]string{"2001:db8::/32", "2001:db8::1"}, []string{"2001:db8::/32", "2001:db8::1/128"}, false},
+		{[]string{"proxy.internal"}, nil, true},
+		{[]string{"10.0.0.0/33"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		prefixes, err := ParseTrustedProxies(tt.items)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTrustedProxies(%v) error = %v, wantErr %v", tt.items, err, tt.wantErr)
+			continue
+		}
+
+		if len(prefixes) != len(tt.want) {
+			t.Errorf("ParseTrustedProxies(%v) = %v, want %v", tt.items, prefixes, tt.want)
+			continue
+		}
+		for i, prefix := range prefixes {
+			if prefix.String() != tt.want[i] {
+				t.Errorf("ParseTrustedProxies(%v)[%d] = %s, want %s", tt.items, i, prefix, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestNewClientIPResolverRejectsUnknownHeader(t *testing.T) {
+	if _, err := NewClientIPResolver(nil, []string{"X-Forwarded-For", "CF-Connecting-IP"}); err == nil {
+		t.Error("NewClientIPResolver() accepted CF-Connecting-IP")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "2001:db8:ffff::/48"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := NewClientIPResolver(trusted, []string{"forwarded", "x-forwarded-for", "x-real-ip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		header http.Header
+		want   string
+	}{
+		{"no proxy", "203.0.113.7", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.7"},
+		{"proxy without headers", "10.0.0.1", http.Header{}, "10.0.0.1"},
+		{"xff single hop", "10.0.0.1", http.Header{"X-Forwarded-For": {"203.0.113.7"}}, "203.0.113.7"},
+		{"xff spoofed left part", "10.0.0.1", http.Header{"X-Forwarded-For": {"198.51.100.1, 203.0.113.7"}}, "203.0.113.7"},
+		{"xff chain of proxies", "10.0.0.1", http.Header{"X-Forwarded-For": {"203.0.113.7, 10.0.0.3", "10.0.0.2"}}, "203.0.113.7"},
+		{"xff only proxies", "10.0.0.1", http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+		{"xff garbage stops chain", "10.0.0.1", http.Header{"X-Forwarded-For": {"203.0.113.7, garbage, 10.0.0.2"}}, "10.0.0.2"},
+		{"xff garbage only", "10.0.0.1", http.Header{"X-Forwarded-For": {"garbage"}}, "10.0.0.1"},
+		{"xff with port", "10.0.0.1", http.Header{"X-Forwarded-For": {"203.0.113.7:51234"}}, "203.0.113.7"},
+		{"xff mapped ipv4", "10.0.0.1", http.Header{"X-Forwarded-For": {"::ffff:203.0.113.7"}}, "203.0.113.7"},
+		{"xff ipv6 in brackets", "2001:db8:ffff::1", http.Header{"X-Forwarded-For": {"[2001:db8::7]"}}, "2001:db8::7"},
+		{"forwarded", "10.0.0.1", http.Header{"Forwarded": {`for=198.51.100.1, for="[2001:db8::7]:443";proto=https`}}, "2001:db8::7"},
+		{"forwarded preferred over xff", "10.0.0.1", http.Header{"Forwarded": {"for=203.0.113.7"}, "X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.7"},
+		{"forwarded obfuscated", "10.0.0.1", http.Header{"Forwarded": {"for=_hidden"}, "X-Forwarded-For": {"203.0.113.7"}}, "203.0.113.7"},
+		{"x-real-ip", "10.0.0.1", http.Header{"X-Real-Ip": {"203.0.113.7"}}, "203.0.113.7"},
+		{"x-real-ip last value", "10.0.0.1", http.Header{"X-Real-Ip": {"198.51.100.1", "203.0.113.7"}}, "203.0.113.7"},
+		{"invalid remote", "not-an-ip", http.Header{"X-Forwarded-For": {"203.0.113.7"}}, "not-an-ip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolver.Resolve(tt.remote, tt.header); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.remote, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPResolverHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := NewClientIPResolver(trusted, []string{HeaderXForwardedFor})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(resolver.Handler())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.Header.Set(HeaderXForwardedFor, "203.0.113.7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Body.String() != "203.0.113.7" {
+		t.Errorf("ClientIP() = %q, want %q", w.Body.String(), "203.0.113.7")
+	}
+}
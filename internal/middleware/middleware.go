@@ -2,21 +2,23 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"hh/internal/repository"
+	"hh/internal/token"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type Middleware struct {
-	repo *repository.TokenRepository
+	repo         *repository.TokenRepository
+	tokenManager *token.Manager
 }
 
-func NewMiddleware(repo *repository.TokenRepository) *Middleware {
-	return &Middleware{repo: repo}
+func NewMiddleware(repo *repository.TokenRepository, tokenManager *token.Manager) *Middleware {
+	return &Middleware{repo: repo, tokenManager: tokenManager}
 }
 
 func AuthMiddleware(m *Middleware) gin.HandlerFunc {
@@ -29,11 +31,8 @@ func AuthMiddleware(m *Middleware) gin.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-		if err != nil || !token.Valid {
+		claims, err := m.tokenManager.ParseClaims(tokenString)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Невалидный токен"})
 			return
 		}
@@ -50,13 +49,36 @@ func AuthMiddleware(m *Middleware) gin.HandlerFunc {
 			return
 		}
 
-		storedSession, err := m.repo.GetCurrentSessionID(context.Background(), userID)
-		if err != nil || sessionID != storedSession {
+		active, err := m.repo.IsSessionActive(context.Background(), sessionID, userID)
+		if err != nil || !active {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
 			return
 		}
 
 		c.Set("user_id", userID)
+		c.Set("session_id", sessionID)
+		// auth_time есть только у сессий, открытых входом пользователя
+		if authTime, ok := claims["auth_time"].(float64); ok {
+			c.Set("auth_time", time.Unix(int64(authTime), 0))
+		}
+		c.Next()
+	}
+}
+
+func AdminMiddleware(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminToken == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin API отключен"})
+			return
+		}
+
+		authHeader := c.GetHeader("Authorization")
+		provided := strings.TrimPrefix(authHeader, "Bearer ")
+		if !strings.HasPrefix(authHeader, "Bearer ") || subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Невалидный admin токен"})
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,464 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"hh/config"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/internal/token"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitStore хранит состояние bucket-ов. Update должен выполнять fn для key атомарно:
+// MemoryRateLimitStore — в пределах процесса, repository.RateLimitRepository — для всех инстансов.
+type RateLimitStore interface {
+	Update(ctx context.Context, key string, fn func(state *model.RateLimitState)) error
+}
+
+// RateLimit — token bucket на Limit запросов за Period; Limit == 0 отключает лимит.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration
+	reset      time.Duration
+}
+
+// take списывает токен, если он есть. Bucket пополняется равномерно, полный за Period.
+func (l RateLimit) take(state *model.RateLimitState, now time.Time) rateLimitResult {
+	capacity := float64(l.Limit)
+	rate := capacity / l.Period.Seconds()
+
+	tokens := capacity
+	if !state.UpdatedAt.IsZero() {
+		tokens = math.Min(capacity, state.Tokens+now.Sub(state.UpdatedAt).Seconds()*rate)
+	}
+
+	result := rateLimitResult{allowed: tokens >= 1}
+	if result.allowed {
+		tokens--
+	} else {
+		result.retryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	state.Tokens = tokens
+	state.UpdatedAt = now
+
+	result.remaining = int(tokens)
+	result.reset = time.Duration((capacity - tokens) / rate * float64(time.Second))
+
+	return result
+}
+
+// RefreshTokenOwners находит user_id владельца refresh token по selector;
+// реализуется repository.TokenRepository.
+type RefreshTokenOwners interface {
+	RefreshTokenOwner(ctx context.Context, selector string) (string, error)
+}
+
+type RateLimiter struct {
+	store        RateLimitStore
+	owners       RefreshTokenOwners
+	tokenManager *token.Manager
+
+	ip     RateLimit
+	user   RateLimit
+	client RateLimit
+
+	lockoutFailures RateLimit
+	lockoutDuration time.Duration
+}
+
+func NewRateLimiter(store RateLimitStore, owners RefreshTokenOwners, tokenManager *token.Manager, cfg *config.Config) *RateLimiter {
+	return &RateLimiter{
+		store:           store,
+		owners:          owners,
+		tokenManager:    tokenManager,
+		ip:              RateLimit{Limit: cfg.RateLimitIP, Period: time.Minute},
+		user:            RateLimit{Limit: cfg.RateLimitUser, Period: time.Minute},
+		client:          RateLimit{Limit: cfg.RateLimitClient, Period: time.Minute},
+		lockoutFailures: RateLimit{Limit: cfg.RefreshLockoutThreshold, Period: cfg.RefreshLockoutWindow},
+		lockoutDuration: cfg.RefreshLockoutDuration,
+	}
+}
+
+type bucket struct {
+	key   string
+	limit RateLimit
+}
+
+// Tokens ограничивает POST /tokens по IP до аутентификации клиента.
+func (l *RateLimiter) Tokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.allow(c, []bucket{{"tokens:ip:" + c.ClientIP(), l.ip}}) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// TokensClient ограничивает POST /tokens по клиенту и по user_id, для которого клиент
+// выпускает токены. Ставится после аутентификации клиента: по заявленному, но не проверенному
+// client_id кто угодно исчерпал бы лимит чужого клиента. Bucket пользователя свой у каждого
+// клиента, чтобы один клиент не мог отнять лимит у другого.
+func (l *RateLimiter) TokensClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID := c.GetString("client_id")
+		if clientID == "" {
+			c.Next()
+			return
+		}
+
+		buckets := []bucket{{"tokens:client:" + clientID, l.client}}
+		if userID := c.PostForm("user_id"); userID != "" {
+			buckets = append(buckets, bucket{"tokens:user:" + clientID + ":" + userID, l.user})
+		}
+
+		if !l.allow(c, buckets) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// PerIP ограничивает эндпоинт только по IP, например регистрацию и повторную отправку писем.
+func (l *RateLimiter) PerIP(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.allow(c, []bucket{{name + ":ip:" + c.ClientIP(), l.ip}}) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Refresh ограничивает POST /refresh по IP и по пользователю, а после
+// REFRESH_LOCKOUT_THRESHOLD неудач за REFRESH_LOCKOUT_WINDOW блокирует IP и пользователя
+// на REFRESH_LOCKOUT_DURATION. Пользователь — владелец refresh token по его selector, а для
+// токенов старого формата — из access token с проверенной подписью. Ключом не может быть сам
+// selector: каждый случайный selector давал бы новый bucket, и перебор обходил бы лимит.
+func (l *RateLimiter) Refresh() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ipKey := "refresh:ip:" + c.ClientIP()
+		userKey := l.refreshUserKey(c)
+		if c.IsAborted() {
+			return
+		}
+
+		l.limitWithLockout(c, ipKey, userKey, []string{ipKey, userKey}, http.StatusUnauthorized)
+	}
+}
+
+// Login ограничивает POST /login по IP и по логину, а после серии неудачных паролей с теми же
+// порогами, что и для /refresh, блокирует IP и пару логин + подсеть клиента. Один логин целиком
+// не блокируется: иначе любой мог бы неверными паролями запереть чужую учётную запись.
+// Перебор одного логина с многих адресов сдерживает лимит по логину.
+func (l *RateLimiter) Login() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ipKey := "login:ip:" + c.ClientIP()
+		lockoutKeys := []string{ipKey}
+
+		var request model.LoginRequest
+		userKey := ""
+		if peekJSON(c, &request) && request.Login != "" {
+			login := strings.ToLower(request.Login)
+			userKey = "login:user:" + login
+			lockoutKeys = append(lockoutKeys, "login:user:"+login+":net:"+clientSubnet(c.ClientIP()))
+		}
+		if c.IsAborted() {
+			return
+		}
+
+		l.limitWithLockout(c, ipKey, userKey, lockoutKeys, http.StatusUnauthorized)
+	}
+}
+
+// MFA ограничивает проверку второго фактора по IP и по пользователю и блокирует их после
+// серии неверных кодов: иначе 6 цифр TOTP перебираются за разумное время. Пользователь
+// берётся из access token после AuthMiddleware, а на /login/mfa — из mfa_token с проверенной подписью.
+func (l *RateLimiter) MFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if userID == "" {
+			var request model.MFALoginRequest
+			if peekJSON(c, &request) {
+				if claims, err := l.tokenManager.ParseClaims(request.MFAToken); err == nil {
+					userID, _ = claims["sub"].(string)
+				}
+			}
+			if c.IsAborted() {
+				return
+			}
+		}
+
+		userKey := ""
+		if userID != "" {
+			userKey = "mfa:user:" + userID
+		}
+
+		ipKey := "mfa:ip:" + c.ClientIP()
+
+		l.limitWithLockout(c, ipKey, userKey, []string{ipKey, userKey}, http.StatusUnauthorized, http.StatusForbidden)
+	}
+}
+
+// limitWithLockout проверяет блокировку lockoutKeys и лимиты по IP и пользователю, а ответы
+// с failureStatuses засчитывает как неудачную попытку для lockoutKeys. Пустые ключи пропускаются.
+func (l *RateLimiter) limitWithLockout(c *gin.Context, ipKey, userKey string, lockoutKeys []string, failureStatuses ...int) {
+	keys := slices.DeleteFunc(slices.Clone(lockoutKeys), func(key string) bool { return key == "" })
+
+	if !l.checkLockout(c, keys) {
+		return
+	}
+
+	buckets := []bucket{{ipKey, l.ip}}
+	if userKey != "" {
+		buckets = append(buckets, bucket{userKey, l.user})
+	}
+
+	if !l.allow(c, buckets) {
+		return
+	}
+
+	c.Next()
+
+	if slices.Contains(failureStatuses, c.Writer.Status()) {
+		l.recordFailure(c.Request.Context(), keys)
+	}
+}
+
+// allow списывает по токену из каждого bucket-а и пишет RateLimit-* заголовки по самому
+// строгому из них. При отказе отвечает 429 с Retry-After.
+func (l *RateLimiter) allow(c *gin.Context, buckets []bucket) bool {
+	now := time.Now()
+	var tightest *rateLimitResult
+	var tightestLimit int
+	var retryAfter time.Duration
+
+	for _, b := range buckets {
+		if b.limit.Limit <= 0 {
+			continue
+		}
+
+		var result rateLimitResult
+		err := l.store.Update(c.Request.Context(), b.key, func(state *model.RateLimitState) {
+			result = b.limit.take(state, now)
+		})
+		if err != nil {
+			// хранилище лимитов недоступно: лучше пропустить запрос, чем положить логин
+			log.Println("rate limit:", err)
+			continue
+		}
+
+		if !result.allowed && result.retryAfter > retryAfter {
+			retryAfter = result.retryAfter
+		}
+		if tightest == nil || result.remaining < tightest.remaining {
+			tightest = &result
+			tightestLimit = b.limit.Limit
+		}
+	}
+
+	if tightest != nil {
+		c.Header("RateLimit-Limit", strconv.Itoa(tightestLimit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(tightest.remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.reset)))
+	}
+
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "слишком много запросов"})
+		return false
+	}
+
+	return true
+}
+
+func (l *RateLimiter) checkLockout(c *gin.Context, keys []string) bool {
+	if l.lockoutFailures.Limit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	var lockedUntil time.Time
+
+	for _, key := range keys {
+		err := l.store.Update(c.Request.Context(), "lockout:"+key, func(state *model.RateLimitState) {
+			if state.LockedUntil.After(lockedUntil) {
+				lockedUntil = state.LockedUntil
+			}
+		})
+		if err != nil {
+			log.Println("rate limit:", err)
+		}
+	}
+
+	if lockedUntil.After(now) {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(lockedUntil.Sub(now))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "слишком много неудачных попыток, попробуйте позже"})
+		return false
+	}
+
+	return true
+}
+
+// recordFailure списывает неудачу из bucket-а неудач; когда он пуст, ключ блокируется.
+func (l *RateLimiter) recordFailure(ctx context.Context, keys []string) {
+	if l.lockoutFailures.Limit <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	for _, key := range keys {
+		err := l.store.Update(ctx, "lockout:"+key, func(state *model.RateLimitState) {
+			if !l.lockoutFailures.take(state, now).allowed {
+				state.LockedUntil = now.Add(l.lockoutDuration)
+			}
+		})
+		if err != nil {
+			log.Println("rate limit:", err)
+		}
+	}
+}
+
+// maxPeekBody — предел тела, которое peekJSON читает в память. Запросы /refresh, /login и MFA
+// укладываются в пару килобайт, а без предела middleware читал бы до лимитов любое тело целиком.
+const maxPeekBody = 64 << 10
+
+// peekJSON разбирает JSON тела запроса в v и возвращает тело на место для хендлера. Тело
+// больше maxPeekBody отклоняется с 413 и прерывает запрос, поэтому после вызова нужно
+// проверить c.IsAborted().
+func peekJSON(c *gin.Context, v any) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPeekBody))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "слишком большое тело запроса"})
+		} else {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "не удалось прочитать тело запроса"})
+		}
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	return json.Unmarshal(body, v) == nil
+}
+
+// refreshUserKey выбирает пользователя так же, как TokenService.RefreshTokens: selector
+// refresh token важнее access token. Неизвестный selector пользователя не даёт, и такой
+// запрос ограничивается только по IP.
+func (l *RateLimiter) refreshUserKey(c *gin.Context) string {
+	var request model.RefreshRequest
+	if !peekJSON(c, &request) {
+		return ""
+	}
+
+	if selector, _, ok := token.SplitRefreshToken(request.RefreshToken); ok {
+		userID, err := l.owners.RefreshTokenOwner(c.Request.Context(), selector)
+		if err != nil {
+			if !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+				log.Println("rate limit:", err)
+			}
+			return ""
+		}
+		return "refresh:user:" + userID
+	}
+
+	if request.AccessToken != "" {
+		if claims, err := l.tokenManager.ParseExpiredClaims(request.AccessToken); err == nil {
+			if sub, _ := claims["sub"].(string); sub != "" {
+				return "refresh:user:" + sub
+			}
+		}
+	}
+
+	return ""
+}
+
+// clientSubnet — сеть клиента для ключей блокировки: /24 для IPv4 и /64 для IPv6, где
+// у одного клиента обычно целая подсеть.
+func clientSubnet(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+
+	bits := 64
+	if addr.Unmap().Is4() {
+		addr, bits = addr.Unmap(), 24
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+
+	return prefix.String()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// MemoryRateLimitStore хранит bucket-ы в памяти процесса; при нескольких инстансах
+// у каждого свои лимиты.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	states    map[string]*model.RateLimitState
+	lastPrune time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{states: make(map[string]*model.RateLimitState)}
+}
+
+func (s *MemoryRateLimitStore) Update(ctx context.Context, key string, fn func(state *model.RateLimitState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPrune) > time.Minute {
+		s.prune(now)
+		s.lastPrune = now
+	}
+
+	state, ok := s.states[key]
+	if !ok {
+		state = &model.RateLimitState{}
+		s.states[key] = state
+	}
+
+	fn(state)
+
+	return nil
+}
+
+// prune удаляет ключи, которые не трогали сутки: их bucket уже полон.
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	for key, state := range s.states {
+		if now.Sub(state.UpdatedAt) > 24*time.Hour && now.After(state.LockedUntil) {
+			delete(s.states, key)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"hh/config"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitTake(t *testing.T) {
+	limit := RateLimit{Limit: 10, Period: time.Minute}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		state          model.RateLimitState
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{"new key gets a full bucket", model.RateLimitState{}, true, 9, 0},
+		{"last token", model.RateLimitState{Tokens: 1, UpdatedAt: now}, true, 0, 0},
+		{"empty bucket", model.RateLimitState{Tokens: 0, UpdatedAt: now}, false, 0, 6 * time.Second},
+		{"half a token", model.RateLimitState{Tokens: 0.5, UpdatedAt: now}, false, 0, 3 * time.Second},
+		// 10 токенов в минуту — один каждые 6 секунд
+		{"refilled since last request", model.RateLimitState{Tokens: 0, UpdatedAt: now.Add(-12 * time.Second)}, true, 1, 0},
+		{"refill is capped", model.RateLimitState{Tokens: 5, UpdatedAt: now.Add(-time.Hour)}, true, 9, 0},
+	}
+
+	for _, tt := range tests {
+		state := tt.state
+		result := limit.take(&state, now)
+
+		if result.allowed != tt.wantAllowed || result.remaining != tt.wantRemaining {
+			t.Errorf("%s: allowed=%v remaining=%d, want %v %d", tt.name, result.allowed, result.remaining, tt.wantAllowed, tt.wantRemaining)
+		}
+		if diff := result.retryAfter - tt.wantRetryAfter; diff > time.Millisecond || diff < -time.Millisecond {
+			t.Errorf("%s: retryAfter = %v, want %v", tt.name, result.retryAfter, tt.wantRetryAfter)
+		}
+		if !state.UpdatedAt.Equal(now) {
+			t.Errorf("%s: UpdatedAt = %v, want %v", tt.name, state.UpdatedAt, now)
+		}
+	}
+}
+
+func TestCeilSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 0},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+	}
+
+	for _, tt := range tests {
+		if got := ceilSeconds(tt.d); got != tt.want {
+			t.Errorf("ceilSeconds(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryRateLimitStorePrunesIdleKeys(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	ctx := context.Background()
+
+	now := time.Now()
+	store.states["idle"] = &model.RateLimitState{UpdatedAt: now.Add(-48 * time.Hour)}
+	store.states["locked"] = &model.RateLimitState{UpdatedAt: now.Add(-48 * time.Hour), LockedUntil: now.Add(time.Hour)}
+	store.states["active"] = &model.RateLimitState{UpdatedAt: now}
+
+	if err := store.Update(ctx, "active", func(state *model.RateLimitState) { state.Tokens = 3 }); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.states["idle"]; ok {
+		t.Error("idle key was not pruned")
+	}
+	if _, ok := store.states["locked"]; !ok {
+		t.Error("locked key was pruned")
+	}
+	if store.states["active"].Tokens != 3 {
+		t.Errorf("active key tokens = %v, want 3", store.states["active"].Tokens)
+	}
+}
+
+// staticOwners — RefreshTokenOwners по карте selector → user_id.
+type staticOwners map[string]string
+
+func (o staticOwners) RefreshTokenOwner(ctx context.Context, selector string) (string, error) {
+	if selector == "broken" {
+		return "", errors.New("database is down")
+	}
+
+	userID, ok := o[selector]
+	if !ok {
+		return "", repository.ErrRefreshTokenNotFound
+	}
+
+	return userID, nil
+}
+
+func newTestRouter(limiter gin.HandlerFunc, path string, status int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.POST(path, limiter, func(c *gin.Context) {
+		c.Status(status)
+	})
+
+	return r
+}
+
+// Лимит клиента считается по проверенному client_id из контекста, bucket пользователя — свой у каждого клиента.
+func TestTokensClientRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), staticOwners{}, nil, &config.Config{RateLimitClient: 100, RateLimitUser: 2})
+
+	r := gin.New()
+	r.POST("/tokens", func(c *gin.Context) {
+		c.Set("client_id", c.GetHeader("X-Test-Client"))
+	}, limiter.TokensClient(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(clientID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tokens", strings.NewReader("user_id=alice"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Test-Client", clientID)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request("billing"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, w.Code)
+		}
+	}
+
+	w := request("billing")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("over limit: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("RateLimit headers = %v", w.Header())
+	}
+
+	if w := request("gateway"); w.Code != http.StatusOK {
+		t.Errorf("same user via other client: status %d, want 200", w.Code)
+	}
+	// без аутентифицированного клиента лимиты клиента не применяются
+	for i := 0; i < 3; i++ {
+		if w := request(""); w.Code != http.StatusOK {
+			t.Errorf("anonymous request %d: status %d, want 200", i, w.Code)
+		}
+	}
+}
+
+func TestRefreshLockout(t *testing.T) {
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), staticOwners{}, nil, &config.Config{
+		RateLimitIP:             100,
+		RateLimitUser:           100,
+		RefreshLockoutThreshold: 3,
+		RefreshLockoutWindow:    time.Minute,
+		RefreshLockoutDuration:  time.Minute,
+	})
+	r := newTestRouter(limiter.Refresh(), "/refresh", http.StatusUnauthorized)
+
+	refresh := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{"refresh_token":"selector.verifier"}`))
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// bucket неудач на 3 попытки пустеет на четвёртой, и тогда ключ блокируется
+	for i := 0; i < 4; i++ {
+		if w := refresh("198.51.100.7"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401", i, w.Code)
+		}
+	}
+
+	w := refresh("198.51.100.7")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("after lockout: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestClientSubnet(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.45", "203.0.113.0/24"},
+		{"::ffff:203.0.113.45", "203.0.113.0/24"},
+		{"2001:db8:1:2:3:4:5:6", "2001:db8:1:2::/64"},
+		{"not-an-ip", "not-an-ip"},
+	}
+
+	for _, tt := range tests {
+		if got := clientSubnet(tt.ip); got != tt.want {
+			t.Errorf("clientSubnet(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+// Неудачные пароли с одной подсети не должны блокировать этот же логин с другой.
+func TestLoginLockoutIsPerSubnet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), staticOwners{}, nil, &config.Config{
+		RateLimitIP:             100,
+		RateLimitUser:           100,
+		RefreshLockoutThreshold: 3,
+		RefreshLockoutWindow:    time.Minute,
+		RefreshLockoutDuration:  time.Minute,
+	})
+
+	r := gin.New()
+	r.POST("/login", limiter.Login(), func(c *gin.Context) {
+		c.Status(http.StatusUnauthorized)
+	})
+
+	login := func(ip string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"login":"Alice","password":"x"}`))
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// bucket неудач на 3 попытки пустеет на четвёртой, и тогда ключ блокируется
+	for i := 0; i < 4; i++ {
+		if code := login("198.51.100.7"); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want 401", i, code)
+		}
+	}
+
+	if code := login("198.51.100.8"); code != http.StatusTooManyRequests {
+		t.Errorf("same subnet after lockout: status %d, want 429", code)
+	}
+	if code := login("203.0.113.9"); code != http.StatusUnauthorized {
+		t.Errorf("other subnet: status %d, want 401 (account must not be locked)", code)
+	}
+}
+
+// Тело больше maxPeekBody отклоняется до хендлера, а обычное доходит до него целиком.
+func TestPeekJSONBodyLimit(t *testing.T) {
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), staticOwners{}, nil, &config.Config{RateLimitIP: 100, RateLimitUser: 100})
+
+	oversize := `{"login":"` + strings.Repeat("a", maxPeekBody) + `"}`
+
+	tests := []struct {
+		name       string
+		middleware gin.HandlerFunc
+		body       string
+		wantStatus int
+	}{
+		{"login", limiter.Login(), `{"login":"alice","password":"x"}`, http.StatusOK},
+		{"login oversize", limiter.Login(), oversize, http.StatusRequestEntityTooLarge},
+		{"refresh oversize", limiter.Refresh(), oversize, http.StatusRequestEntityTooLarge},
+		{"mfa oversize", limiter.MFA(), oversize, http.StatusRequestEntityTooLarge},
+		// не JSON лимитер не разбирает, но и не отклоняет: ответ за хендлером
+		{"not json", limiter.Login(), "login=alice", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+
+			var handlerBody string
+			r := gin.New()
+			r.POST("/", tt.middleware, func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				handlerBody = string(body)
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && handlerBody != tt.body {
+				t.Errorf("handler got body %q, want %q", handlerBody, tt.body)
+			}
+		})
+	}
+}
+
+func TestRefreshUserKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), staticOwners{"known": "alice"}, nil, &config.Config{})
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"known selector", `{"refresh_token":"known.verifier"}`, "refresh:user:alice"},
+		{"unknown selector", `{"refresh_token":"random.verifier"}`, ""},
+		{"store error", `{"refresh_token":"broken.verifier"}`, ""},
+		{"legacy token without access token", `{"refresh_token":"legacy"}`, ""},
+		{"not json", "refresh_token=known.verifier", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(tt.body))
+
+			if got := limiter.refreshUserKey(c); got != tt.want {
+				t.Errorf("refreshUserKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Лимит пользователя на /refresh не обходится сменой selector: все selector-ы пользователя
+// попадают в один bucket.
+func TestRefreshUserLimitAcrossSelectors(t *testing.T) {
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), staticOwners{"first": "alice", "second": "alice"}, nil, &config.Config{
+		RateLimitIP:   100,
+		RateLimitUser: 2,
+	})
+	r := newTestRouter(limiter.Refresh(), "/refresh", http.StatusUnauthorized)
+
+	for i, selector := range []string{"first", "second", "first"} {
+		req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{"refresh_token":"`+selector+`.verifier"}`))
+		req.RemoteAddr = "198.51.100.7:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		want := http.StatusUnauthorized
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Errorf("request %d with selector %q: status %d, want %d", i, selector, w.Code, want)
+		}
+	}
+}
+
+// Блокировку /refresh набирают только неверные учётные данные (401): отозванная сессия (403)
+// и сбой сервера (500) её не приближают.
+func TestRefreshLockoutCountsOnlyCredentialFailures(t *testing.T) {
+	tests := []struct {
+		status     int
+		wantLocked bool
+	}{
+		{http.StatusUnauthorized, true},
+		{http.StatusForbidden, false},
+		{http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			limiter := NewRateLimiter(NewMemoryRateLimitStore(), staticOwners{}, nil, &config.Config{
+				RateLimitIP:             100,
+				RateLimitUser:           100,
+				RefreshLockoutThreshold: 3,
+				RefreshLockoutWindow:    time.Minute,
+				RefreshLockoutDuration:  time.Minute,
+			})
+			r := newTestRouter(limiter.Refresh(), "/refresh", tt.status)
+
+			locked := false
+			for i := 0; i < 5; i++ {
+				req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{"refresh_token":"selector.verifier"}`))
+				req.RemoteAddr = "198.51.100.7:1234"
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code == http.StatusTooManyRequests {
+					locked = true
+				}
+			}
+
+			if locked != tt.wantLocked {
+				t.Errorf("locked = %v, want %v", locked, tt.wantLocked)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,31 +9,516 @@ import (
 
 type UserTokenRequest struct {
 	UserID string `form:"user_id" binding:"required,uuid"`
+	Scope  string `form:"scope"`
 }
 
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type,omitempty" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in,omitempty" example:"900"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type SessionParams struct {
+	UserID    string
+	SessionID string
+	UserAgent string
+	IPAddress string
+	ClientID  string
+	Scope     string
+	// Reason попадает в событие login, по умолчанию token_issued
+	Reason string
+	// AMR — методы аутентификации пользователя (RFC 8176), попадают в claim amr
+	AMR []string
+}
+
+type Client struct {
+	ID              string      `json:"client_id"`
+	Name            string      `json:"name"`
+	SecretHash      string      `json:"-"`
+	PublicKey       string      `json:"public_key,omitempty"`
+	Trusted         bool        `json:"trusted"`
+	AllowedSubjects []uuid.UUID `json:"allowed_subjects"`
+	Scopes          []string    `json:"scopes"`
+	Active          bool        `json:"active"`
+	CreatedAt       time.Time   `json:"created_at"`
+	ClientLifetimes
+	BindingPolicy *BindingPolicy `json:"binding_policy,omitempty"`
+}
+
+// ClientLifetimes переопределяет время жизни токенов для клиента, 0 значит значение из конфига.
+type ClientLifetimes struct {
+	AccessTokenTTL     int `json:"access_token_ttl,omitempty" example:"900"`
+	RefreshIdleTimeout int `json:"refresh_idle_timeout,omitempty" example:"86400"`
+	SessionMaxLifetime int `json:"session_max_lifetime,omitempty" example:"2592000"`
+}
+
+type ClientRegistration struct {
+	ID              string      `json:"client_id" binding:"required" example:"billing-service"`
+	Name            string      `json:"name" example:"Billing"`
+	PublicKey       string      `json:"public_key,omitempty" example:"-----BEGIN PUBLIC KEY-----..."`
+	Trusted         bool        `json:"trusted"`
+	AllowedSubjects []uuid.UUID `json:"allowed_subjects"`
+	Scopes          []string    `json:"scopes" example:"profile"`
+	ClientLifetimes
+	BindingPolicy *BindingPolicy `json:"binding_policy,omitempty"`
+}
+
+// Действия политики привязки сессии, от мягкого к строгому.
+const (
+	BindingActionAllow  = "allow"
+	BindingActionNotify = "notify"
+	BindingActionReauth = "reauth"
+	BindingActionRevoke = "revoke"
+)
+
+// BindingPolicy задаёт, что делать при refresh, если изменился сигнал, к которому привязана
+// сессия. Пустое поле значит значение из BINDING_POLICY. allow — только записать решение,
+// notify — записать и отправить webhook, reauth — отказать в refresh, не трогая сессию,
+// revoke — отозвать сессию.
+type BindingPolicy struct {
+	UserAgent string `json:"user_agent,omitempty" binding:"omitempty,oneof=allow notify reauth revoke" example:"notify"`
+	IP        string `json:"ip,omitempty" binding:"omitempty,oneof=allow notify reauth revoke" example:"allow"`
+	Subnet    string `json:"subnet,omitempty" binding:"omitempty,oneof=allow notify reauth revoke" example:"notify"`
+	ASN       string `json:"asn,omitempty" binding:"omitempty,oneof=allow notify reauth revoke" example:"notify"`
+	Country   string `json:"country,omitempty" binding:"omitempty,oneof=allow notify reauth revoke" example:"reauth"`
+}
+
+type ClientCredentials struct {
+	ClientID            string
+	ClientSecret        string
+	ClientAssertionType string
+	ClientAssertion     string
 }
 
 type RefreshRequest struct {
-	AccessToken  string `json:"access_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	RefreshToken string `json:"refresh_token" binding:"required" example:"d9f8a7b3f2e3c4b5..."`
+	AccessToken  string `json:"access_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" binding:"required" example:"q9Xc2mV0bR7w1LkT3nY8aA.d9f8a7b3f2e3c4b5..."`
 }
 
 type RefreshTokenRecord struct {
-	ID               uuid.UUID `db:"id"`
-	UserID           uuid.UUID `db:"user_id"`
-	RefreshTokenHash string    `db:"refresh_token_hash"`
-	UserAgent        string    `db:"user_agent"`
-	IPAddress        string    `db:"ip_address"`
-	Revoked          bool      `db:"revoked"`
-	CreatedAt        time.Time `db:"created_at"`
+	ID               uuid.UUID  `db:"id"`
+	FamilyID         uuid.UUID  `db:"family_id"`
+	ParentID         *uuid.UUID `db:"parent_id"`
+	UserID           uuid.UUID  `db:"user_id"`
+	Selector         string     `db:"selector"`
+	RefreshTokenHash string     `db:"refresh_token_hash"`
+	UserAgent        string     `db:"user_agent"`
+	IPAddress        string     `db:"ip_address"`
+	Device           string     `db:"device"`
+	ClientID         *string    `db:"client_id"`
+	Scope            string     `db:"scope"`
+	Revoked          bool       `db:"revoked"`
+	CreatedAt        time.Time  `db:"created_at"`
+	LastUsedAt       time.Time  `db:"last_used_at"`
+	ExpiresAt        time.Time  `db:"expires_at"`
+	SessionExpiresAt time.Time  `db:"session_expires_at"`
+	AMR              []string   `db:"amr"`
+	AuthTime         *time.Time `db:"auth_time"`
+	Rotated          bool       `db:"-"`
+}
+
+type Session struct {
+	ID         uuid.UUID `json:"id" example:"0b6f7a2e-3f7c-4c1e-9d55-5d1c7b6a2f10"`
+	Device     string    `json:"device" example:"iPhone"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"`
+	IPAddress  string    `json:"ip_address" example:"203.0.113.10"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+// SecurityEventSchemaVersion меняется только при несовместимом изменении SecurityEvent.
+const SecurityEventSchemaVersion = 1
+
+// Типы событий безопасности. Это стабильные имена: на них подписываются webhook-и
+// и по ним фильтрует SIEM, в X-Event-Type они уходят с префиксом "security.".
+const (
+	SecurityEventLogin               = "login"
+	SecurityEventLogout              = "logout"
+	SecurityEventRefresh             = "refresh"
+	SecurityEventRefreshDenied       = "refresh_denied"
+	SecurityEventIPChange            = "ip_change"
+	SecurityEventUserAgentMismatch   = "user_agent_mismatch"
+	SecurityEventInvalidRefreshToken = "invalid_refresh_token"
+	SecurityEventTokenReuse          = "token_reuse"
+	SecurityEventSessionRevoked      = "session_revoked"
+	SecurityEventPasswordReset       = "password_reset"
+	SecurityEventMFAEnabled          = "mfa_enabled"
+	SecurityEventMFADisabled         = "mfa_disabled"
+	SecurityEventPasskeyAdded        = "passkey_added"
+	SecurityEventPasskeyRemoved      = "passkey_removed"
+)
+
+var SecurityEventTypes = []string{
+	SecurityEventLogin,
+	SecurityEventLogout,
+	SecurityEventRefresh,
+	SecurityEventRefreshDenied,
+	SecurityEventIPChange,
+	SecurityEventUserAgentMismatch,
+	SecurityEventInvalidRefreshToken,
+	SecurityEventTokenReuse,
+	SecurityEventSessionRevoked,
+	SecurityEventPasswordReset,
+	SecurityEventMFAEnabled,
+	SecurityEventMFADisabled,
+	SecurityEventPasskeyAdded,
+	SecurityEventPasskeyRemoved,
+}
+
+// Причины событий: машиночитаемые коды, по одному на каждое решение TokenService.
+const (
+	ReasonTokenIssued          = "token_issued"
+	ReasonTokenRotated         = "token_rotated"
+	ReasonIPAddressChanged     = "ip_address_changed"
+	ReasonUserAgentChanged     = "user_agent_changed"
+	ReasonVerifierMismatch     = "verifier_mismatch"
+	ReasonRotatedTokenReused   = "rotated_token_reused"
+	ReasonSessionRevoked       = "session_revoked"
+	ReasonRefreshTokenExpired  = "refresh_token_expired"
+	ReasonConcurrentRotation   = "concurrent_rotation"
+	ReasonUserLogout           = "user_logout"
+	ReasonUserRequest          = "user_request"
+	ReasonOtherSessionsRevoked = "other_sessions_revoked"
+	ReasonAdminRequest         = "admin_request"
+	ReasonClientRevocation     = "client_revocation"
+	ReasonRefreshTokenNotFound = "refresh_token_not_found"
+	ReasonSubnetChanged        = "subnet_changed"
+	ReasonASNChanged           = "asn_changed"
+	ReasonCountryChanged       = "country_changed"
+	ReasonPasswordVerified     = "password_verified"
+	ReasonInvalidCredentials   = "invalid_credentials"
+	ReasonEmailNotVerified     = "email_not_verified"
+	ReasonResetTokenVerified   = "reset_token_verified"
+	ReasonTOTPVerified         = "totp_verified"
+	ReasonRecoveryCodeUsed     = "recovery_code_used"
+	ReasonInvalidMFACode       = "invalid_mfa_code"
+	ReasonPasskeyVerified      = "passkey_verified"
+	ReasonInvalidPasskey       = "invalid_passkey"
+	ReasonSignCountRollback    = "sign_count_rollback"
+)
+
+// SecurityEvent — событие безопасности в стабильной схеме: так оно хранится в security_events
+// и в outbox webhook-ов.
+type SecurityEvent struct {
+	ID                uuid.UUID  `json:"event_id" db:"id"`
+	SchemaVersion     int        `json:"schema_version" db:"-" example:"1"`
+	Type              string     `json:"type" db:"event_type" example:"ip_change"`
+	UserID            uuid.UUID  `json:"user_id" db:"user_id"`
+	SessionID         *uuid.UUID `json:"session_id" db:"session_id"`
+	ClientID          string     `json:"client_id,omitempty" db:"client_id"`
+	IPAddress         string     `json:"ip_address" db:"ip_address"`
+	PreviousIPAddress string     `json:"previous_ip_address,omitempty" db:"previous_ip_address"`
+	UserAgent         string     `json:"user_agent" db:"user_agent"`
+	Reason            string     `json:"reason" db:"reason" example:"ip_address_changed"`
+	Action            string     `json:"action,omitempty" db:"action" example:"notify"`
+	CreatedAt         time.Time  `json:"occurred_at" db:"created_at"`
 }
 
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty" example:"access_token"`
+}
+
+// WebhookPayload — исходный формат тела webhook. Поля после Event добавлены позже,
+// старые получатели их просто игнорируют.
 type WebhookPayload struct {
-	UserID    string `json:"user_id"`
-	IPAddress string `json:"ip_address"`
-	UserAgent string `json:"user_agent"`
-	Event     string `json:"event"`
+	UserID     string     `json:"user_id"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	Event      string     `json:"event"`
+	EventID    uuid.UUID  `json:"event_id"`
+	SessionID  *uuid.UUID `json:"session_id"`
+	Reason     string     `json:"reason"`
+	OccurredAt time.Time  `json:"occurred_at"`
+}
+
+// NewWebhookPayload переводит событие в исходный формат. Для ip_change и token_reuse
+// Event сохраняет имена, которые получатели видели до появления каталога событий.
+func NewWebhookPayload(event SecurityEvent) WebhookPayload {
+	name := event.Type
+	switch event.Type {
+	case SecurityEventIPChange:
+		name = "ip_address_changed"
+	case SecurityEventTokenReuse:
+		name = "refresh_token_reused"
+	}
+
+	return WebhookPayload{
+		UserID:     event.UserID.String(),
+		IPAddress:  event.IPAddress,
+		UserAgent:  event.UserAgent,
+		Event:      name,
+		EventID:    event.ID,
+		SessionID:  event.SessionID,
+		Reason:     event.Reason,
+		OccurredAt: event.CreatedAt,
+	}
+}
+
+// Форматы тела webhook: исходный WebhookPayload или CloudEvents 1.0 в structured/binary HTTP режиме.
+const (
+	WebhookFormatLegacy                = "legacy"
+	WebhookFormatCloudEventsStructured = "cloudevents-structured"
+	WebhookFormatCloudEventsBinary     = "cloudevents-binary"
+)
+
+type WebhookSubscription struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url" example:"https://siem.example.com/hooks/auth"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"event_types" example:"ip_change,token_reuse"`
+	Format     string    `json:"format" example:"cloudevents-structured"`
+	Active     bool      `json:"active" example:"true"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookSubscriptionRequest создаёт или заменяет подписку. event_types — типы из SecurityEventTypes,
+// пустой список означает все события. При создании пустой secret генерируется, пустой format
+// означает legacy; при обновлении пустые secret и format оставляют прежние значения.
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required,url" example:"https://siem.example.com/hooks/auth"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"event_types" example:"ip_change,token_reuse"`
+	Format     string   `json:"format,omitempty" binding:"omitempty,oneof=legacy cloudevents-structured cloudevents-binary" example:"cloudevents-structured"`
+	Active     *bool    `json:"active,omitempty" example:"true"`
+}
+
+type WebhookOutboxEvent struct {
+	ID             uuid.UUID         `json:"id"`
+	SubscriptionID *uuid.UUID        `json:"subscription_id,omitempty"`
+	EventType      string            `json:"event_type" example:"security.ip_change"`
+	Payload        json.RawMessage   `json:"payload" swaggertype:"object"`
+	Headers        map[string]string `json:"headers"`
+	Status         string            `json:"status" example:"dead"`
+	Attempts       int               `json:"attempts" example:"10"`
+	NextAttemptAt  time.Time         `json:"next_attempt_at"`
+	LastError      string            `json:"last_error" example:"webhook вернул статус 503"`
+	CreatedAt      time.Time         `json:"created_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at,omitempty"`
+}
+
+// Исход и тип инициатора записи аудита.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+
+	AuditActorUser      = "user"
+	AuditActorClient    = "client"
+	AuditActorAdmin     = "admin"
+	AuditActorAnonymous = "anonymous"
+)
+
+// Действия аудита, которые пишут обработчики. Решения TokenService пишутся
+// с action, равным типу SecurityEvent.
+const (
+	AuditActionClientAuthentication = "client_authentication"
+	AuditActionSubjectAuthorization = "subject_authorization"
+	AuditActionClientRegistration   = "client_registration"
+	AuditActionWebhookSubscription  = "webhook_subscription"
+	AuditActionUserCreation         = "user_creation"
+	AuditActionPasswordLogin        = "password_login"
+	AuditActionUserRegistration     = "user_registration"
+	AuditActionEmailVerification    = "email_verification"
+	AuditActionPasswordForgot       = "password_forgot"
+	AuditActionMFALogin             = "mfa_login"
+	AuditActionPasskeyLogin         = "passkey_login"
+	AuditActionWebhookReplay        = "webhook_replay"
+)
+
+// AuditEntry — строка auth_audit_log: кто (actor) что сделал (action) с чьей сессией (subject, session)
+// и чем это закончилось. Target — объект действий администратора: kid, клиент, подписка.
+// Hash покрывает содержимое строки и PrevHash, так что правка любой строки рвёт цепочку.
+type AuditEntry struct {
+	ID         uuid.UUID  `json:"id"`
+	Seq        int64      `json:"seq"`
+	OccurredAt time.Time  `json:"occurred_at"`
+	Action     string     `json:"action" example:"refresh"`
+	ActorType  string     `json:"actor_type" example:"user"`
+	ActorID    string     `json:"actor_id,omitempty"`
+	SubjectID  *uuid.UUID `json:"subject_id,omitempty"`
+	SessionID  *uuid.UUID `json:"session_id,omitempty"`
+	ClientID   string     `json:"client_id,omitempty"`
+	Target     string     `json:"target,omitempty"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	Outcome    string     `json:"outcome" example:"success"`
+	Reason     string     `json:"reason" example:"token_rotated"`
+	PrevHash   string     `json:"prev_hash"`
+	Hash       string     `json:"hash"`
+}
+
+// AuditCheckpoint фиксирует хэш цепочки аудита на seq; Signature — JWT с claims seq и hash,
+// подписанный ключом token.Manager.
+type AuditCheckpoint struct {
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditQuery — фильтры GET /admin/audit, все необязательные.
+type AuditQuery struct {
+	Action    string    `form:"action"`
+	ActorType string    `form:"actor_type"`
+	ActorID   string    `form:"actor_id"`
+	SubjectID string    `form:"subject_id" binding:"omitempty,uuid"`
+	SessionID string    `form:"session_id" binding:"omitempty,uuid"`
+	ClientID  string    `form:"client_id"`
+	Outcome   string    `form:"outcome" binding:"omitempty,oneof=success failure"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `form:"limit"`
+	Offset    int       `form:"offset"`
+}
+
+// RateLimitState — состояние token bucket для одного ключа. Нулевой UpdatedAt означает
+// новый ключ с полным bucket-ом.
+type RateLimitState struct {
+	Tokens      float64
+	UpdatedAt   time.Time
+	LockedUntil time.Time
+}
+
+type User struct {
+	ID           uuid.UUID `json:"id"`
+	Email        string    `json:"email,omitempty" example:"user@example.com"`
+	Username     string    `json:"username,omitempty" example:"ivan"`
+	PasswordHash string    `json:"-"`
+	// EmailVerifiedAt пуст, пока адрес не подтверждён; вход до этого запрещён
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// CreateUserRequest — нужен email или username; username без @, чтобы вход по логину был однозначным.
+type CreateUserRequest struct {
+	Email    string `json:"email" binding:"omitempty,email,max=254" example:"user@example.com"`
+	Username string `json:"username" binding:"omitempty,excludes=@,max=64" example:"ivan"`
+	Password string `json:"password" binding:"required,min=8,max=1024" example:"correct horse battery staple"`
+}
+
+// RegisterRequest — самостоятельная регистрация, email обязателен: на него уходит ссылка подтверждения.
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required,email,max=254" example:"user@example.com"`
+	Username string `json:"username" binding:"omitempty,excludes=@,max=64" example:"ivan"`
+	Password string `json:"password" binding:"required,min=8,max=1024" example:"correct horse battery staple"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=1024" example:"correct horse battery staple"`
+}
+
+// PasswordReset — одноразовый токен сброса пароля; как и refresh token, хранится
+// как selector и хэш verifier.
+type PasswordReset struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Selector     string
+	VerifierHash string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	UsedAt       *time.Time
+}
+
+// EmailVerification — одноразовая ссылка подтверждения email. Email запоминается, чтобы
+// ссылка подтверждала именно тот адрес, на который ушла.
+type EmailVerification struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Email        string
+	Selector     string
+	VerifierHash string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	UsedAt       *time.Time
+}
+
+// LoginRequest — login это email или username.
+type LoginRequest struct {
+	Login    string `json:"login" binding:"required" example:"user@example.com"`
+	Password string `json:"password" binding:"required,max=1024" example:"correct horse battery staple"`
+}
+
+// Методы аутентификации для claim amr (RFC 8176).
+const (
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRMultiFactor = "mfa"
+	AMRHardwareKey = "hwk"
+)
+
+type UserTOTP struct {
+	UserID           uuid.UUID
+	SecretCiphertext []byte
+	ConfirmedAt      *time.Time
+	LastUsedStep     int64
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/Auth:user@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Auth"`
+}
+
+type MFACodeRequest struct {
+	// Code — код из приложения или recovery code
+	Code string `json:"code" binding:"required,max=64" example:"123456"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k3v7q-m2x9p"`
+}
+
+// MFAChallenge — ответ /login для пользователя с TOTP: токены выдаются после /login/mfa.
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required" example:"true"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in" example:"300"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required,max=64" example:"123456"`
+}
+
+// Passkey — ключ WebAuthn пользователя.
+type Passkey struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"-"`
+	CredentialID []byte    `json:"-"`
+	PublicKey    []byte    `json:"-"`
+	// Algorithm — алгоритм COSE: -7 ES256, -8 EdDSA, -257 RS256
+	Algorithm int64 `json:"algorithm" example:"-7"`
+	// SignCount — счётчик подписей с последнего входа; 0, если аутентификатор его не ведёт
+	SignCount  int64     `json:"sign_count" example:"0"`
+	AAGUID     uuid.UUID `json:"aaguid"`
+	Transports []string  `json:"transports" example:"internal"`
+	// BackupEligible и BackupState — может ли ключ синхронизироваться между устройствами и синхронизирован ли он
+	BackupEligible bool       `json:"backup_eligible"`
+	BackupState    bool       `json:"backup_state"`
+	Name           string     `json:"name" example:"MacBook"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
 }
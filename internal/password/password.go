@@ -0,0 +1,113 @@
+// Package password хэширует пароли Argon2id и хранит их в PHC-формате
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>, чтобы параметры можно было менять без миграции.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var ErrInvalidHash = errors.New("невалидный хэш пароля")
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// Params — параметры Argon2id: память в KiB, число проходов и потоков.
+type Params struct {
+	Memory      int
+	Iterations  int
+	Parallelism int
+}
+
+type Hasher struct {
+	params Params
+}
+
+func NewHasher(params Params) (*Hasher, error) {
+	if params.Parallelism < 1 || params.Parallelism > 255 || params.Iterations < 1 ||
+		params.Memory < 8*params.Parallelism || params.Memory > math.MaxUint32 {
+		return nil, fmt.Errorf("невалидные параметры argon2id: m=%d, t=%d, p=%d", params.Memory, params.Iterations, params.Parallelism)
+	}
+
+	return &Hasher{params: params}, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := idKey(password, salt, h.params, keyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.params.Memory,
+		h.params.Iterations,
+		h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify сверяет пароль с хэшем с параметрами из самого хэша. needsRehash сообщает,
+// что хэш посчитан с другими параметрами и его стоит пересчитать после успешного входа.
+func (h *Hasher) Verify(password, encoded string) (ok bool, needsRehash bool, err error) {
+	params, salt, key, err := decode(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	candidate := idKey(password, salt, params, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return false, false, nil
+	}
+
+	return true, params != h.params || len(salt) != saltLength || len(key) != keyLength, nil
+}
+
+func idKey(password string, salt []byte, params Params, length uint32) []byte {
+	return argon2.IDKey([]byte(password), salt, uint32(params.Iterations), uint32(params.Memory), uint8(params.Parallelism), length)
+}
+
+func decode(encoded string) (Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	var params Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	if _, err := NewHasher(params); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	return params, salt, key, nil
+}
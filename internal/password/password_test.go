@@ -0,0 +1,149 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Маленькие параметры, чтобы тесты не тратили 64 MiB на каждый хэш.
+var testParams = Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestNewHasherValidatesParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{"valid", testParams, false},
+		{"no iterations", Params{Memory: 64, Iterations: 0, Parallelism: 1}, true},
+		{"no parallelism", Params{Memory: 64, Iterations: 1, Parallelism: 0}, true},
+		{"too many threads", Params{Memory: 8 * 256, Iterations: 1, Parallelism: 256}, true},
+		{"memory below 8*p", Params{Memory: 15, Iterations: 1, Parallelism: 2}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHasher(tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("NewHasher(%+v) error = %v, wantErr %v", tt.params, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashAndVerify(t *testing.T) {
+	hasher, err := NewHasher(testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Hash() = %q, want PHC argon2id prefix", hash)
+	}
+
+	other, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Error("Hash() returned the same string twice, salt is not random")
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"correct", "correct horse", true},
+		{"wrong", "correct horse!", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := hasher.Verify(tt.password, hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.want || needsRehash {
+				t.Errorf("Verify(%q) = %v, %v, want %v, false", tt.password, ok, needsRehash, tt.want)
+			}
+		})
+	}
+}
+
+// Хэш со старыми параметрами проверяется по параметрам из самого хэша и помечается для пересчёта.
+func TestVerifyNeedsRehash(t *testing.T) {
+	old, err := NewHasher(testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := old.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		params Params
+		want   bool
+	}{
+		{"same params", testParams, false},
+		{"more memory", Params{Memory: 128, Iterations: 1, Parallelism: 1}, true},
+		{"more iterations", Params{Memory: 64, Iterations: 2, Parallelism: 1}, true},
+		{"more threads", Params{Memory: 64, Iterations: 1, Parallelism: 2}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewHasher(tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, needsRehash, err := hasher.Verify("secret", hash)
+			if err != nil || !ok {
+				t.Fatalf("Verify() = %v, %v, want ok", ok, err)
+			}
+			if needsRehash != tt.want {
+				t.Errorf("needsRehash = %v, want %v", needsRehash, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsMalformedHash(t *testing.T) {
+	hasher, err := NewHasher(testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"bcrypt", "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"},
+		{"argon2i", "$argon2i$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaA"},
+		{"wrong version", "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaA"},
+		{"bad params", "$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHQ$aGFzaA"},
+		{"invalid params", "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$aGFzaA"},
+		{"bad salt", "$argon2id$v=19$m=64,t=1,p=1$!!!$aGFzaA"},
+		{"bad key", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$!!!"},
+		{"empty key", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$"},
+		{"missing part", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := hasher.Verify("secret", tt.hash); !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrInvalidHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Package proxyproto разбирает заголовок PROXY protocol v1 и v2 (HAProxy) на входящих
+// соединениях, чтобы за L4 балансировщиком виден был адрес клиента, а не балансировщика.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	ErrNoHeader      = errors.New("proxyproto: нет заголовка PROXY protocol")
+	ErrInvalidHeader = errors.New("proxyproto: невалидный заголовок PROXY protocol")
+)
+
+// v1 ограничивает строку 107 байтами вместе с CRLF.
+const v1MaxLength = 107
+
+// Listener читает заголовок только у соединений от доверенных адресов, и от них он обязателен:
+// спецификация запрещает угадывать, есть ли заголовок. Остальные соединения проходят как есть.
+type Listener struct {
+	net.Listener
+	trusted       func(netip.Addr) bool
+	headerTimeout time.Duration
+}
+
+func NewListener(inner net.Listener, trusted func(netip.Addr) bool, headerTimeout time.Duration) *Listener {
+	return &Listener{Listener: inner, trusted: trusted, headerTimeout: headerTimeout}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	peer, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok || !l.trusted(peer.AddrPort().Addr()) {
+		return conn, nil
+	}
+
+	// заголовок читается лениво: Accept не должен ждать медленного клиента
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn), headerTimeout: l.headerTimeout}, nil
+}
+
+type Conn struct {
+	net.Conn
+	reader        *bufio.Reader
+	headerTimeout time.Duration
+
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	return c.reader.Read(b)
+}
+
+// RemoteAddr возвращает адрес клиента из заголовка, а для LOCAL и UNKNOWN — адрес прокси.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) readHeader() {
+	if c.headerTimeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.headerTimeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+
+	c.remoteAddr, c.err = ReadHeader(c.reader)
+	if c.err != nil {
+		c.Conn.Close()
+	}
+}
+
+// ReadHeader читает заголовок v1 или v2 и возвращает адрес источника; nil значит, что
+// заголовок есть, но адреса в нём нет (LOCAL, UNKNOWN, неизвестное семейство адресов).
+func ReadHeader(r *bufio.Reader) (net.Addr, error) {
+	// самый короткий заголовок, "PROXY UNKNOWN\r\n", длиннее сигнатуры v2
+	peek, err := r.Peek(len(v2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoHeader, err)
+	}
+
+	switch {
+	case bytes.Equal(peek, v2Signature):
+		return readV2(r)
+	case bytes.HasPrefix(peek, v1Prefix):
+		return readV1(r)
+	default:
+		return nil, ErrNoHeader
+	}
+}
+
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < v1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if bytes.HasSuffix(line, []byte("\r\n")) {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("%w: строка v1 длиннее %d байт", ErrInvalidHeader, v1MaxLength)
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) < 2 {
+		return nil, ErrInvalidHeader
+	}
+
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, fmt.Errorf("%w: протокол %q", ErrInvalidHeader, fields[1])
+	}
+
+	if len(fields) != 6 {
+		return nil, ErrInvalidHeader
+	}
+
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil || addr.Is4() != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("%w: адрес %q", ErrInvalidHeader, fields[2])
+	}
+
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: порт %q", ErrInvalidHeader, fields[4])
+	}
+
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("%w: версия %d", ErrInvalidHeader, header[12]>>4)
+	}
+
+	command := header[12] & 0x0f
+	family := header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	switch command {
+	case 0x0: // LOCAL: соединение открыл сам прокси, например health check
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("%w: команда %d", ErrInvalidHeader, command)
+	}
+
+	// TLV после адресов не нужны и пропускаются вместе с payload
+	switch family {
+	case 0x11, 0x12: // TCP и UDP поверх IPv4
+		if len(payload) < 12 {
+			return nil, ErrInvalidHeader
+		}
+		addr := netip.AddrFrom4([4]byte(payload[0:4]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(payload[8:10]))), nil
+	case 0x21, 0x22: // TCP и UDP поверх IPv6
+		if len(payload) < 36 {
+			return nil, ErrInvalidHeader
+		}
+		addr := netip.AddrFrom16([16]byte(payload[0:16]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(payload[32:34]))), nil
+	default:
+		return nil, nil
+	}
+}
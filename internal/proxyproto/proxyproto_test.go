@@ -0,0 +1,166 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// v2Header собирает заголовок v2 с командой command, семейством family и payload.
+func v2Header(command, family byte, payload []byte) []byte {
+	header := append([]byte{}, v2Signature...)
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+
+	return append(header, payload...)
+}
+
+func v2IPv4(src, dst [4]byte, srcPort, dstPort uint16) []byte {
+	payload := append(src[:], dst[:]...)
+	payload = binary.BigEndian.AppendUint16(payload, srcPort)
+
+	return binary.BigEndian.AppendUint16(payload, dstPort)
+}
+
+func v2IPv6(src, dst netip.Addr, srcPort, dstPort uint16) []byte {
+	payload := append(src.AsSlice(), dst.AsSlice()...)
+	payload = binary.BigEndian.AppendUint16(payload, srcPort)
+
+	return binary.BigEndian.AppendUint16(payload, dstPort)
+}
+
+func TestReadHeader(t *testing.T) {
+	ipv4 := v2IPv4([4]byte{203, 0, 113, 7}, [4]byte{10, 0, 0, 1}, 51234, 443)
+	ipv6 := v2IPv6(netip.MustParseAddr("2001:db8::7"), netip.MustParseAddr("2001:db8::1"), 51234, 443)
+
+	tests := []struct {
+		name     string
+		input    []byte
+		wantAddr string
+		wantErr  error
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n"), "203.0.113.7:51234", nil},
+		{"v1 tcp6", []byte("PROXY TCP6 2001:db8::7 2001:db8::1 51234 443\r\n"), "[2001:db8::7]:51234", nil},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\n"), "", nil},
+		{"v1 unknown with addresses", []byte("PROXY UNKNOWN 203.0.113.7 10.0.0.1 51234 443\r\n"), "", nil},
+		{"v1 family mismatch", []byte("PROXY TCP4 2001:db8::7 2001:db8::1 51234 443\r\n"), "", ErrInvalidHeader},
+		{"v1 bad address", []byte("PROXY TCP4 203.0.113.999 10.0.0.1 51234 443\r\n"), "", ErrInvalidHeader},
+		{"v1 bad port", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 70000 443\r\n"), "", ErrInvalidHeader},
+		{"v1 missing fields", []byte("PROXY TCP4 203.0.113.7 10.0.0.1\r\n"), "", ErrInvalidHeader},
+		{"v1 unknown protocol", []byte("PROXY UDP4 203.0.113.7 10.0.0.1 51234 443\r\n"), "", ErrInvalidHeader},
+		{"v1 too long", []byte("PROXY TCP4 " + strings.Repeat("1", v1MaxLength) + "\r\n"), "", ErrInvalidHeader},
+		{"v2 tcp4", v2Header(0x1, 0x11, ipv4), "203.0.113.7:51234", nil},
+		{"v2 udp4", v2Header(0x1, 0x12, ipv4), "203.0.113.7:51234", nil},
+		{"v2 tcp6", v2Header(0x1, 0x21, ipv6), "[2001:db8::7]:51234", nil},
+		{"v2 with tlv", v2Header(0x1, 0x11, append(ipv4, 0x04, 0x00, 0x01, 0xff)), "203.0.113.7:51234", nil},
+		{"v2 local", v2Header(0x0, 0x00, nil), "", nil},
+		{"v2 unix socket", v2Header(0x1, 0x31, make([]byte, 216)), "", nil},
+		{"v2 short ipv4", v2Header(0x1, 0x11, ipv4[:8]), "", ErrInvalidHeader},
+		{"v2 short ipv6", v2Header(0x1, 0x21, ipv6[:32]), "", ErrInvalidHeader},
+		{"v2 unknown command", v2Header(0x2, 0x11, ipv4), "", ErrInvalidHeader},
+		{"v2 wrong version", append(append(append([]byte{}, v2Signature...), 0x11, 0x11, 0x00, 0x0c), ipv4...), "", ErrInvalidHeader},
+		{"plain http", []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"), "", ErrNoHeader},
+		{"too short", []byte("PROXY"), "", ErrNoHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := ReadHeader(bufio.NewReader(bytes.NewReader(tt.input)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadHeader() error = %v, want %v", err, tt.wantErr)
+			}
+
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.wantAddr {
+				t.Errorf("ReadHeader() = %q, want %q", got, tt.wantAddr)
+			}
+		})
+	}
+}
+
+// Данные после заголовка должны остаться в reader для HTTP-сервера.
+func TestReadHeaderKeepsPayload(t *testing.T) {
+	for name, header := range map[string][]byte{
+		"v1": []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n"),
+		"v2": v2Header(0x1, 0x11, v2IPv4([4]byte{203, 0, 113, 7}, [4]byte{10, 0, 0, 1}, 51234, 443)),
+	} {
+		reader := bufio.NewReader(bytes.NewReader(append(header, "GET / HTTP/1.1\r\n"...)))
+		if _, err := ReadHeader(reader); err != nil {
+			t.Fatalf("%s: ReadHeader() error = %v", name, err)
+		}
+
+		rest, _ := io.ReadAll(reader)
+		if string(rest) != "GET / HTTP/1.1\r\n" {
+			t.Errorf("%s: rest = %q", name, rest)
+		}
+	}
+}
+
+func TestListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("нет loopback:", err)
+	}
+	defer inner.Close()
+
+	tests := []struct {
+		name     string
+		trusted  bool
+		send     string
+		wantAddr string
+		wantData string
+		wantErr  bool
+	}{
+		{"trusted with header", true, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nhello", "203.0.113.7:51234", "hello", false},
+		{"trusted without header", true, "hello, world!!!!", "", "", true},
+		{"untrusted keeps header", false, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n", "", "PROXY", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener := NewListener(inner, func(netip.Addr) bool { return tt.trusted }, time.Second)
+
+			client, err := net.Dial("tcp", inner.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			if _, err := client.Write([]byte(tt.send)); err != nil {
+				t.Fatal(err)
+			}
+
+			conn, err := listener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			wantAddr := tt.wantAddr
+			if wantAddr == "" {
+				wantAddr = client.LocalAddr().String()
+			}
+			if got := conn.RemoteAddr().String(); got != wantAddr {
+				t.Errorf("RemoteAddr() = %q, want %q", got, wantAddr)
+			}
+
+			data := make([]byte, 5)
+			_, err = io.ReadFull(conn, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(data) != tt.wantData {
+				t.Errorf("Read() = %q, want %q", data, tt.wantData)
+			}
+		})
+	}
+}
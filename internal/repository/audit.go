@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/net/context"
+)
+
+const auditEntryColumns = `id, seq, occurred_at, action, actor_type, actor_id, subject_id, session_id, client_id,
+	target, ip_address, user_agent, outcome, reason, prev_hash, hash`
+
+func scanAuditEntry(row pgx.Row) (model.AuditEntry, error) {
+	var entry model.AuditEntry
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.Seq,
+		&entry.OccurredAt,
+		&entry.Action,
+		&entry.ActorType,
+		&entry.ActorID,
+		&entry.SubjectID,
+		&entry.SessionID,
+		&entry.ClientID,
+		&entry.Target,
+		&entry.IPAddress,
+		&entry.UserAgent,
+		&entry.Outcome,
+		&entry.Reason,
+		&entry.PrevHash,
+		&entry.Hash,
+	)
+	if err != nil {
+		return entry, fmt.Errorf("не удалось прочитать запись аудита: %w", err)
+	}
+
+	return entry, nil
+}
+
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// auditChainLock — ключ pg_advisory_xact_lock, под которым дописывается цепочка аудита.
+const auditChainLock = 7_042_017
+
+// auditPendingBatch — сколько записей переносится в цепочку за одну транзакцию.
+const auditPendingBatch = 500
+
+const auditPendingColumns = `id, occurred_at, action, actor_type, actor_id, subject_id, session_id, client_id,
+	target, ip_address, user_agent, outcome, reason`
+
+// Record ставит запись аудита в очередь цепочки; в auth_audit_log её переносит ChainPending.
+func (r *AuditRepository) Record(ctx context.Context, entry model.AuditEntry) error {
+	return insertAuditEntry(ctx, r.db, entry)
+}
+
+// RecordAudit ставит запись аудита в очередь в транзакции WithTx или без неё. Цепочка здесь
+// не блокируется: бизнес-транзакции не должны ждать друг друга из-за аудита.
+func (r *TokenRepository) RecordAudit(ctx context.Context, entry model.AuditEntry) error {
+	return insertAuditEntry(ctx, r.db, entry)
+}
+
+func insertAuditEntry(ctx context.Context, db querier, entry model.AuditEntry) error {
+	// TIMESTAMP хранит микросекунды в UTC: хэш считается от того, что потом прочитается из БД
+	entry.OccurredAt = entry.OccurredAt.UTC().Truncate(time.Microsecond)
+
+	query := `
+		INSERT INTO auth_audit_pending (` + auditPendingColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	_, err := db.Exec(
+		ctx,
+		query,
+		entry.ID,
+		entry.OccurredAt,
+		entry.Action,
+		entry.ActorType,
+		entry.ActorID,
+		entry.SubjectID,
+		entry.SessionID,
+		entry.ClientID,
+		entry.Target,
+		entry.IPAddress,
+		entry.UserAgent,
+		entry.Outcome,
+		entry.Reason,
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось записать аудит: %w", err)
+	}
+
+	return nil
+}
+
+// ChainPending переносит очередь в auth_audit_log, дописывая prev_hash и hash. Каждая пачка —
+// отдельная короткая транзакция под advisory lock, поэтому несколько экземпляров сервиса
+// не порвут цепочку. Возвращает число перенесённых записей.
+func (r *AuditRepository) ChainPending(ctx context.Context) (int, error) {
+	total := 0
+
+	for {
+		n, err := r.chainPendingBatch(ctx)
+		total += n
+		if err != nil || n < auditPendingBatch {
+			return total, err
+		}
+	}
+}
+
+func (r *AuditRepository) chainPendingBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return 0, fmt.Errorf("не удалось заблокировать цепочку аудита: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT seq, `+auditPendingColumns+`
+		FROM auth_audit_pending
+		ORDER BY seq
+		LIMIT $1
+	`, auditPendingBatch)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось прочитать очередь аудита: %w", err)
+	}
+
+	var pending []int64
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.AuditEntry, error) {
+		var seq int64
+		var entry model.AuditEntry
+
+		err := row.Scan(
+			&seq,
+			&entry.ID,
+			&entry.OccurredAt,
+			&entry.Action,
+			&entry.ActorType,
+			&entry.ActorID,
+			&entry.SubjectID,
+			&entry.SessionID,
+			&entry.ClientID,
+			&entry.Target,
+			&entry.IPAddress,
+			&entry.UserAgent,
+			&entry.Outcome,
+			&entry.Reason,
+		)
+		pending = append(pending, seq)
+
+		return entry, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("не удалось прочитать очередь аудита: %w", err)
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	var prevHash string
+	err = tx.QueryRow(ctx, `SELECT hash FROM auth_audit_log ORDER BY seq DESC LIMIT 1`).Scan(&prevHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("не удалось прочитать конец цепочки аудита: %w", err)
+	}
+
+	query := `
+		INSERT INTO auth_audit_log (` + auditPendingColumns + `, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+
+	for _, entry := range entries {
+		entry.PrevHash = prevHash
+		entry.Hash = AuditEntryHash(entry)
+
+		_, err := tx.Exec(
+			ctx,
+			query,
+			entry.ID,
+			entry.OccurredAt,
+			entry.Action,
+			entry.ActorType,
+			entry.ActorID,
+			entry.SubjectID,
+			entry.SessionID,
+			entry.ClientID,
+			entry.Target,
+			entry.IPAddress,
+			entry.UserAgent,
+			entry.Outcome,
+			entry.Reason,
+			entry.PrevHash,
+			entry.Hash,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("не удалось записать аудит: %w", err)
+		}
+
+		prevHash = entry.Hash
+	}
+
+	// удаляются ровно прочитанные seq: запись с меньшим seq из ещё не закоммиченной
+	// транзакции могла появиться после SELECT и попадёт в следующую пачку
+	if _, err := tx.Exec(ctx, `DELETE FROM auth_audit_pending WHERE seq = ANY($1)`, pending); err != nil {
+		return 0, fmt.Errorf("не удалось очистить очередь аудита: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("не удалось записать аудит: %w", err)
+	}
+
+	return len(entries), nil
+}
+
+// AuditEntryHash — hex SHA-256 от prev_hash и содержимого строки. Seq в хэш не входит:
+// он выдаётся базой, а порядок и так задаётся ссылкой на prev_hash.
+func AuditEntryHash(entry model.AuditEntry) string {
+	uuidString := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		return id.String()
+	}
+
+	fields := []string{
+		entry.PrevHash,
+		entry.ID.String(),
+		entry.OccurredAt.UTC().Format(time.RFC3339Nano),
+		entry.Action,
+		entry.ActorType,
+		entry.ActorID,
+		uuidString(entry.SubjectID),
+		uuidString(entry.SessionID),
+		entry.ClientID,
+		entry.Target,
+		entry.IPAddress,
+		entry.UserAgent,
+		entry.Outcome,
+		entry.Reason,
+	}
+
+	// JSON-массив однозначно разделяет поля, в отличие от склейки через разделитель
+	data, _ := json.Marshal(fields)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *AuditRepository) List(ctx context.Context, q model.AuditQuery) ([]model.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if q.Action != "" {
+		where("action = ?", q.Action)
+	}
+	if q.ActorType != "" {
+		where("actor_type = ?", q.ActorType)
+	}
+	if q.ActorID != "" {
+		where("actor_id = ?", q.ActorID)
+	}
+	if q.SubjectID != "" {
+		where("subject_id = ?", q.SubjectID)
+	}
+	if q.SessionID != "" {
+		where("session_id = ?", q.SessionID)
+	}
+	if q.ClientID != "" {
+		where("client_id = ?", q.ClientID)
+	}
+	if q.Outcome != "" {
+		where("outcome = ?", q.Outcome)
+	}
+	if !q.From.IsZero() {
+		where("occurred_at >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		where("occurred_at < ?", q.To)
+	}
+
+	query := `
+		SELECT ` + auditEntryColumns + `
+		FROM auth_audit_log
+	`
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ") + "\n"
+	}
+
+	args = append(args, q.Limit, q.Offset)
+	query += fmt.Sprintf("ORDER BY seq DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить аудит: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/net/context"
+)
+
+const auditChainBatch = 1000
+
+// AuditChainBreak — первая строка, на которой цепочка аудита не сходится.
+type AuditChainBreak struct {
+	Seq    int64
+	ID     uuid.UUID
+	Reason string
+}
+
+// VerifyChain проходит auth_audit_log по возрастанию seq и пересчитывает хэши. Строки без hash
+// в начале таблицы записаны до появления цепочки и пропускаются; первая строка с hash должна
+// начинать цепочку с пустым prev_hash, иначе из начала цепочки удалены строки. Возвращает
+// число проверенных строк и первый разрыв, если он есть.
+func (r *AuditRepository) VerifyChain(ctx context.Context) (int64, *AuditChainBreak, error) {
+	var checked, after int64
+	prevHash := ""
+	started := false
+
+	for {
+		rows, err := r.db.Query(ctx, `
+			SELECT `+auditEntryColumns+`
+			FROM auth_audit_log
+			WHERE seq > $1
+			ORDER BY seq
+			LIMIT $2
+		`, after, auditChainBatch)
+		if err != nil {
+			return checked, nil, fmt.Errorf("не удалось прочитать аудит: %w", err)
+		}
+
+		entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.AuditEntry, error) {
+			return scanAuditEntry(row)
+		})
+		if err != nil {
+			return checked, nil, err
+		}
+		if len(entries) == 0 {
+			return checked, nil, nil
+		}
+
+		for _, entry := range entries {
+			after = entry.Seq
+
+			if !started && entry.Hash == "" {
+				continue
+			}
+
+			if !started && entry.PrevHash != "" {
+				return checked, &AuditChainBreak{Seq: entry.Seq, ID: entry.ID, Reason: "первая строка цепочки ссылается на отсутствующую строку"}, nil
+			}
+			if started && entry.PrevHash != prevHash {
+				return checked, &AuditChainBreak{Seq: entry.Seq, ID: entry.ID, Reason: "prev_hash не совпадает с hash предыдущей строки"}, nil
+			}
+			if AuditEntryHash(entry) != entry.Hash {
+				return checked, &AuditChainBreak{Seq: entry.Seq, ID: entry.ID, Reason: "hash не совпадает с содержимым строки"}, nil
+			}
+
+			started = true
+			prevHash = entry.Hash
+			checked++
+		}
+	}
+}
+
+// AuditHead возвращает seq и hash последней строки цепочки; ok == false, если цепочка пуста.
+func (r *AuditRepository) AuditHead(ctx context.Context) (int64, string, bool, error) {
+	var seq int64
+	var hash string
+
+	err := r.db.QueryRow(ctx, `SELECT seq, hash FROM auth_audit_log WHERE hash <> '' ORDER BY seq DESC LIMIT 1`).Scan(&seq, &hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", false, nil
+	}
+	if err != nil {
+		return 0, "", false, fmt.Errorf("не удалось прочитать конец цепочки аудита: %w", err)
+	}
+
+	return seq, hash, true, nil
+}
+
+// AuditHashAt возвращает hash строки с данным seq.
+func (r *AuditRepository) AuditHashAt(ctx context.Context, seq int64) (string, bool, error) {
+	var hash string
+
+	err := r.db.QueryRow(ctx, `SELECT hash FROM auth_audit_log WHERE seq = $1`, seq).Scan(&hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("не удалось прочитать запись аудита: %w", err)
+	}
+
+	return hash, true, nil
+}
+
+func (r *AuditRepository) LastCheckpointSeq(ctx context.Context) (int64, error) {
+	var seq int64
+
+	if err := r.db.QueryRow(ctx, `SELECT COALESCE(MAX(seq), 0) FROM audit_checkpoints`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("не удалось прочитать контрольную точку: %w", err)
+	}
+
+	return seq, nil
+}
+
+func (r *AuditRepository) CreateCheckpoint(ctx context.Context, checkpoint model.AuditCheckpoint) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO audit_checkpoints (seq, hash, signature, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (seq) DO NOTHING
+	`, checkpoint.Seq, checkpoint.Hash, checkpoint.Signature, checkpoint.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить контрольную точку: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AuditRepository) ListCheckpoints(ctx context.Context) ([]model.AuditCheckpoint, error) {
+	rows, err := r.db.Query(ctx, `SELECT seq, hash, signature, created_at FROM audit_checkpoints ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить контрольные точки: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []model.AuditCheckpoint
+	for rows.Next() {
+		var checkpoint model.AuditCheckpoint
+		if err := rows.Scan(&checkpoint.Seq, &checkpoint.Hash, &checkpoint.Signature, &checkpoint.CreatedAt); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать контрольную точку: %w", err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	return checkpoints, rows.Err()
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"hh/internal/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAuditEntryHash(t *testing.T) {
+	subject := uuid.New()
+	session := uuid.New()
+
+	base := model.AuditEntry{
+		ID:         uuid.New(),
+		OccurredAt: time.Date(2024, 1, 1, 12, 0, 0, 123456000, time.UTC),
+		Action:     model.SecurityEventRefresh,
+		ActorType:  model.AuditActorUser,
+		ActorID:    subject.String(),
+		SubjectID:  &subject,
+		SessionID:  &session,
+		ClientID:   "billing",
+		IPAddress:  "10.0.0.1",
+		UserAgent:  "curl",
+		Outcome:    model.AuditOutcomeSuccess,
+		Reason:     model.ReasonTokenRotated,
+		PrevHash:   "previous",
+	}
+	hash := AuditEntryHash(base)
+
+	if len(hash) != 64 {
+		t.Fatalf("AuditEntryHash() = %q, want hex SHA-256", hash)
+	}
+
+	// то же содержимое, прочитанное из БД, даёт тот же hash
+	same := base
+	same.Seq = 42
+	same.Hash = "stored"
+	same.OccurredAt = base.OccurredAt.In(time.FixedZone("MSK", 3*60*60))
+	if got := AuditEntryHash(same); got != hash {
+		t.Errorf("hash depends on seq, stored hash or time zone: %s != %s", got, hash)
+	}
+
+	other := uuid.New()
+	tests := []struct {
+		name   string
+		modify func(*model.AuditEntry)
+	}{
+		{"prev hash", func(e *model.AuditEntry) { e.PrevHash = "" }},
+		{"id", func(e *model.AuditEntry) { e.ID = uuid.New() }},
+		{"occurred at", func(e *model.AuditEntry) { e.OccurredAt = e.OccurredAt.Add(time.Microsecond) }},
+		{"action", func(e *model.AuditEntry) { e.Action = model.SecurityEventLogin }},
+		{"actor type", func(e *model.AuditEntry) { e.ActorType = model.AuditActorAdmin }},
+		{"actor id", func(e *model.AuditEntry) { e.ActorID = "" }},
+		{"subject", func(e *model.AuditEntry) { e.SubjectID = &other }},
+		{"no subject", func(e *model.AuditEntry) { e.SubjectID = nil }},
+		{"session", func(e *model.AuditEntry) { e.SessionID = nil }},
+		{"client", func(e *model.AuditEntry) { e.ClientID = "gateway" }},
+		{"target", func(e *model.AuditEntry) { e.Target = "kid" }},
+		{"ip", func(e *model.AuditEntry) { e.IPAddress = "10.0.0.2" }},
+		{"user agent", func(e *model.AuditEntry) { e.UserAgent = "" }},
+		{"outcome", func(e *model.AuditEntry) { e.Outcome = model.AuditOutcomeFailure }},
+		{"reason", func(e *model.AuditEntry) { e.Reason = model.ReasonAdminRequest }},
+		// перенос текста между соседними полями не должен давать тот же hash
+		{"field boundary", func(e *model.AuditEntry) { e.IPAddress, e.UserAgent = "10.0.0.1curl", "" }},
+	}
+
+	for _, tt := range tests {
+		entry := base
+		tt.modify(&entry)
+		if AuditEntryHash(entry) == hash {
+			t.Errorf("%s: hash did not change", tt.name)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/net/context"
+)
+
+var ErrClientNotFound = errors.New("клиент не найден")
+
+type ClientRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewClientRepository(db *pgxpool.Pool) *ClientRepository {
+	return &ClientRepository{db: db}
+}
+
+func (r *ClientRepository) GetClient(ctx context.Context, clientID string) (*model.Client, error) {
+	query := `
+		SELECT id, name, COALESCE(secret_hash, ''), COALESCE(public_key, ''), trusted, allowed_subjects, scopes, active, created_at,
+			COALESCE(access_token_ttl_seconds, 0), COALESCE(refresh_idle_timeout_seconds, 0), COALESCE(session_max_lifetime_seconds, 0),
+			binding_policy
+		FROM clients
+		WHERE id = $1
+	`
+
+	var client model.Client
+	err := r.db.QueryRow(ctx, query, clientID).Scan(
+		&client.ID,
+		&client.Name,
+		&client.SecretHash,
+		&client.PublicKey,
+		&client.Trusted,
+		&client.AllowedSubjects,
+		&client.Scopes,
+		&client.Active,
+		&client.CreatedAt,
+		&client.AccessTokenTTL,
+		&client.RefreshIdleTimeout,
+		&client.SessionMaxLifetime,
+		&client.BindingPolicy,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить клиента: %w", err)
+	}
+
+	return &client, nil
+}
+
+func (r *ClientRepository) CreateClient(ctx context.Context, client model.Client) error {
+	query := `
+		INSERT INTO clients (
+			id, name, secret_hash, public_key, trusted, allowed_subjects, scopes, active, created_at,
+			access_token_ttl_seconds, refresh_idle_timeout_seconds, session_max_lifetime_seconds, binding_policy
+		)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, NULLIF($10, 0), NULLIF($11, 0), NULLIF($12, 0), $13)
+	`
+
+	_, err := r.db.Exec(
+		ctx,
+		query,
+		client.ID,
+		client.Name,
+		client.SecretHash,
+		client.PublicKey,
+		client.Trusted,
+		client.AllowedSubjects,
+		client.Scopes,
+		client.Active,
+		client.CreatedAt,
+		client.AccessTokenTTL,
+		client.RefreshIdleTimeout,
+		client.SessionMaxLifetime,
+		client.BindingPolicy,
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось создать клиента: %w", err)
+	}
+
+	return nil
+}
+
+// UseAssertionID запоминает jti client_assertion; false значит, что assertion уже предъявлялся.
+func (r *ClientRepository) UseAssertionID(ctx context.Context, clientID, jti string, expiresAt time.Time) (bool, error) {
+	if _, err := r.db.Exec(ctx, `DELETE FROM client_assertion_jtis WHERE expires_at < now()`); err != nil {
+		return false, fmt.Errorf("не удалось очистить jti: %w", err)
+	}
+
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO client_assertion_jtis (client_id, jti, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, clientID, jti, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("не удалось сохранить jti: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
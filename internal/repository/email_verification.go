@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/net/context"
+)
+
+var ErrEmailVerificationNotFound = errors.New("ссылка подтверждения не найдена")
+
+func (r *UserRepository) CreateEmailVerification(ctx context.Context, verification model.EmailVerification) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO email_verifications (user_id, email, selector, verifier_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, verification.UserID, verification.Email, verification.Selector, verification.VerifierHash, verification.CreatedAt, verification.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить ссылку подтверждения: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) GetEmailVerificationBySelector(ctx context.Context, selector string) (*model.EmailVerification, error) {
+	var verification model.EmailVerification
+
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, email, selector, verifier_hash, created_at, expires_at, used_at
+		FROM email_verifications
+		WHERE selector = $1
+	`, selector).Scan(
+		&verification.ID,
+		&verification.UserID,
+		&verification.Email,
+		&verification.Selector,
+		&verification.VerifierHash,
+		&verification.CreatedAt,
+		&verification.ExpiresAt,
+		&verification.UsedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEmailVerificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить ссылку подтверждения: %w", err)
+	}
+
+	return &verification, nil
+}
+
+// ConsumeEmailVerification помечает ссылку использованной, а вместе с ней и остальные
+// неиспользованные ссылки пользователя. false значит, что ссылку уже использовали.
+func (r *UserRepository) ConsumeEmailVerification(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `UPDATE email_verifications SET used_at = now() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("не удалось использовать ссылку подтверждения: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = r.db.Exec(ctx, `UPDATE email_verifications SET used_at = now() WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return false, fmt.Errorf("не удалось отозвать ссылки подтверждения: %w", err)
+	}
+
+	return true, nil
+}
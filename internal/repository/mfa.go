@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/net/context"
+)
+
+var (
+	ErrTOTPNotFound       = errors.New("TOTP не подключён")
+	ErrTOTPAlreadyEnabled = errors.New("TOTP уже подключён")
+)
+
+func (r *UserRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*model.UserTOTP, error) {
+	var totp model.UserTOTP
+
+	err := r.db.QueryRow(ctx, `
+		SELECT user_id, secret_ciphertext, confirmed_at, last_used_step
+		FROM user_totp
+		WHERE user_id = $1
+	`, userID).Scan(&totp.UserID, &totp.SecretCiphertext, &totp.ConfirmedAt, &totp.LastUsedStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTOTPNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить TOTP: %w", err)
+	}
+
+	return &totp, nil
+}
+
+// SavePendingTOTP сохраняет новый неподтверждённый секрет поверх прежнего неподтверждённого.
+// Подтверждённый секрет не перезаписывается: сначала TOTP нужно отключить.
+func (r *UserRepository) SavePendingTOTP(ctx context.Context, userID uuid.UUID, ciphertext []byte) error {
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO user_totp (user_id, secret_ciphertext)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_ciphertext = EXCLUDED.secret_ciphertext, last_used_step = 0, created_at = now()
+		WHERE user_totp.confirmed_at IS NULL
+	`, userID, ciphertext)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить TOTP: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTP включает TOTP и запоминает шаг кода, которым его подтвердили.
+// false значит, что TOTP уже подтверждён или этот код уже предъявляли.
+func (r *UserRepository) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE user_totp SET confirmed_at = now(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("не удалось подтвердить TOTP: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// UseTOTPStep принимает код шага step, только если он новее последнего принятого.
+// false значит, что код уже использовали.
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE user_totp SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("не удалось использовать код TOTP: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// DeleteTOTP отключает TOTP и удаляет recovery codes.
+func (r *UserRepository) DeleteTOTP(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("не удалось удалить recovery codes: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("не удалось удалить TOTP: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes заменяет все recovery codes пользователя новыми хэшами.
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("не удалось удалить recovery codes: %w", err)
+	}
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])
+	`, userID, hashes)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode помечает код использованным. false значит, что кода нет или он уже использован.
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, hash)
+	if err != nil {
+		return false, fmt.Errorf("не удалось использовать recovery code: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// UseMFAToken помечает mfa_token использованным; false значит, что его уже обменяли на токены
+// или он сгорел после maxFailures неверных кодов.
+func (r *UserRepository) UseMFAToken(ctx context.Context, jti string, expiresAt time.Time, maxFailures int) (bool, error) {
+	if _, err := r.db.Exec(ctx, `DELETE FROM mfa_token_uses WHERE expires_at < now()`); err != nil {
+		return false, fmt.Errorf("не удалось очистить mfa_token: %w", err)
+	}
+
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO mfa_token_uses (jti, expires_at, used)
+		VALUES ($1, $2, true)
+		ON CONFLICT (jti) DO UPDATE SET used = true
+		WHERE mfa_token_uses.used = false AND mfa_token_uses.failed_attempts < $3
+	`, jti, expiresAt, maxFailures)
+	if err != nil {
+		return false, fmt.Errorf("не удалось сохранить mfa_token: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// RecordMFATokenFailure засчитывает mfa_token неверный код. Вызывается вне транзакции проверки
+// кода: она при неверном коде откатывается вместе с UseMFAToken.
+func (r *UserRepository) RecordMFATokenFailure(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO mfa_token_uses (jti, expires_at, failed_attempts)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (jti) DO UPDATE SET failed_attempts = mfa_token_uses.failed_attempts + 1
+	`, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("не удалось засчитать неверный код mfa_token: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/net/context"
+)
+
+var (
+	ErrPasskeyNotFound = errors.New("passkey не найден")
+	ErrPasskeyExists   = errors.New("passkey уже зарегистрирован")
+)
+
+const passkeyColumns = `id, user_id, credential_id, public_key, algorithm, sign_count, aaguid, transports,
+	backup_eligible, backup_state, name, created_at, last_used_at`
+
+func scanPasskey(row pgx.Row) (*model.Passkey, error) {
+	var passkey model.Passkey
+
+	err := row.Scan(
+		&passkey.ID,
+		&passkey.UserID,
+		&passkey.CredentialID,
+		&passkey.PublicKey,
+		&passkey.Algorithm,
+		&passkey.SignCount,
+		&passkey.AAGUID,
+		&passkey.Transports,
+		&passkey.BackupEligible,
+		&passkey.BackupState,
+		&passkey.Name,
+		&passkey.CreatedAt,
+		&passkey.LastUsedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPasskeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать passkey: %w", err)
+	}
+
+	return &passkey, nil
+}
+
+func (r *UserRepository) CreatePasskey(ctx context.Context, passkey model.Passkey) (*model.Passkey, error) {
+	query := `
+		INSERT INTO webauthn_credentials (user_id, credential_id, public_key, algorithm, sign_count, aaguid, transports, backup_eligible, backup_state, name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + passkeyColumns
+
+	created, err := scanPasskey(r.db.QueryRow(ctx, query,
+		passkey.UserID,
+		passkey.CredentialID,
+		passkey.PublicKey,
+		passkey.Algorithm,
+		passkey.SignCount,
+		passkey.AAGUID,
+		passkey.Transports,
+		passkey.BackupEligible,
+		passkey.BackupState,
+		passkey.Name,
+	))
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrPasskeyExists
+	}
+
+	return created, err
+}
+
+func (r *UserRepository) GetPasskeyByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error) {
+	query := `SELECT ` + passkeyColumns + ` FROM webauthn_credentials WHERE credential_id = $1`
+
+	return scanPasskey(r.db.QueryRow(ctx, query, credentialID))
+}
+
+func (r *UserRepository) ListPasskeys(ctx context.Context, userID uuid.UUID) ([]model.Passkey, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+passkeyColumns+` FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить passkey: %w", err)
+	}
+	defer rows.Close()
+
+	passkeys := []model.Passkey{}
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, *passkey)
+	}
+
+	return passkeys, rows.Err()
+}
+
+// UsePasskey сохраняет новый счётчик подписей, только если с момента чтения его никто
+// не менял. false значит, что параллельно прошёл вход тем же ключом.
+func (r *UserRepository) UsePasskey(ctx context.Context, id uuid.UUID, oldSignCount, newSignCount int64, backupState bool) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE webauthn_credentials
+		SET sign_count = $3, backup_state = $4, last_used_at = now()
+		WHERE id = $1 AND sign_count = $2
+	`, id, oldSignCount, newSignCount, backupState)
+	if err != nil {
+		return false, fmt.Errorf("не удалось обновить passkey: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *UserRepository) DeletePasskey(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("не удалось удалить passkey: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPasskeyNotFound
+	}
+
+	return nil
+}
+
+// DeleteAllPasskeys удаляет все passkey пользователя и возвращает, сколько их было.
+func (r *UserRepository) DeleteAllPasskeys(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM webauthn_credentials WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось удалить passkey: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// UseWebAuthnChallenge запоминает jti challenge; false значит, что его уже использовали.
+func (r *UserRepository) UseWebAuthnChallenge(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	if _, err := r.db.Exec(ctx, `DELETE FROM webauthn_challenges WHERE expires_at < now()`); err != nil {
+		return false, fmt.Errorf("не удалось очистить challenge: %w", err)
+	}
+
+	result, err := r.db.Exec(ctx, `
+		INSERT INTO webauthn_challenges (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, jti, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("не удалось сохранить challenge: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
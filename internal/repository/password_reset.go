@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/net/context"
+)
+
+var ErrPasswordResetNotFound = errors.New("токен сброса пароля не найден")
+
+func (r *UserRepository) CreatePasswordReset(ctx context.Context, reset model.PasswordReset) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO password_resets (user_id, selector, verifier_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, reset.UserID, reset.Selector, reset.VerifierHash, reset.CreatedAt, reset.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить токен сброса пароля: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) GetPasswordResetBySelector(ctx context.Context, selector string) (*model.PasswordReset, error) {
+	var reset model.PasswordReset
+
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, selector, verifier_hash, created_at, expires_at, used_at
+		FROM password_resets
+		WHERE selector = $1
+	`, selector).Scan(
+		&reset.ID,
+		&reset.UserID,
+		&reset.Selector,
+		&reset.VerifierHash,
+		&reset.CreatedAt,
+		&reset.ExpiresAt,
+		&reset.UsedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPasswordResetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить токен сброса пароля: %w", err)
+	}
+
+	return &reset, nil
+}
+
+// ConsumePasswordReset помечает токен использованным, а вместе с ним и остальные
+// неиспользованные токены пользователя. false значит, что токен уже использовали.
+func (r *UserRepository) ConsumePasswordReset(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(ctx, `UPDATE password_resets SET used_at = now() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("не удалось использовать токен сброса пароля: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = r.db.Exec(ctx, `UPDATE password_resets SET used_at = now() WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return false, fmt.Errorf("не удалось отозвать токены сброса пароля: %w", err)
+	}
+
+	return true, nil
+}
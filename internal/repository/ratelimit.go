@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"fmt"
+	"hh/internal/model"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/net/context"
+)
+
+const rateLimitPruneInterval = time.Minute
+
+// RateLimitRepository хранит bucket-ы rate limit в Postgres, чтобы лимиты были общими
+// для всех инстансов сервиса.
+type RateLimitRepository struct {
+	db        *pgxpool.Pool
+	lastPrune atomic.Int64
+}
+
+func NewRateLimitRepository(db *pgxpool.Pool) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Update выполняет fn над состоянием key под блокировкой строки и сохраняет результат.
+func (r *RateLimitRepository) Update(ctx context.Context, key string, fn func(state *model.RateLimitState)) error {
+	r.prune(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO rate_limits (key) VALUES ($1) ON CONFLICT DO NOTHING`, key); err != nil {
+		return fmt.Errorf("не удалось создать rate limit: %w", err)
+	}
+
+	var state model.RateLimitState
+	var updatedAt, lockedUntil *time.Time
+
+	err = tx.QueryRow(ctx, `SELECT tokens, updated_at, locked_until FROM rate_limits WHERE key = $1 FOR UPDATE`, key).
+		Scan(&state.Tokens, &updatedAt, &lockedUntil)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать rate limit: %w", err)
+	}
+	if updatedAt != nil {
+		state.UpdatedAt = *updatedAt
+	}
+	if lockedUntil != nil {
+		state.LockedUntil = *lockedUntil
+	}
+
+	fn(&state)
+
+	lockedUntil = nil
+	if !state.LockedUntil.IsZero() {
+		lockedUntil = &state.LockedUntil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE rate_limits
+		SET tokens = $2, updated_at = $3, locked_until = $4
+		WHERE key = $1
+	`, key, state.Tokens, state.UpdatedAt, lockedUntil)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить rate limit: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// prune не чаще раза в минуту удаляет ключи, которые давно не трогали: их bucket уже полон.
+func (r *RateLimitRepository) prune(ctx context.Context) {
+	now := time.Now().UnixNano()
+	last := r.lastPrune.Load()
+	if now-last < int64(rateLimitPruneInterval) || !r.lastPrune.CompareAndSwap(last, now) {
+		return
+	}
+
+	r.db.Exec(ctx, `
+		DELETE FROM rate_limits
+		WHERE (updated_at IS NULL OR updated_at < now() - interval '1 day') AND (locked_until IS NULL OR locked_until < now())
+	`)
+}
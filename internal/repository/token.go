@@ -1,83 +1,230 @@
 package repository
 
 import (
-	"bytes"
-	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"hh/internal/model"
-	"io"
-	"net/http"
+	"hh/pkg/webhooksig"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/net/context"
+)
 
-	"hh/config"
+var (
+	ErrSessionNotActive     = errors.New("сессия не найдена или отозвана")
+	ErrRefreshTokenNotFound = errors.New("refresh token не найден")
 )
 
 type TokenRepository struct {
-	db         *pgxpool.Pool
-	httpClient *http.Client
-	cfg        *config.Config
+	pool *pgxpool.Pool
+	db   querier
 }
 
-func NewTokenRepository(db *pgxpool.Pool, httpClient *http.Client, cfg *config.Config) *TokenRepository {
-	return &TokenRepository{db: db, httpClient: httpClient, cfg: cfg}
+func NewTokenRepository(db *pgxpool.Pool) *TokenRepository {
+	return &TokenRepository{pool: db, db: db}
+}
+
+// WithTx выполняет fn в транзакции: все вызовы через переданный repo идут в одну транзакцию.
+func (r *TokenRepository) WithTx(ctx context.Context, fn func(repo *TokenRepository) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&TokenRepository{pool: r.pool, db: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *TokenRepository) GetTokens(ctx context.Context, token model.RefreshTokenRecord) (model.RefreshTokenRecord, error) {
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+func insertRefreshToken(ctx context.Context, db querier, token model.RefreshTokenRecord) (model.RefreshTokenRecord, error) {
 	query := `
-		INSERT INTO refresh_tokens (id, user_id, refresh_token_hash, user_agent, ip_address, revoked, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, user_id, refresh_token_hash, user_agent, ip_address, revoked, created_at;
+		INSERT INTO refresh_tokens (
+			id, family_id, parent_id, user_id, selector, refresh_token_hash, user_agent, ip_address, device,
+			client_id, scope, revoked, created_at, last_used_at, expires_at, session_expires_at, amr, auth_time
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13, $14, $15, $16, $17)
+		RETURNING ` + refreshTokenColumns + `, false;
 	`
 
-	var savedToken model.RefreshTokenRecord
-	err := r.db.QueryRow(
+	savedToken, err := scanRefreshToken(db.QueryRow(
 		ctx,
 		query,
 		token.ID,
+		token.FamilyID,
+		token.ParentID,
 		token.UserID,
+		token.Selector,
 		token.RefreshTokenHash,
 		token.UserAgent,
 		token.IPAddress,
+		token.Device,
+		token.ClientID,
+		token.Scope,
 		token.Revoked,
 		token.CreatedAt,
-	).Scan(
-		&savedToken.ID,
-		&savedToken.UserID,
-		&savedToken.RefreshTokenHash,
-		&savedToken.UserAgent,
-		&savedToken.IPAddress,
-		&savedToken.Revoked,
-		&savedToken.CreatedAt,
+		token.ExpiresAt,
+		token.SessionExpiresAt,
+		token.AMR,
+		token.AuthTime,
+	))
+	if err != nil {
+		return model.RefreshTokenRecord{}, err
+	}
+
+	return *savedToken, nil
+}
+
+const refreshTokenColumns = `id, family_id, parent_id, user_id, COALESCE(selector, ''), refresh_token_hash, user_agent, ip_address, device, client_id, scope, revoked, created_at, last_used_at, expires_at, session_expires_at, amr, auth_time`
+
+func scanRefreshToken(row pgx.Row) (*model.RefreshTokenRecord, error) {
+	var token model.RefreshTokenRecord
+
+	err := row.Scan(
+		&token.ID,
+		&token.FamilyID,
+		&token.ParentID,
+		&token.UserID,
+		&token.Selector,
+		&token.RefreshTokenHash,
+		&token.UserAgent,
+		&token.IPAddress,
+		&token.Device,
+		&token.ClientID,
+		&token.Scope,
+		&token.Revoked,
+		&token.CreatedAt,
+		&token.LastUsedAt,
+		&token.ExpiresAt,
+		&token.SessionExpiresAt,
+		&token.AMR,
+		&token.AuthTime,
+		&token.Rotated,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *TokenRepository) GetRefreshToken(ctx context.Context, tokenID string) (*model.RefreshTokenRecord, error) {
+	query := `
+		SELECT ` + refreshTokenColumns + `,
+			EXISTS (SELECT 1 FROM refresh_tokens c WHERE c.parent_id = t.id)
+		FROM refresh_tokens t
+		WHERE id = $1;
+	`
+
+	refreshToken, err := scanRefreshToken(r.db.QueryRow(ctx, query, tokenID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
 
+	return refreshToken, nil
+}
+
+func (r *TokenRepository) GetRefreshTokenBySelector(ctx context.Context, selector string) (*model.RefreshTokenRecord, error) {
+	query := `
+		SELECT ` + refreshTokenColumns + `,
+			EXISTS (SELECT 1 FROM refresh_tokens c WHERE c.parent_id = t.id)
+		FROM refresh_tokens t
+		WHERE selector = $1;
+	`
+
+	refreshToken, err := scanRefreshToken(r.db.QueryRow(ctx, query, selector))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
 	if err != nil {
-		return model.RefreshTokenRecord{}, err
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return refreshToken, nil
+}
+
+// RefreshTokenOwner возвращает user_id refresh token по selector — для лимитов /refresh
+// до проверки verifier, поэтому читается только владелец.
+func (r *TokenRepository) RefreshTokenOwner(ctx context.Context, selector string) (string, error) {
+	var userID uuid.UUID
+
+	err := r.db.QueryRow(ctx, `SELECT user_id FROM refresh_tokens WHERE selector = $1`, selector).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get refresh token owner: %w", err)
 	}
 
-	return savedToken, nil
+	return userID.String(), nil
 }
 
-func (r *TokenRepository) GetRefreshToken(ctx context.Context, userID string) (*model.RefreshTokenRecord, error) {
+func (r *TokenRepository) GetActiveRefreshToken(ctx context.Context, familyID string) (*model.RefreshTokenRecord, error) {
 	query := `
-		SELECT refresh_token_hash, user_agent, ip_address, revoked, created_at
-		FROM refresh_tokens
-		WHERE user_id = $1
+		SELECT ` + refreshTokenColumns + `, false
+		FROM refresh_tokens t
+		WHERE family_id = $1 AND revoked = false
 		ORDER BY created_at DESC
 		LIMIT 1;
 	`
-	row := r.db.QueryRow(ctx, query, userID)
 
-	var refreshToken model.RefreshTokenRecord
-	err := row.Scan(&refreshToken.RefreshTokenHash, &refreshToken.UserAgent, &refreshToken.IPAddress, &refreshToken.Revoked, &refreshToken.CreatedAt)
+	refreshToken, err := scanRefreshToken(r.db.QueryRow(ctx, query, familyID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRefreshTokenNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("refreshToken not found")
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
 
-	return &refreshToken, nil
+	return refreshToken, nil
+}
+
+// RotateRefreshToken отзывает текущий токен семьи и сохраняет следующий, вызывать внутри WithTx.
+func (r *TokenRepository) RotateRefreshToken(ctx context.Context, oldTokenID uuid.UUID, next model.RefreshTokenRecord) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked = true
+		WHERE id = $1 AND revoked = false
+	`, oldTokenID)
+	if err != nil {
+		return fmt.Errorf("не удалось отозвать refresh token: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotActive
+	}
+
+	if _, err := insertRefreshToken(ctx, r.db, next); err != nil {
+		return fmt.Errorf("не удалось сохранить refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked = true
+		WHERE family_id = $1 AND revoked = false
+	`
+
+	if _, err := r.db.Exec(ctx, query, familyID); err != nil {
+		return fmt.Errorf("не удалось отозвать сессию: %w", err)
+	}
+
+	return nil
 }
 
 func (r *TokenRepository) RevokeUserTokens(ctx context.Context, userID string) error {
@@ -97,62 +244,147 @@ func (r *TokenRepository) RevokeUserTokens(ctx context.Context, userID string) e
 	return nil
 }
 
-func (r *TokenRepository) SendIpChangeWebhook(ctx context.Context, userID, oldIP, userAgent, newIP string) error {
-	payload := model.WebhookPayload{
-		UserID:    userID,
-		IPAddress: newIP,
-		UserAgent: userAgent,
-		Event:     "ip_address_changed",
-	}
-
-	payloadBytes, _ := json.Marshal(payload)
+// RecordSecurityEvent сохраняет событие в security_events и ставит его в outbox
+// для всех подписанных на этот тип webhook-ов. Решения политики привязки с action allow
+// только сохраняются.
+func (r *TokenRepository) RecordSecurityEvent(ctx context.Context, event model.SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (id, event_type, user_id, session_id, client_id, ip_address, previous_ip_address, user_agent, reason, action, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
 
-	req, err := http.NewRequestWithContext(
+	_, err := r.db.Exec(
 		ctx,
-		"POST",
-		r.cfg.WebhookURL,
-		bytes.NewBuffer(payloadBytes),
+		query,
+		event.ID,
+		event.Type,
+		event.UserID,
+		event.SessionID,
+		event.ClientID,
+		event.IPAddress,
+		event.PreviousIPAddress,
+		event.UserAgent,
+		event.Reason,
+		event.Action,
+		event.CreatedAt,
 	)
 	if err != nil {
-		return fmt.Errorf("не удалось создать webhook запрос")
+		return fmt.Errorf("не удалось записать событие безопасности: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Event-Type", "security.ip_change")
-	req.Header.Set("X-Old-IP", oldIP)
+	if event.Action == model.BindingActionAllow {
+		return nil
+	}
 
-	resp, err := r.httpClient.Do(req)
+	var headers map[string]string
+	if event.PreviousIPAddress != "" {
+		headers = map[string]string{webhooksig.OldIPHeader: event.PreviousIPAddress}
+	}
+
+	return enqueueWebhook(ctx, r.db, event.Type, event, headers)
+}
+
+func (r *TokenRepository) ListSessions(ctx context.Context, userID string) ([]model.Session, error) {
+	query := `
+		SELECT t.family_id, t.device, t.user_agent, t.ip_address,
+			(SELECT min(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id),
+			t.last_used_at
+		FROM refresh_tokens t
+		WHERE t.user_id = $1 AND t.revoked = false AND t.expires_at > now()
+		ORDER BY t.last_used_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
 	if err != nil {
-		return fmt.Errorf("ошибка отправки webhook")
+		return nil, fmt.Errorf("не удалось получить сессии: %w", err)
 	}
-	defer resp.Body.Close()
+	defer rows.Close()
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("webhook вернул статус %d: %s", resp.StatusCode, string(body))
+	sessions := []model.Session{}
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(
+			&session.ID,
+			&session.Device,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+		); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать сессию: %w", err)
+		}
+		sessions = append(sessions, session)
 	}
 
-	return nil
+	return sessions, rows.Err()
 }
 
-func (r *TokenRepository) GetCurrentSessionID(ctx context.Context, userID string) (string, error) {
-	var sessionID string
+func (r *TokenRepository) RevokeUserSession(ctx context.Context, userID, sessionID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked = true
+		WHERE family_id = $1 AND user_id = $2 AND revoked = false
+	`
 
+	result, err := r.db.Exec(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("не удалось отозвать сессию: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotActive
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions отзывает все сессии пользователя, кроме keepSessionID, и возвращает их id.
+func (r *TokenRepository) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) ([]uuid.UUID, error) {
 	query := `
-		SELECT id 
-		FROM refresh_tokens 
-		WHERE user_id = $1 AND revoked = false
-		ORDER BY created_at DESC 
-		LIMIT 1
+		WITH revoked AS (
+			UPDATE refresh_tokens
+			SET revoked = true
+			WHERE user_id = $1 AND family_id <> $2 AND revoked = false
+			RETURNING family_id
+		)
+		SELECT DISTINCT family_id FROM revoked
 	`
-	err := r.db.QueryRow(ctx, query, userID).Scan(&sessionID)
+
+	rows, err := r.db.Query(ctx, query, userID, keepSessionID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", fmt.Errorf("no active session found for user: %s", userID)
+		return nil, fmt.Errorf("не удалось отозвать сессии: %w", err)
+	}
+	defer rows.Close()
+
+	var sessionIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("не удалось отозвать сессии: %w", err)
 		}
-		return "", fmt.Errorf("failed to get current session: %w", err)
+		sessionIDs = append(sessionIDs, id)
 	}
 
-	return sessionID, nil
+	return sessionIDs, rows.Err()
+}
+
+// IsSessionActive сообщает, есть ли у сессии неотозванный refresh token, срок которого
+// не истёк ни по idle-окну, ни по абсолютному сроку жизни сессии.
+func (r *TokenRepository) IsSessionActive(ctx context.Context, sessionID, userID string) (bool, error) {
+	var active bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM refresh_tokens
+			WHERE family_id = $1 AND user_id = $2 AND revoked = false
+				AND expires_at > $3 AND session_expires_at > $3
+		)
+	`
+	err := r.db.QueryRow(ctx, query, sessionID, userID, time.Now()).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
 
+	return active, nil
 }
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/net/context"
+)
+
+var (
+	ErrUserNotFound = errors.New("пользователь не найден")
+	ErrUserExists   = errors.New("пользователь с таким email или username уже существует")
+)
+
+const userColumns = `id, COALESCE(email, ''), COALESCE(username, ''), password_hash, email_verified_at, created_at, updated_at`
+
+type UserRepository struct {
+	pool *pgxpool.Pool
+	db   querier
+}
+
+func NewUserRepository(db *pgxpool.Pool) *UserRepository {
+	return &UserRepository{pool: db, db: db}
+}
+
+// WithTx выполняет fn в транзакции, общей для пользователей и токенов: например, смена
+// пароля и отзыв сессий фиксируются вместе.
+func (r *UserRepository) WithTx(ctx context.Context, fn func(users *UserRepository, tokens *TokenRepository) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&UserRepository{pool: r.pool, db: tx}, &TokenRepository{pool: r.pool, db: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func scanUser(row pgx.Row) (*model.User, error) {
+	var user model.User
+
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.PasswordHash,
+		&user.EmailVerifiedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать пользователя: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, user model.User) (*model.User, error) {
+	query := `
+		INSERT INTO users (email, username, password_hash, email_verified_at)
+		VALUES (NULLIF($1, ''), NULLIF($2, ''), $3, $4)
+		RETURNING ` + userColumns
+
+	created, err := scanUser(r.db.QueryRow(ctx, query, user.Email, user.Username, user.PasswordHash, user.EmailVerifiedAt))
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrUserExists
+	}
+
+	return created, err
+}
+
+func (r *UserRepository) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	return scanUser(r.db.QueryRow(ctx, query, id))
+}
+
+// GetUserByLogin ищет по email или username без учёта регистра.
+func (r *UserRepository) GetUserByLogin(ctx context.Context, login string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE lower(email) = lower($1) OR lower(username) = lower($1)`
+
+	return scanUser(r.db.QueryRow(ctx, query, login))
+}
+
+// GetUserByEmail ищет по email без учёта регистра.
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE lower(email) = lower($1)`
+
+	return scanUser(r.db.QueryRow(ctx, query, email))
+}
+
+// VerifyEmail подтверждает email, если он всё ещё принадлежит пользователю и не подтверждён.
+// false значит, что ссылка уже использована или устарела после смены адреса.
+func (r *UserRepository) VerifyEmail(ctx context.Context, id uuid.UUID, email string) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE users SET email_verified_at = now(), updated_at = now()
+		WHERE id = $1 AND lower(email) = lower($2) AND email_verified_at IS NULL
+	`, id, email)
+	if err != nil {
+		return false, fmt.Errorf("не удалось подтвердить email: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// UpdatePasswordHash перезаписывает хэш, только если он не менялся с момента проверки пароля.
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users SET password_hash = $3, updated_at = now()
+		WHERE id = $1 AND password_hash = $2
+	`, id, oldHash, newHash)
+	if err != nil {
+		return fmt.Errorf("не удалось обновить хэш пароля: %w", err)
+	}
+
+	return nil
+}
+
+// SetPasswordHash меняет пароль и подтверждает email: ссылка сброса пришла на этот адрес.
+func (r *UserRepository) SetPasswordHash(ctx context.Context, id uuid.UUID, hash string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $2, email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+		WHERE id = $1
+	`, id, hash)
+	if err != nil {
+		return fmt.Errorf("не удалось сменить пароль: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/net/context"
+)
+
+const (
+	WebhookStatusPending   = "pending"
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusDead      = "dead"
+)
+
+var ErrWebhookEventNotFound = errors.New("событие не найдено")
+
+// enqueueWebhook кладёт в outbox по строке на каждую активную подписку на eventType.
+// Пустой event_types у подписки означает подписку на все события.
+func enqueueWebhook(ctx context.Context, db querier, eventType string, payload interface{}, headers map[string]string) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	_, err = db.Exec(ctx, `
+		INSERT INTO webhook_outbox (subscription_id, event_type, payload, headers)
+		SELECT id, $1, $2, $3
+		FROM webhook_subscriptions
+		WHERE active AND (cardinality(event_types) = 0 OR $4 = ANY(event_types))
+	`, "security."+eventType, payloadBytes, headers, eventType)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить webhook в outbox: %w", err)
+	}
+
+	return nil
+}
+
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookOutboxColumns = `id, subscription_id, event_type, payload, headers, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+func scanWebhookEvent(row pgx.Row) (model.WebhookOutboxEvent, error) {
+	var event model.WebhookOutboxEvent
+
+	err := row.Scan(
+		&event.ID,
+		&event.SubscriptionID,
+		&event.EventType,
+		&event.Payload,
+		&event.Headers,
+		&event.Status,
+		&event.Attempts,
+		&event.NextAttemptAt,
+		&event.LastError,
+		&event.CreatedAt,
+		&event.DeliveredAt,
+	)
+
+	return event, err
+}
+
+// WebhookDelivery — событие outbox вместе с адресом и секретом его подписки.
+// Found == false, если подписка была удалена или событие создано до появления подписок.
+type WebhookDelivery struct {
+	Event  model.WebhookOutboxEvent
+	URL    string
+	Secret string
+	Format string
+	Active bool
+	Found  bool
+}
+
+// ClaimDue забирает готовые к отправке события и сдвигает их next_attempt_at на lease,
+// чтобы параллельные диспетчеры не отправили одно событие дважды.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_outbox
+			SET next_attempt_at = now() + $2::int * interval '1 second'
+			WHERE id IN (
+				SELECT id
+				FROM webhook_outbox
+				WHERE status = 'pending' AND next_attempt_at <= now()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING ` + webhookOutboxColumns + `
+		)
+		SELECT claimed.*, COALESCE(s.url, ''), COALESCE(s.secret, ''), COALESCE(s.format, 'legacy'), COALESCE(s.active, false), s.id IS NOT NULL
+		FROM claimed
+		LEFT JOIN webhook_subscriptions s ON s.id = claimed.subscription_id
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, int(lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить события outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		e := &d.Event
+
+		err := rows.Scan(
+			&e.ID,
+			&e.SubscriptionID,
+			&e.EventType,
+			&e.Payload,
+			&e.Headers,
+			&e.Status,
+			&e.Attempts,
+			&e.NextAttemptAt,
+			&e.LastError,
+			&e.CreatedAt,
+			&e.DeliveredAt,
+			&d.URL,
+			&d.Secret,
+			&d.Format,
+			&d.Active,
+			&d.Found,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать событие outbox: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_outbox
+		SET status = 'delivered', attempts = attempts + 1, delivered_at = now(), last_error = ''
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("не удалось отметить доставку: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed записывает неудачную попытку: событие либо ждёт nextAttemptAt, либо уходит в dead.
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := WebhookStatusPending
+	if dead {
+		status = WebhookStatusDead
+	}
+
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_outbox
+		SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_error = $4
+		WHERE id = $1
+	`, id, status, nextAttemptAt, lastError)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить ошибку доставки: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) ListDead(ctx context.Context, limit, offset int) ([]model.WebhookOutboxEvent, error) {
+	query := `
+		SELECT ` + webhookOutboxColumns + `
+		FROM webhook_outbox
+		WHERE status = 'dead'
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить dead события: %w", err)
+	}
+	defer rows.Close()
+
+	events := []model.WebhookOutboxEvent{}
+	for rows.Next() {
+		event, err := scanWebhookEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать событие outbox: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *WebhookRepository) Replay(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE webhook_outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), last_error = ''
+		WHERE id = $1 AND status = 'dead'
+	`, id)
+	if err != nil {
+		return fmt.Errorf("не удалось вернуть событие в очередь: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrWebhookEventNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"hh/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/net/context"
+)
+
+var ErrWebhookSubscriptionNotFound = errors.New("подписка не найдена")
+
+const webhookSubscriptionColumns = `id, url, secret, event_types, format, active, created_at, updated_at`
+
+func scanWebhookSubscription(row pgx.Row) (*model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+
+	err := row.Scan(
+		&sub.ID,
+		&sub.URL,
+		&sub.Secret,
+		&sub.EventTypes,
+		&sub.Format,
+		&sub.Active,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать подписку: %w", err)
+	}
+
+	return &sub, nil
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, event_types, format, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + webhookSubscriptionColumns
+
+	return scanWebhookSubscription(r.db.QueryRow(ctx, query, sub.URL, sub.Secret, sub.EventTypes, sub.Format, sub.Active))
+}
+
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*model.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+
+	return scanWebhookSubscription(r.db.QueryRow(ctx, query, id))
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить подписки: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []model.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+
+	return subs, rows.Err()
+}
+
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, sub model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $2, secret = $3, event_types = $4, format = $5, active = $6, updated_at = now()
+		WHERE id = $1
+		RETURNING ` + webhookSubscriptionColumns
+
+	return scanWebhookSubscription(r.db.QueryRow(ctx, query, sub.ID, sub.URL, sub.Secret, sub.EventTypes, sub.Format, sub.Active))
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("не удалось удалить подписку: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrWebhookSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// EnsureSubscription создаёт подписку на url, если её ещё нет, и привязывает к ней
+// события outbox, созданные до появления подписок.
+func (r *WebhookRepository) EnsureSubscription(ctx context.Context, sub model.WebhookSubscription) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT id FROM webhook_subscriptions WHERE url = $1 ORDER BY created_at LIMIT 1`, sub.URL).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = tx.QueryRow(ctx, `
+			INSERT INTO webhook_subscriptions (url, secret, event_types, format, active)
+			VALUES ($1, $2, $3, $4, true)
+			RETURNING id
+		`, sub.URL, sub.Secret, sub.EventTypes, sub.Format).Scan(&id)
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось создать подписку: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE webhook_outbox SET subscription_id = $1 WHERE subscription_id IS NULL`, id); err != nil {
+		return fmt.Errorf("не удалось привязать события outbox: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,73 @@
+// Package secretbox шифрует секреты, которые нужно хранить в БД в обратимом виде,
+// например TOTP-секреты: AES-256-GCM со случайным nonce перед шифротекстом.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var ErrDecrypt = errors.New("не удалось расшифровать секрет")
+
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New принимает ключ из 32 байт.
+func New(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("ключ шифрования должен быть 32 байта, получено %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// NewFromBase64 принимает ключ в base64, например из переменной окружения.
+func NewFromBase64(encoded string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("ключ шифрования: %w", err)
+	}
+
+	return New(key)
+}
+
+// Seal шифрует plaintext. additionalData не шифруется, но привязывается к шифротексту:
+// например ID владельца, чтобы секрет нельзя было переставить другому пользователю.
+func (b *Box) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (b *Box) Open(ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < b.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, sealed := ciphertext[:b.aead.NonceSize()], ciphertext[b.aead.NonceSize():]
+
+	plaintext, err := b.aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/internal/token"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditService struct {
+	auditRepository *repository.AuditRepository
+
+	// checkpointKey подписывает контрольные точки, nil — точки не создаются;
+	// verificationKeys — все ключи, которыми подписывались точки
+	checkpointKey    *token.Key
+	verificationKeys []*token.Key
+}
+
+func NewAuditService(auditRepository *repository.AuditRepository, checkpointKey *token.Key, verificationKeys []*token.Key) *AuditService {
+	return &AuditService{auditRepository: auditRepository, checkpointKey: checkpointKey, verificationKeys: verificationKeys}
+}
+
+// Record ставит запись аудита от имени обработчика в очередь цепочки. Ошибка записи только
+// логируется: запрос, который уже обработан, из-за аудита не падает.
+func (s *AuditService) Record(ctx context.Context, entry model.AuditEntry) {
+	entry.ID = uuid.New()
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now()
+	}
+
+	if err := s.auditRepository.Record(ctx, entry); err != nil {
+		log.Println("audit:", err)
+	}
+}
+
+func (s *AuditService) List(ctx context.Context, q model.AuditQuery) ([]model.AuditEntry, error) {
+	if q.Limit <= 0 || q.Limit > 100 {
+		q.Limit = 100
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	// очередь переносится сразу, чтобы в выдаче были и записи последних секунд
+	if _, err := s.auditRepository.ChainPending(ctx); err != nil {
+		log.Println("audit chain:", err)
+	}
+
+	return s.auditRepository.List(ctx, q)
+}
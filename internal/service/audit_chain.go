@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/internal/token"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// checkpointType — значение typ в JWT контрольной точки.
+const checkpointType = "audit_checkpoint"
+
+// AuditVerification — результат проверки цепочки аудита и контрольных точек.
+type AuditVerification struct {
+	Checked          int64
+	Break            *repository.AuditChainBreak
+	Checkpoints      int
+	CheckpointErrors []string
+}
+
+func (v AuditVerification) OK() bool {
+	return v.Break == nil && len(v.CheckpointErrors) == 0
+}
+
+// RunChainWriter раз в interval переносит очередь аудита в цепочку, пока не отменён ctx.
+func (s *AuditService) RunChainWriter(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.auditRepository.ChainPending(ctx); err != nil {
+			log.Println("audit chain:", err)
+		}
+	}
+}
+
+// RunCheckpoints раз в interval подписывает конец цепочки аудита, пока не отменён ctx.
+// Без ключа AUDIT_SIGNING_KEY_PATH ничего не делает.
+func (s *AuditService) RunCheckpoints(ctx context.Context, interval time.Duration) {
+	if s.checkpointKey == nil {
+		log.Println("audit checkpoint: AUDIT_SIGNING_KEY_PATH не задан, контрольные точки не создаются")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Checkpoint(ctx); err != nil {
+			log.Println("audit checkpoint:", err)
+		}
+	}
+}
+
+// Checkpoint подписывает seq и hash последней строки цепочки, если с прошлой точки были новые записи.
+func (s *AuditService) Checkpoint(ctx context.Context) error {
+	if s.checkpointKey == nil {
+		return errors.New("не задан ключ подписи контрольных точек")
+	}
+
+	seq, hash, ok, err := s.auditRepository.AuditHead(ctx)
+	if err != nil || !ok {
+		return err
+	}
+
+	lastSeq, err := s.auditRepository.LastCheckpointSeq(ctx)
+	if err != nil {
+		return err
+	}
+	if lastSeq >= seq {
+		return nil
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	signature, err := s.checkpointKey.SignJWT(jwt.MapClaims{
+		"typ":  checkpointType,
+		"seq":  seq,
+		"hash": hash,
+		"iat":  now.Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось подписать контрольную точку: %w", err)
+	}
+
+	return s.auditRepository.CreateCheckpoint(ctx, model.AuditCheckpoint{
+		Seq:       seq,
+		Hash:      hash,
+		Signature: signature,
+		CreatedAt: now,
+	})
+}
+
+// Verify пересчитывает цепочку и проверяет, что каждая контрольная точка подписана одним
+// из ключей аудита и совпадает с hash строки, на которую указывает. Записи, которые ещё
+// стоят в очереди, не проверяются: Verify ничего не пишет и работает с доступом только на чтение.
+func (s *AuditService) Verify(ctx context.Context) (AuditVerification, error) {
+	var result AuditVerification
+
+	checked, chainBreak, err := s.auditRepository.VerifyChain(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Checked = checked
+	result.Break = chainBreak
+
+	checkpoints, err := s.auditRepository.ListCheckpoints(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Checkpoints = len(checkpoints)
+
+	for _, checkpoint := range checkpoints {
+		if err := s.verifyCheckpoint(ctx, checkpoint); err != nil {
+			result.CheckpointErrors = append(result.CheckpointErrors, fmt.Sprintf("seq %d: %v", checkpoint.Seq, err))
+		}
+	}
+
+	return result, nil
+}
+
+func (s *AuditService) verifyCheckpoint(ctx context.Context, checkpoint model.AuditCheckpoint) error {
+	claims, err := token.ParseJWTWithKeys(s.verificationKeys, checkpoint.Signature, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return fmt.Errorf("подпись не прошла проверку: %w", err)
+	}
+
+	typ, _ := claims["typ"].(string)
+	seq, _ := claims["seq"].(float64)
+	hash, _ := claims["hash"].(string)
+	if typ != checkpointType || int64(seq) != checkpoint.Seq || hash != checkpoint.Hash {
+		return fmt.Errorf("подписанные seq/hash не совпадают с записью контрольной точки")
+	}
+
+	rowHash, ok, err := s.auditRepository.AuditHashAt(ctx, checkpoint.Seq)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("строка аудита удалена")
+	}
+	if rowHash != checkpoint.Hash {
+		return fmt.Errorf("hash строки аудита не совпадает с контрольной точкой")
+	}
+
+	return nil
+}
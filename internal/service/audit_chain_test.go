@@ -0,0 +1,25 @@
+package service
+
+import (
+	"hh/internal/repository"
+	"testing"
+)
+
+func TestAuditVerificationOK(t *testing.T) {
+	tests := []struct {
+		name   string
+		result AuditVerification
+		want   bool
+	}{
+		{"empty log", AuditVerification{}, true},
+		{"intact chain", AuditVerification{Checked: 10, Checkpoints: 2}, true},
+		{"chain break", AuditVerification{Checked: 3, Break: &repository.AuditChainBreak{Seq: 4}}, false},
+		{"bad checkpoint", AuditVerification{Checked: 10, CheckpointErrors: []string{"seq 5: строка аудита удалена"}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.result.OK(); got != tt.want {
+			t.Errorf("%s: OK() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
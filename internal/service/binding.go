@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"net/netip"
+	"strings"
+)
+
+// defaultBindingPolicy повторяет поведение до появления политик: смена user agent отзывает
+// сессию, смена IP только уведомляет.
+var defaultBindingPolicy = model.BindingPolicy{
+	UserAgent: model.BindingActionRevoke,
+	IP:        model.BindingActionNotify,
+	Subnet:    model.BindingActionAllow,
+	ASN:       model.BindingActionAllow,
+	Country:   model.BindingActionAllow,
+}
+
+var bindingActionRank = map[string]int{
+	model.BindingActionAllow:  0,
+	model.BindingActionNotify: 1,
+	model.BindingActionReauth: 2,
+	model.BindingActionRevoke: 3,
+}
+
+// ParseBindingPolicy разбирает BINDING_POLICY вида "user_agent=notify,country=reauth"
+// поверх политики по умолчанию.
+func ParseBindingPolicy(entries []string) (model.BindingPolicy, error) {
+	policy := defaultBindingPolicy
+
+	for _, entry := range entries {
+		signal, action, ok := strings.Cut(entry, "=")
+		if !ok {
+			return model.BindingPolicy{}, fmt.Errorf("BINDING_POLICY: ожидается signal=action, получено %q", entry)
+		}
+		action = strings.TrimSpace(action)
+		if _, ok := bindingActionRank[action]; !ok {
+			return model.BindingPolicy{}, fmt.Errorf("BINDING_POLICY: неизвестное действие %q", action)
+		}
+
+		switch strings.TrimSpace(signal) {
+		case "user_agent":
+			policy.UserAgent = action
+		case "ip":
+			policy.IP = action
+		case "subnet":
+			policy.Subnet = action
+		case "asn":
+			policy.ASN = action
+		case "country":
+			policy.Country = action
+		default:
+			return model.BindingPolicy{}, fmt.Errorf("BINDING_POLICY: неизвестный сигнал %q", signal)
+		}
+	}
+
+	return policy, nil
+}
+
+// bindingPolicyFor накладывает политику клиента на политику из конфига.
+func (s *TokenService) bindingPolicyFor(ctx context.Context, clientID string) (model.BindingPolicy, error) {
+	policy := s.bindingPolicy
+	if clientID == "" {
+		return policy, nil
+	}
+
+	client, err := s.clientRepository.GetClient(ctx, clientID)
+	if errors.Is(err, repository.ErrClientNotFound) {
+		return policy, nil
+	}
+	if err != nil {
+		return model.BindingPolicy{}, err
+	}
+	if client.BindingPolicy == nil {
+		return policy, nil
+	}
+
+	override := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	override(&policy.UserAgent, client.BindingPolicy.UserAgent)
+	override(&policy.IP, client.BindingPolicy.IP)
+	override(&policy.Subnet, client.BindingPolicy.Subnet)
+	override(&policy.ASN, client.BindingPolicy.ASN)
+	override(&policy.Country, client.BindingPolicy.Country)
+
+	return policy, nil
+}
+
+// evaluateBinding сравнивает сигналы refresh-запроса с сессией и возвращает по событию на
+// каждый изменившийся сигнал с решением политики, а также самое строгое из решений.
+func (s *TokenService) evaluateBinding(ctx context.Context, storedToken *model.RefreshTokenRecord, ip, userAgent string) ([]model.SecurityEvent, string, error) {
+	policy, err := s.bindingPolicyFor(ctx, stringValue(storedToken.ClientID))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var events []model.SecurityEvent
+	strictest := model.BindingActionAllow
+
+	decide := func(eventType, reason, action string) {
+		event := tokenEvent(storedToken, eventType, reason, ip, userAgent)
+		event.Action = action
+		if eventType == model.SecurityEventIPChange {
+			event.PreviousIPAddress = storedToken.IPAddress
+		}
+		events = append(events, event)
+
+		if bindingActionRank[action] > bindingActionRank[strictest] {
+			strictest = action
+		}
+	}
+
+	if storedToken.UserAgent != userAgent {
+		decide(model.SecurityEventUserAgentMismatch, model.ReasonUserAgentChanged, policy.UserAgent)
+	}
+
+	if storedToken.IPAddress != ip {
+		decide(model.SecurityEventIPChange, model.ReasonIPAddressChanged, policy.IP)
+
+		if !sameSubnet(storedToken.IPAddress, ip) {
+			decide(model.SecurityEventIPChange, model.ReasonSubnetChanged, policy.Subnet)
+		}
+
+		// без данных об адресе сигнал не оценивается: отсутствие в таблице не значит смену сети
+		if s.geo != nil {
+			previous, okPrevious := s.geo.Lookup(storedToken.IPAddress)
+			current, okCurrent := s.geo.Lookup(ip)
+			if okPrevious && okCurrent {
+				if previous.ASN != 0 && current.ASN != 0 && previous.ASN != current.ASN {
+					decide(model.SecurityEventIPChange, model.ReasonASNChanged, policy.ASN)
+				}
+				if previous.Country != "" && current.Country != "" && previous.Country != current.Country {
+					decide(model.SecurityEventIPChange, model.ReasonCountryChanged, policy.Country)
+				}
+			}
+		}
+	}
+
+	return events, strictest, nil
+}
+
+// sameSubnet сравнивает адреса по /24 для IPv4 и /64 для IPv6.
+func sameSubnet(a, b string) bool {
+	addrA, errA := netip.ParseAddr(a)
+	addrB, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	addrA, addrB = addrA.Unmap(), addrB.Unmap()
+	if addrA.Is4() != addrB.Is4() {
+		return false
+	}
+
+	bits := 64
+	if addrA.Is4() {
+		bits = 24
+	}
+
+	prefixA, _ := addrA.Prefix(bits)
+	prefixB, _ := addrB.Prefix(bits)
+
+	return prefixA == prefixB
+}
+
+func bindingReasons(events []model.SecurityEvent, action string) string {
+	var reasons []string
+	for _, event := range events {
+		if event.Action == action {
+			reasons = append(reasons, event.Reason)
+		}
+	}
+
+	return strings.Join(reasons, ", ")
+}
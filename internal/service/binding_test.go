@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"hh/internal/geoip"
+	"hh/internal/model"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseBindingPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    model.BindingPolicy
+		wantErr bool
+	}{
+		{"default", nil, defaultBindingPolicy, false},
+		{"override", []string{"user_agent=notify", " country = reauth"}, model.BindingPolicy{
+			UserAgent: model.BindingActionNotify,
+			IP:        model.BindingActionNotify,
+			Subnet:    model.BindingActionAllow,
+			ASN:       model.BindingActionAllow,
+			Country:   model.BindingActionReauth,
+		}, false},
+		{"no equals sign", []string{"user_agent"}, model.BindingPolicy{}, true},
+		{"unknown action", []string{"ip=block"}, model.BindingPolicy{}, true},
+		{"unknown signal", []string{"city=revoke"}, model.BindingPolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBindingPolicy(tt.entries)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseBindingPolicy() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: ParseBindingPolicy() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSameSubnet(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"203.0.113.10", "203.0.113.200", true},
+		{"203.0.113.10", "203.0.114.10", false},
+		{"::ffff:203.0.113.10", "203.0.113.20", true},
+		{"2001:db8:1:2::1", "2001:db8:1:2:ffff::1", true},
+		{"2001:db8:1:2::1", "2001:db8:1:3::1", false},
+		{"203.0.113.10", "2001:db8::1", false},
+		{"unknown", "unknown", true},
+		{"unknown", "203.0.113.10", false},
+	}
+
+	for _, tt := range tests {
+		if got := sameSubnet(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameSubnet(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+type staticResolver map[string]geoip.Info
+
+func (r staticResolver) Lookup(ip string) (geoip.Info, bool) {
+	info, ok := r[ip]
+	return info, ok
+}
+
+// Без клиента политика берётся из конфига, поэтому evaluateBinding не ходит в БД.
+func TestEvaluateBinding(t *testing.T) {
+	geo := staticResolver{
+		"203.0.113.10":  {ASN: 13238, Country: "RU"},
+		"203.0.113.20":  {ASN: 13238, Country: "RU"},
+		"198.51.100.10": {ASN: 15169, Country: "RU"},
+		"192.0.2.10":    {ASN: 15169, Country: "NL"},
+	}
+	policy := model.BindingPolicy{
+		UserAgent: model.BindingActionRevoke,
+		IP:        model.BindingActionAllow,
+		Subnet:    model.BindingActionNotify,
+		ASN:       model.BindingActionNotify,
+		Country:   model.BindingActionReauth,
+	}
+	s := &TokenService{bindingPolicy: policy, geo: geo}
+
+	stored := &model.RefreshTokenRecord{UserID: uuid.New(), FamilyID: uuid.New(), IPAddress: "203.0.113.10", UserAgent: "curl"}
+
+	tests := []struct {
+		name          string
+		ip            string
+		userAgent     string
+		wantReasons   []string
+		wantStrictest string
+	}{
+		{"nothing changed", "203.0.113.10", "curl", nil, model.BindingActionAllow},
+		{"same subnet", "203.0.113.20", "curl", []string{model.ReasonIPAddressChanged}, model.BindingActionAllow},
+		{"other asn", "198.51.100.10", "curl", []string{model.ReasonIPAddressChanged, model.ReasonSubnetChanged, model.ReasonASNChanged}, model.BindingActionNotify},
+		{"other country", "192.0.2.10", "curl", []string{model.ReasonIPAddressChanged, model.ReasonSubnetChanged, model.ReasonASNChanged, model.ReasonCountryChanged}, model.BindingActionReauth},
+		// адреса нет в таблице: ASN и страна не оцениваются
+		{"unknown address", "10.0.0.1", "curl", []string{model.ReasonIPAddressChanged, model.ReasonSubnetChanged}, model.BindingActionNotify},
+		{"user agent wins", "192.0.2.10", "wget", []string{model.ReasonUserAgentChanged, model.ReasonIPAddressChanged, model.ReasonSubnetChanged, model.ReasonASNChanged, model.ReasonCountryChanged}, model.BindingActionRevoke},
+	}
+
+	for _, tt := range tests {
+		events, strictest, err := s.evaluateBinding(context.Background(), stored, tt.ip, tt.userAgent)
+		if err != nil {
+			t.Fatalf("%s: evaluateBinding() error = %v", tt.name, err)
+		}
+		if strictest != tt.wantStrictest {
+			t.Errorf("%s: strictest = %s, want %s", tt.name, strictest, tt.wantStrictest)
+		}
+
+		var reasons []string
+		for _, event := range events {
+			reasons = append(reasons, event.Reason)
+			if event.Type == model.SecurityEventIPChange && event.PreviousIPAddress != stored.IPAddress {
+				t.Errorf("%s: PreviousIPAddress = %q", tt.name, event.PreviousIPAddress)
+			}
+		}
+		if len(reasons) != len(tt.wantReasons) {
+			t.Errorf("%s: reasons = %v, want %v", tt.name, reasons, tt.wantReasons)
+			continue
+		}
+		for i := range reasons {
+			if reasons[i] != tt.wantReasons[i] {
+				t.Errorf("%s: reasons = %v, want %v", tt.name, reasons, tt.wantReasons)
+				break
+			}
+		}
+	}
+}
+
+func TestBindingReasons(t *testing.T) {
+	events := []model.SecurityEvent{
+		{Reason: model.ReasonIPAddressChanged, Action: model.BindingActionAllow},
+		{Reason: model.ReasonASNChanged, Action: model.BindingActionReauth},
+		{Reason: model.ReasonCountryChanged, Action: model.BindingActionReauth},
+	}
+
+	if got := bindingReasons(events, model.BindingActionReauth); got != "asn_changed, country_changed" {
+		t.Errorf("bindingReasons() = %q", got)
+	}
+	if got := bindingReasons(events, model.BindingActionRevoke); got != "" {
+		t.Errorf("bindingReasons() = %q, want empty", got)
+	}
+}
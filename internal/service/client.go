@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hh/config"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/internal/token"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthError несёт код ошибки из RFC 6749, хендлер отдаёт его клиенту как есть.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func invalidClient(description string) error {
+	return &OAuthError{Code: "invalid_client", Description: description}
+}
+
+type ClientService struct {
+	clientRepository *repository.ClientRepository
+	cfg              *config.Config
+}
+
+func NewClientService(clientRepository *repository.ClientRepository, cfg *config.Config) *ClientService {
+	return &ClientService{clientRepository: clientRepository, cfg: cfg}
+}
+
+func (s *ClientService) Authenticate(ctx context.Context, creds model.ClientCredentials) (*model.Client, error) {
+	switch {
+	case creds.ClientAssertion != "":
+		return s.authenticateAssertion(ctx, creds)
+	case creds.ClientID != "" && creds.ClientSecret != "":
+		return s.authenticateSecret(ctx, creds)
+	default:
+		return nil, invalidClient("требуется аутентификация клиента")
+	}
+}
+
+func (s *ClientService) authenticateSecret(ctx context.Context, creds model.ClientCredentials) (*model.Client, error) {
+	client, err := s.getActiveClient(ctx, creds.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.SecretHash == "" || subtle.ConstantTimeCompare([]byte(hashSecret(creds.ClientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, invalidClient("неверные учётные данные клиента")
+	}
+
+	return client, nil
+}
+
+func (s *ClientService) authenticateAssertion(ctx context.Context, creds model.ClientCredentials) (*model.Client, error) {
+	if creds.ClientAssertionType != token.ClientAssertionType {
+		return nil, invalidClient("неподдерживаемый client_assertion_type")
+	}
+
+	clientID, err := token.PeekIssuer(creds.ClientAssertion)
+	if err != nil {
+		return nil, invalidClient("невалидный client_assertion")
+	}
+
+	if creds.ClientID != "" && creds.ClientID != clientID {
+		return nil, invalidClient("client_id не совпадает с iss assertion")
+	}
+
+	client, err := s.getActiveClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.PublicKey == "" {
+		return nil, invalidClient("для клиента не настроен private_key_jwt")
+	}
+
+	key, err := token.ParsePublicKeyPEM("", "", []byte(client.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("невалидный публичный ключ клиента %s: %w", client.ID, err)
+	}
+
+	audiences := []string{s.cfg.PublicURL, s.cfg.PublicURL + "/tokens"}
+
+	jti, expiresAt, err := token.VerifyClientAssertion(key, creds.ClientAssertion, client.ID, audiences)
+	if err != nil {
+		return nil, invalidClient("невалидный client_assertion: " + err.Error())
+	}
+
+	fresh, err := s.clientRepository.UseAssertionID(ctx, client.ID, jti, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, invalidClient("client_assertion уже использован")
+	}
+
+	return client, nil
+}
+
+func (s *ClientService) getActiveClient(ctx context.Context, clientID string) (*model.Client, error) {
+	client, err := s.clientRepository.GetClient(ctx, clientID)
+	if errors.Is(err, repository.ErrClientNotFound) {
+		return nil, invalidClient("неверные учётные данные клиента")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.Active {
+		return nil, invalidClient("клиент отключен")
+	}
+
+	return client, nil
+}
+
+// AuthorizeSubject проверяет, что клиенту можно выпускать токены для userID, и возвращает выданный scope.
+func (s *ClientService) AuthorizeSubject(client *model.Client, userID, requestedScope string) (string, error) {
+	subject, err := uuid.Parse(userID)
+	if err != nil {
+		return "", &OAuthError{Code: "invalid_request", Description: "user_id должен быть GUID"}
+	}
+
+	if !client.Trusted && !slices.Contains(client.AllowedSubjects, subject) {
+		return "", &OAuthError{Code: "unauthorized_client", Description: "клиенту не разрешено выпускать токены для этого пользователя"}
+	}
+
+	requested := strings.Fields(requestedScope)
+	if len(requested) == 0 {
+		return strings.Join(client.Scopes, " "), nil
+	}
+
+	for _, scope := range requested {
+		if !slices.Contains(client.Scopes, scope) {
+			return "", &OAuthError{Code: "invalid_scope", Description: "scope не разрешён клиенту: " + scope}
+		}
+	}
+
+	return strings.Join(requested, " "), nil
+}
+
+func (s *ClientService) Register(ctx context.Context, registration model.ClientRegistration) (*model.Client, string, error) {
+	client := model.Client{
+		ID:              registration.ID,
+		Name:            registration.Name,
+		PublicKey:       registration.PublicKey,
+		Trusted:         registration.Trusted,
+		AllowedSubjects: registration.AllowedSubjects,
+		Scopes:          registration.Scopes,
+		Active:          true,
+		CreatedAt:       time.Now(),
+		ClientLifetimes: registration.ClientLifetimes,
+	}
+	if client.AllowedSubjects == nil {
+		client.AllowedSubjects = []uuid.UUID{}
+	}
+	if client.Scopes == nil {
+		client.Scopes = []string{}
+	}
+
+	// иначе после ротации ключа подписи живые access токены клиента перестанут проверяться
+	if time.Duration(client.AccessTokenTTL)*time.Second > s.cfg.JWTKeyRetireAfter {
+		return nil, "", &OAuthError{Code: "invalid_request", Description: "access_token_ttl больше JWT_KEY_RETIRE_AFTER"}
+	}
+
+	var secret string
+	if client.PublicKey != "" {
+		if _, err := token.ParsePublicKeyPEM("", "", []byte(client.PublicKey)); err != nil {
+			return nil, "", &OAuthError{Code: "invalid_request", Description: "невалидный public_key: " + err.Error()}
+		}
+	} else {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		secret = base64.RawURLEncoding.EncodeToString(b)
+		client.SecretHash = hashSecret(secret)
+	}
+
+	if err := s.clientRepository.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	return &client, secret, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hh/internal/model"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAuthorizeSubject(t *testing.T) {
+	allowed := uuid.New()
+	other := uuid.New()
+
+	client := &model.Client{ID: "billing", AllowedSubjects: []uuid.UUID{allowed}, Scopes: []string{"profile", "email"}}
+	trusted := &model.Client{ID: "gateway", Trusted: true, Scopes: []string{"profile"}}
+
+	tests := []struct {
+		name      string
+		client    *model.Client
+		userID    string
+		scope     string
+		wantScope string
+		wantCode  string
+	}{
+		{"allowed subject, default scope", client, allowed.String(), "", "profile email", ""},
+		{"allowed subject, narrowed scope", client, allowed.String(), "email", "email", ""},
+		{"trusted client, any subject", trusted, other.String(), "profile", "profile", ""},
+		{"foreign subject", client, other.String(), "", "", "unauthorized_client"},
+		{"scope not granted", client, allowed.String(), "profile admin", "", "invalid_scope"},
+		{"user id is not a guid", client, "alice", "", "", "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := (&ClientService{}).AuthorizeSubject(tt.client, tt.userID, tt.scope)
+
+			var oauthErr *OAuthError
+			if tt.wantCode != "" {
+				if !errors.As(err, &oauthErr) || oauthErr.Code != tt.wantCode {
+					t.Fatalf("AuthorizeSubject() error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil || scope != tt.wantScope {
+				t.Errorf("AuthorizeSubject() = %q, %v, want %q", scope, err, tt.wantScope)
+			}
+		})
+	}
+}
+
+// Без учётных данных или с чужим типом assertion клиент отклоняется до обращения к БД.
+func TestAuthenticateRejectsWithoutLookup(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds model.ClientCredentials
+	}{
+		{"no credentials", model.ClientCredentials{}},
+		{"client id without secret", model.ClientCredentials{ClientID: "billing"}},
+		{"unsupported assertion type", model.ClientCredentials{ClientAssertionType: "urn:example:saml", ClientAssertion: "x"}},
+		{"assertion without iss", model.ClientCredentials{ClientAssertionType: "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", ClientAssertion: "garbage"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&ClientService{}).Authenticate(context.Background(), tt.creds)
+
+			var oauthErr *OAuthError
+			if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_client" {
+				t.Errorf("Authenticate() error = %v, want invalid_client", err)
+			}
+		})
+	}
+}
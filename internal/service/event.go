@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newSecurityEvent(eventType, reason string, userID, sessionID uuid.UUID, clientID, ip, userAgent string) model.SecurityEvent {
+	return model.SecurityEvent{
+		ID:            uuid.New(),
+		SchemaVersion: model.SecurityEventSchemaVersion,
+		Type:          eventType,
+		UserID:        userID,
+		SessionID:     &sessionID,
+		ClientID:      clientID,
+		IPAddress:     ip,
+		UserAgent:     userAgent,
+		Reason:        reason,
+		CreatedAt:     time.Now(),
+	}
+}
+
+// tokenEvent — событие о сессии, к которой относится storedToken.
+func tokenEvent(storedToken *model.RefreshTokenRecord, eventType, reason, ip, userAgent string) model.SecurityEvent {
+	return newSecurityEvent(eventType, reason, storedToken.UserID, storedToken.FamilyID, stringValue(storedToken.ClientID), ip, userAgent)
+}
+
+// recordEvent пишет событие безопасности и запись аудита через repo, чтобы они попали
+// в ту же транзакцию, что и само действие.
+func recordEvent(ctx context.Context, repo *repository.TokenRepository, event model.SecurityEvent) error {
+	if err := repo.RecordSecurityEvent(ctx, event); err != nil {
+		return err
+	}
+
+	return repo.RecordAudit(ctx, auditEntryFor(event))
+}
+
+func recordEvents(ctx context.Context, repo *repository.TokenRepository, events []model.SecurityEvent) error {
+	for _, event := range events {
+		if err := recordEvent(ctx, repo, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func auditEntryFor(event model.SecurityEvent) model.AuditEntry {
+	entry := model.AuditEntry{
+		ID:         uuid.New(),
+		OccurredAt: event.CreatedAt,
+		Action:     event.Type,
+		ActorType:  model.AuditActorUser,
+		ActorID:    event.UserID.String(),
+		SubjectID:  &event.UserID,
+		SessionID:  event.SessionID,
+		ClientID:   event.ClientID,
+		IPAddress:  event.IPAddress,
+		UserAgent:  event.UserAgent,
+		Outcome:    model.AuditOutcomeSuccess,
+		Reason:     event.Reason,
+	}
+
+	switch {
+	case event.Reason == model.ReasonAdminRequest:
+		entry.ActorType, entry.ActorID = model.AuditActorAdmin, ""
+	case event.Type == model.SecurityEventLogin && event.ClientID != "", event.Reason == model.ReasonClientRevocation:
+		entry.ActorType, entry.ActorID = model.AuditActorClient, event.ClientID
+	}
+
+	switch event.Type {
+	case model.SecurityEventRefreshDenied, model.SecurityEventInvalidRefreshToken, model.SecurityEventTokenReuse:
+		entry.Outcome = model.AuditOutcomeFailure
+	}
+
+	// решение политики привязки: в причине аудита видно и изменившийся сигнал, и действие
+	if event.Action != "" {
+		entry.Reason = event.Reason + ":" + event.Action
+		if event.Action == model.BindingActionReauth || event.Action == model.BindingActionRevoke {
+			entry.Outcome = model.AuditOutcomeFailure
+		}
+	}
+
+	return entry
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"hh/internal/model"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTokenEvent(t *testing.T) {
+	clientID := "billing"
+	stored := &model.RefreshTokenRecord{UserID: uuid.New(), FamilyID: uuid.New(), ClientID: &clientID}
+
+	tests := []struct {
+		name         string
+		clientID     *string
+		wantClientID string
+	}{
+		{"client token", &clientID, "billing"},
+		{"user token", nil, ""},
+	}
+
+	for _, tt := range tests {
+		stored.ClientID = tt.clientID
+		event := tokenEvent(stored, model.SecurityEventTokenReuse, model.ReasonRotatedTokenReused, "10.0.0.1", "curl")
+
+		if event.ID == uuid.Nil || event.CreatedAt.IsZero() || event.SchemaVersion != model.SecurityEventSchemaVersion {
+			t.Errorf("%s: event envelope = %+v", tt.name, event)
+		}
+		// сессия — это семейство refresh токенов
+		if event.SessionID == nil || *event.SessionID != stored.FamilyID || event.UserID != stored.UserID {
+			t.Errorf("%s: event user/session = %v/%v", tt.name, event.UserID, event.SessionID)
+		}
+		if event.Type != model.SecurityEventTokenReuse || event.Reason != model.ReasonRotatedTokenReused {
+			t.Errorf("%s: event type/reason = %s/%s", tt.name, event.Type, event.Reason)
+		}
+		if event.ClientID != tt.wantClientID || event.IPAddress != "10.0.0.1" || event.UserAgent != "curl" {
+			t.Errorf("%s: event client/ip/ua = %q/%q/%q", tt.name, event.ClientID, event.IPAddress, event.UserAgent)
+		}
+	}
+}
+
+func TestAuditEntryFor(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name        string
+		eventType   string
+		reason      string
+		wantActor   string
+		wantActorID string
+		wantOutcome string
+	}{
+		{"refresh", model.SecurityEventRefresh, model.ReasonTokenRotated, model.AuditActorUser, userID.String(), model.AuditOutcomeSuccess},
+		{"user logout", model.SecurityEventLogout, model.ReasonUserLogout, model.AuditActorUser, userID.String(), model.AuditOutcomeSuccess},
+		{"login by client", model.SecurityEventLogin, model.ReasonTokenIssued, model.AuditActorClient, "billing", model.AuditOutcomeSuccess},
+		{"client revocation", model.SecurityEventSessionRevoked, model.ReasonClientRevocation, model.AuditActorClient, "billing", model.AuditOutcomeSuccess},
+		{"admin revocation", model.SecurityEventSessionRevoked, model.ReasonAdminRequest, model.AuditActorAdmin, "", model.AuditOutcomeSuccess},
+		{"refresh denied", model.SecurityEventRefreshDenied, model.ReasonRefreshTokenExpired, model.AuditActorUser, userID.String(), model.AuditOutcomeFailure},
+		{"invalid token", model.SecurityEventInvalidRefreshToken, model.ReasonVerifierMismatch, model.AuditActorUser, userID.String(), model.AuditOutcomeFailure},
+		{"token reuse", model.SecurityEventTokenReuse, model.ReasonRotatedTokenReused, model.AuditActorUser, userID.String(), model.AuditOutcomeFailure},
+		// исход решения политики привязки задаёт действие, а не сам сигнал
+		{"user agent mismatch", model.SecurityEventUserAgentMismatch, model.ReasonUserAgentChanged, model.AuditActorUser, userID.String(), model.AuditOutcomeSuccess},
+		// смена IP сама по себе не отказ: токен выдан
+		{"ip change", model.SecurityEventIPChange, model.ReasonIPAddressChanged, model.AuditActorUser, userID.String(), model.AuditOutcomeSuccess},
+	}
+
+	for _, tt := range tests {
+		event := newSecurityEvent(tt.eventType, tt.reason, userID, uuid.New(), "billing", "10.0.0.1", "curl")
+		entry := auditEntryFor(event)
+
+		if entry.ActorType != tt.wantActor || entry.ActorID != tt.wantActorID || entry.Outcome != tt.wantOutcome {
+			t.Errorf("%s: actor %s/%q outcome %s, want %s/%q %s", tt.name, entry.ActorType, entry.ActorID, entry.Outcome, tt.wantActor, tt.wantActorID, tt.wantOutcome)
+		}
+		if entry.Action != tt.eventType || entry.Reason != tt.reason || !entry.OccurredAt.Equal(event.CreatedAt) {
+			t.Errorf("%s: entry = %+v", tt.name, entry)
+		}
+		if entry.SubjectID == nil || *entry.SubjectID != userID || entry.SessionID != event.SessionID || entry.ClientID != "billing" {
+			t.Errorf("%s: subject/session/client = %v/%v/%q", tt.name, entry.SubjectID, entry.SessionID, entry.ClientID)
+		}
+		if entry.IPAddress != "10.0.0.1" || entry.UserAgent != "curl" || entry.ID == uuid.Nil || entry.ID == event.ID {
+			t.Errorf("%s: entry = %+v", tt.name, entry)
+		}
+	}
+}
+
+func TestAuditEntryForBindingDecision(t *testing.T) {
+	tests := []struct {
+		action      string
+		wantReason  string
+		wantOutcome string
+	}{
+		{model.BindingActionAllow, "user_agent_changed:allow", model.AuditOutcomeSuccess},
+		{model.BindingActionNotify, "user_agent_changed:notify", model.AuditOutcomeSuccess},
+		{model.BindingActionReauth, "user_agent_changed:reauth", model.AuditOutcomeFailure},
+		{model.BindingActionRevoke, "user_agent_changed:revoke", model.AuditOutcomeFailure},
+	}
+
+	for _, tt := range tests {
+		event := newSecurityEvent(model.SecurityEventUserAgentMismatch, model.ReasonUserAgentChanged, uuid.New(), uuid.New(), "", "10.0.0.1", "curl")
+		event.Action = tt.action
+
+		entry := auditEntryFor(event)
+		if entry.Reason != tt.wantReason || entry.Outcome != tt.wantOutcome {
+			t.Errorf("%s: reason %q outcome %s, want %q %s", tt.action, entry.Reason, entry.Outcome, tt.wantReason, tt.wantOutcome)
+		}
+	}
+}
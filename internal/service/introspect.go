@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"hh/internal/model"
+	"hh/internal/token"
+
+	"github.com/google/uuid"
+)
+
+// Introspect реализует RFC 7662: токен активен, только если валидна подпись/хэш и жива сессия в refresh_tokens.
+func (s *TokenService) Introspect(ctx context.Context, tokenString, tokenTypeHint string) (*model.IntrospectionResponse, error) {
+	lookups := []func(context.Context, string) (*model.IntrospectionResponse, error){
+		s.introspectAccessToken,
+		s.introspectRefreshToken,
+	}
+	if tokenTypeHint == "refresh_token" {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		response, err := lookup(ctx, tokenString)
+		if err != nil {
+			return nil, err
+		}
+		if response != nil {
+			return response, nil
+		}
+	}
+
+	return &model.IntrospectionResponse{Active: false}, nil
+}
+
+func (s *TokenService) introspectAccessToken(ctx context.Context, tokenString string) (*model.IntrospectionResponse, error) {
+	claims, err := s.tokenManager.ParseClaims(tokenString)
+	if err != nil {
+		return nil, nil
+	}
+
+	// тем же ключом подписаны mfa_token и challenge passkey: у них есть typ, а у access token нет.
+	// Без валидных sub и session_id токен не access token, и в БД его искать незачем
+	if _, ok := claims["typ"]; ok {
+		return nil, nil
+	}
+
+	userID, _ := claims["sub"].(string)
+	sessionID, _ := claims["session_id"].(string)
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, nil
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return nil, nil
+	}
+
+	active, err := s.tokenRepository.IsSessionActive(ctx, sessionID, userID)
+	if err != nil || !active {
+		return nil, err
+	}
+
+	response := &model.IntrospectionResponse{
+		Active:    true,
+		Sub:       userID,
+		SessionID: sessionID,
+		TokenType: "access_token",
+	}
+	response.ClientID, _ = claims["client_id"].(string)
+	response.Scope, _ = claims["scope"].(string)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		response.Exp = exp.Unix()
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		response.Iat = iat.Unix()
+	}
+
+	return response, nil
+}
+
+func (s *TokenService) introspectRefreshToken(ctx context.Context, tokenString string) (*model.IntrospectionResponse, error) {
+	selector, _, ok := token.SplitRefreshToken(tokenString)
+	if !ok {
+		return nil, nil
+	}
+
+	storedToken, err := s.tokenRepository.GetRefreshTokenBySelector(ctx, selector)
+	if err != nil {
+		return nil, nil
+	}
+
+	if storedToken.Revoked || s.refreshTokenExpired(storedToken) {
+		return nil, nil
+	}
+
+	if err := s.tokenManager.VerifyRefreshToken(storedToken.RefreshTokenHash, tokenString); err != nil {
+		return nil, nil
+	}
+
+	return &model.IntrospectionResponse{
+		Active:    true,
+		Sub:       storedToken.UserID.String(),
+		SessionID: storedToken.FamilyID.String(),
+		Exp:       storedToken.ExpiresAt.Unix(),
+		Iat:       storedToken.CreatedAt.Unix(),
+		ClientID:  stringValue(storedToken.ClientID),
+		Scope:     storedToken.Scope,
+		TokenType: "refresh_token",
+	}, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"hh/internal/token"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Строка, которая не разбирается ни как JWT, ни как selector.verifier, неактивна без обращения к БД.
+func TestIntrospectUnparseableToken(t *testing.T) {
+	s := &TokenService{tokenManager: newTestTokenManager(t)}
+
+	for _, hint := range []string{"", "access_token", "refresh_token", "unknown"} {
+		response, err := s.Introspect(context.Background(), "garbage", hint)
+		if err != nil {
+			t.Fatalf("Introspect(hint=%q) error = %v", hint, err)
+		}
+		if response.Active || response.Sub != "" || response.TokenType != "" {
+			t.Errorf("Introspect(hint=%q) = %+v, want only active=false", hint, response)
+		}
+	}
+}
+
+func newTestTokenManager(t *testing.T) *token.Manager {
+	t.Helper()
+
+	key, err := token.NewHMACKey("", "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := token.NewKeyRing(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := token.NewManager(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return manager
+}
+
+// JWT с валидной подписью, который не является access token, в БД не ищется и неактивен.
+func TestIntrospectAccessTokenRejectsOtherJWTs(t *testing.T) {
+	manager := newTestTokenManager(t)
+	s := &TokenService{tokenManager: manager}
+
+	sign := func(claims jwt.MapClaims) string {
+		signed, err := manager.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	exp := time.Now().Add(time.Minute).Unix()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"mfa token", sign(jwt.MapClaims{"typ": "mfa", "sub": uuid.NewString(), "session_id": uuid.NewString(), "exp": exp})},
+		{"subject is not a user id", sign(jwt.MapClaims{"sub": "billing", "session_id": uuid.NewString(), "exp": exp})},
+		{"no session", sign(jwt.MapClaims{"sub": uuid.NewString(), "exp": exp})},
+		{"session is not a uuid", sign(jwt.MapClaims{"sub": uuid.NewString(), "session_id": 42, "exp": exp})},
+	}
+
+	for _, tt := range tests {
+		response, err := s.introspectAccessToken(context.Background(), tt.token)
+		if err != nil || response != nil {
+			t.Errorf("%s: introspectAccessToken() = %+v, %v, want nil", tt.name, response, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"time"
+)
+
+type lifetimes struct {
+	accessTokenTTL     time.Duration
+	refreshIdleTimeout time.Duration
+	sessionMaxLifetime time.Duration
+}
+
+// lifetimesFor берёт времена жизни из конфига и накладывает переопределения клиента, если они заданы.
+func (s *TokenService) lifetimesFor(ctx context.Context, clientID string) (lifetimes, error) {
+	result := lifetimes{
+		accessTokenTTL:     s.cfg.AccessTokenTTL,
+		refreshIdleTimeout: s.cfg.RefreshIdleTimeout,
+		sessionMaxLifetime: s.cfg.SessionMaxLifetime,
+	}
+
+	if clientID == "" {
+		return result, nil
+	}
+
+	client, err := s.clientRepository.GetClient(ctx, clientID)
+	if errors.Is(err, repository.ErrClientNotFound) {
+		return result, nil
+	}
+	if err != nil {
+		return lifetimes{}, err
+	}
+
+	if client.AccessTokenTTL > 0 {
+		result.accessTokenTTL = time.Duration(client.AccessTokenTTL) * time.Second
+	}
+	if client.RefreshIdleTimeout > 0 {
+		result.refreshIdleTimeout = time.Duration(client.RefreshIdleTimeout) * time.Second
+	}
+	if client.SessionMaxLifetime > 0 {
+		result.sessionMaxLifetime = time.Duration(client.SessionMaxLifetime) * time.Second
+	}
+
+	return result, nil
+}
+
+// refreshExpiresAt продлевает idle-окно, но не дальше абсолютного срока жизни сессии.
+func (l lifetimes) refreshExpiresAt(now, sessionExpiresAt time.Time) time.Time {
+	expiresAt := now.Add(l.refreshIdleTimeout)
+	if expiresAt.After(sessionExpiresAt) {
+		return sessionExpiresAt
+	}
+
+	return expiresAt
+}
+
+func (s *TokenService) refreshTokenExpired(storedToken *model.RefreshTokenRecord) bool {
+	return !time.Now().Before(storedToken.ExpiresAt)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hh/config"
+	"hh/internal/model"
+	"testing"
+	"time"
+)
+
+func TestRefreshExpiresAt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := lifetimes{refreshIdleTimeout: 24 * time.Hour}
+
+	tests := []struct {
+		name             string
+		sessionExpiresAt time.Time
+		want             time.Time
+	}{
+		{"idle window inside session", now.Add(30 * 24 * time.Hour), now.Add(24 * time.Hour)},
+		{"clamped to session end", now.Add(time.Hour), now.Add(time.Hour)},
+		{"session ends with idle window", now.Add(24 * time.Hour), now.Add(24 * time.Hour)},
+		{"session already over", now.Add(-time.Minute), now.Add(-time.Minute)},
+	}
+
+	for _, tt := range tests {
+		if got := l.refreshExpiresAt(now, tt.sessionExpiresAt); !got.Equal(tt.want) {
+			t.Errorf("%s: refreshExpiresAt() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshTokenExpired(t *testing.T) {
+	s := &TokenService{}
+
+	tests := []struct {
+		expiresAt time.Time
+		want      bool
+	}{
+		{time.Now().Add(time.Minute), false},
+		{time.Now().Add(-time.Minute), true},
+		{time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		if got := s.refreshTokenExpired(&model.RefreshTokenRecord{ExpiresAt: tt.expiresAt}); got != tt.want {
+			t.Errorf("refreshTokenExpired(%v) = %v, want %v", tt.expiresAt, got, tt.want)
+		}
+	}
+}
+
+// Токены без клиента живут по глобальному конфигу, в БД за клиентом не ходим.
+func TestLifetimesForWithoutClient(t *testing.T) {
+	s := &TokenService{cfg: &config.Config{
+		AccessTokenTTL:     5 * time.Minute,
+		RefreshIdleTimeout: time.Hour,
+		SessionMaxLifetime: 24 * time.Hour,
+	}}
+
+	got, err := s.lifetimesFor(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := lifetimes{accessTokenTTL: 5 * time.Minute, refreshIdleTimeout: time.Hour, sessionMaxLifetime: 24 * time.Hour}
+	if got != want {
+		t.Errorf("lifetimesFor() = %+v, want %+v", got, want)
+	}
+}
+
+// access токен не должен переживать ключ, которым он подписан.
+func TestRegisterRejectsAccessTTLOverKeyRetirement(t *testing.T) {
+	s := &ClientService{cfg: &config.Config{JWTKeyRetireAfter: time.Hour}}
+
+	registration := model.ClientRegistration{ID: "billing", Name: "Billing"}
+	registration.AccessTokenTTL = int((2 * time.Hour).Seconds())
+
+	_, _, err := s.Register(context.Background(), registration)
+
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_request" {
+		t.Errorf("Register() error = %v, want invalid_request", err)
+	}
+}
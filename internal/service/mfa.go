@@ -0,0 +1,417 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/internal/totp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFADisabled        = errors.New("TOTP не настроен на сервере")
+	ErrTOTPAlreadyEnabled = errors.New("TOTP уже подключён")
+	ErrTOTPNotEnabled     = errors.New("TOTP не подключён")
+	ErrInvalidMFACode     = errors.New("неверный или уже использованный код")
+	ErrInvalidMFAToken    = errors.New("mfa_token недействителен или истёк")
+)
+
+const (
+	// mfaTokenType — значение typ в JWT первого шага входа
+	mfaTokenType = "mfa"
+
+	// totpSkew — сколько соседних шагов принимается из-за расхождения часов
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+
+	// mfaTokenMaxFailures — сколько неверных кодов выдерживает один mfa_token. Лимиты MFA
+	// считают попытки по IP и пользователю, а этот предел сжигает сам токен, которым перебирают коды
+	mfaTokenMaxFailures = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// secondFactor — чем пользователь подтвердил второй шаг входа.
+type secondFactor int
+
+const (
+	factorTOTP secondFactor = iota
+	factorRecoveryCode
+)
+
+// reason — причина для события входа.
+func (f secondFactor) reason() string {
+	if f == factorRecoveryCode {
+		return model.ReasonRecoveryCodeUsed
+	}
+
+	return model.ReasonTOTPVerified
+}
+
+// amr — методы входа для claim amr после пароля и этого фактора. Для recovery code в
+// RFC 8176 нет своего метода, и otp он не является, поэтому остаётся только mfa.
+func (f secondFactor) amr() []string {
+	if f == factorRecoveryCode {
+		return []string{model.AMRPassword, model.AMRMultiFactor}
+	}
+
+	return []string{model.AMRPassword, model.AMROTP, model.AMRMultiFactor}
+}
+
+// EnrollTOTP создаёт новый секрет и возвращает его вместе с otpauth:// URI для QR-кода.
+// TOTP включается только после ConfirmTOTP, до этого секрет можно перевыпустить.
+func (s *UserService) EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error) {
+	if s.secretBox == nil {
+		return nil, ErrMFADisabled
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := s.secretBox.Seal(secret, user.ID[:])
+	if err != nil {
+		return nil, fmt.Errorf("не удалось зашифровать секрет TOTP: %w", err)
+	}
+
+	err = s.userRepository.SavePendingTOTP(ctx, user.ID, ciphertext)
+	if errors.Is(err, repository.ErrTOTPAlreadyEnabled) {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:     totp.EncodeSecret(secret),
+		OTPAuthURI: totp.URI(s.cfg.MFAIssuer, account, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает TOTP по первому коду из приложения и возвращает recovery codes.
+// Коды показываются один раз, в БД хранятся только их хэши.
+func (s *UserService) ConfirmTOTP(ctx context.Context, userID, sessionID, code, ip, userAgent string) ([]string, error) {
+	if s.secretBox == nil {
+		return nil, ErrMFADisabled
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.userRepository.GetTOTP(ctx, user.ID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return nil, ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.ConfirmedAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := s.secretBox.Open(record.SecretCiphertext, user.ID[:])
+	if err != nil {
+		return nil, fmt.Errorf("секрет TOTP пользователя %s: %w", user.ID, err)
+	}
+
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userRepository.WithTx(ctx, func(users *repository.UserRepository, tokens *repository.TokenRepository) error {
+		confirmed, err := users.ConfirmTOTP(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return ErrInvalidMFACode
+		}
+
+		if err := users.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, tokens, userEvent(model.SecurityEventMFAEnabled, model.ReasonUserRequest, user.ID, sessionID, ip, userAgent))
+	})
+	if err != nil && !errors.Is(err, ErrInvalidMFACode) {
+		return nil, fmt.Errorf("не удалось подключить TOTP: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP отключает TOTP и удаляет recovery codes. Нужен действующий код или
+// recovery code, чтобы украденной сессии не хватало для снятия второго фактора.
+func (s *UserService) DisableTOTP(ctx context.Context, userID, sessionID, code, ip, userAgent string) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepository.WithTx(ctx, func(users *repository.UserRepository, tokens *repository.TokenRepository) error {
+		if _, err := s.verifySecondFactor(ctx, users, user.ID, code); err != nil {
+			return err
+		}
+
+		if err := users.DeleteTOTP(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, tokens, userEvent(model.SecurityEventMFADisabled, model.ReasonUserRequest, user.ID, sessionID, ip, userAgent))
+	})
+	if err != nil && !errors.Is(err, ErrInvalidMFACode) && !errors.Is(err, ErrTOTPNotEnabled) && !errors.Is(err, ErrMFADisabled) {
+		return fmt.Errorf("не удалось отключить TOTP: %w", err)
+	}
+
+	return err
+}
+
+// CompleteMFALogin — второй шаг входа: обменивает mfa_token из /login и код TOTP
+// или recovery code на пару токенов. mfa_token одноразовый: он расходуется вместе с кодом
+// в одной транзакции. Неверный код откатывает её, но засчитывается токену отдельно, и после
+// mfaTokenMaxFailures неудач токен сгорает.
+func (s *UserService) CompleteMFALogin(ctx context.Context, mfaToken, code, ip, userAgent string) (*model.TokenPair, error) {
+	claims, err := s.tokenManager.ParseClaims(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	typ, _ := claims["typ"].(string)
+	sub, _ := claims["sub"].(string)
+	jti, _ := claims["jti"].(string)
+	userID, err := uuid.Parse(sub)
+	expiresAt, expErr := claims.GetExpirationTime()
+	if typ != mfaTokenType || err != nil || jti == "" || expErr != nil || expiresAt == nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	var factor secondFactor
+	err = s.userRepository.WithTx(ctx, func(users *repository.UserRepository, tokens *repository.TokenRepository) error {
+		used, err := users.UseMFAToken(ctx, jti, expiresAt.Time, mfaTokenMaxFailures)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFAToken
+		}
+
+		factor, err = s.verifySecondFactor(ctx, users, userID, code)
+		return err
+	})
+	if errors.Is(err, ErrInvalidMFACode) {
+		if err := s.userRepository.RecordMFATokenFailure(ctx, jti, expiresAt.Time); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenService.GetTokens(ctx, model.SessionParams{
+		UserID:    userID.String(),
+		SessionID: uuid.New().String(),
+		UserAgent: userAgent,
+		IPAddress: ip,
+		Reason:    factor.reason(),
+		AMR:       factor.amr(),
+	})
+}
+
+// mfaRequired сообщает, нужен ли пользователю второй шаг входа.
+func (s *UserService) mfaRequired(ctx context.Context, userID uuid.UUID) (bool, error) {
+	record, err := s.userRepository.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return record.ConfirmedAt != nil, nil
+}
+
+func (s *UserService) mfaChallenge(user *model.User) (*model.MFAChallenge, error) {
+	now := time.Now()
+
+	mfaToken, err := s.tokenManager.Sign(jwt.MapClaims{
+		"typ": mfaTokenType,
+		"sub": user.ID.String(),
+		"jti": uuid.NewString(),
+		"iat": now.Unix(),
+		"exp": now.Add(s.cfg.MFATokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подписать mfa_token: %w", err)
+	}
+
+	return &model.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		ExpiresIn:   int(s.cfg.MFATokenTTL.Seconds()),
+	}, nil
+}
+
+// verifySecondFactor принимает код TOTP или recovery code и возвращает, какой из них подошёл.
+// Оба вида кодов одноразовые: для TOTP запоминается последний принятый шаг.
+func (s *UserService) verifySecondFactor(ctx context.Context, users *repository.UserRepository, userID uuid.UUID, code string) (secondFactor, error) {
+	code = strings.TrimSpace(code)
+
+	if !isTOTPCode(code) {
+		used, err := users.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+		if err != nil {
+			return 0, err
+		}
+		if !used {
+			return 0, ErrInvalidMFACode
+		}
+
+		return factorRecoveryCode, nil
+	}
+
+	if s.secretBox == nil {
+		return 0, ErrMFADisabled
+	}
+
+	record, err := users.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrTOTPNotFound) {
+		return 0, ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return 0, err
+	}
+	if record.ConfirmedAt == nil {
+		return 0, ErrTOTPNotEnabled
+	}
+
+	secret, err := s.secretBox.Open(record.SecretCiphertext, userID[:])
+	if err != nil {
+		return 0, fmt.Errorf("секрет TOTP пользователя %s: %w", userID, err)
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return 0, ErrInvalidMFACode
+	}
+
+	used, err := users.UseTOTPStep(ctx, userID, step)
+	if err != nil {
+		return 0, err
+	}
+	if !used {
+		return 0, ErrInvalidMFACode
+	}
+
+	return factorTOTP, nil
+}
+
+func (s *UserService) getUser(ctx context.Context, userID string) (*model.User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.userRepository.GetUser(ctx, id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+
+	return user, err
+}
+
+// userEvent — событие от имени пользователя в рамках сессии его access token.
+func userEvent(eventType, reason string, userID uuid.UUID, sessionID, ip, userAgent string) model.SecurityEvent {
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		sessionUUID = uuid.Nil
+	}
+
+	event := newSecurityEvent(eventType, reason, userID, sessionUUID, "", ip, userAgent)
+	if sessionUUID == uuid.Nil {
+		event.SessionID = nil
+	}
+
+	return event
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRecoveryCodes генерирует коды вида xxxxx-xxxxx (50 бит энтропии) и их хэши.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))[:10]
+		code := encoded[:5] + "-" + encoded[5:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode хэширует код без учёта регистра, пробелов и дефисов. Хватает SHA-256:
+// у кода 50 бит случайности, а перебор онлайн ограничен лимитами.
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"hh/internal/model"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestIsTOTPCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"123456", true},
+		{"000000", true},
+		{"12345", false},
+		{"1234567", false},
+		{"12345a", false},
+		{"abcde-fghij", false},
+		{"１２３４５６", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isTOTPCode(tt.code); got != tt.want {
+			t.Errorf("isTOTPCode(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("newRecoveryCodes() = %d codes, %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q does not match xxxxx-xxxxx", code)
+		}
+		if isTOTPCode(code) {
+			t.Errorf("code %q is taken for a TOTP code", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q is repeated", code)
+		}
+		seen[code] = true
+
+		if hashes[i] != hashRecoveryCode(code) {
+			t.Errorf("hash of %q does not match hashRecoveryCode", code)
+		}
+	}
+}
+
+// Код принимается в том виде, в каком его переписал пользователь.
+func TestHashRecoveryCodeNormalizes(t *testing.T) {
+	want := hashRecoveryCode("abcde-fgh23")
+
+	tests := []struct {
+		code string
+		same bool
+	}{
+		{"abcde-fgh23", true},
+		{"ABCDE-FGH23", true},
+		{"abcdefgh23", true},
+		{" abcde fgh23 ", true},
+		{"ab-cde-fgh-23", true},
+		{"abcde-fgh24", false},
+		{"abcde_fgh23", false},
+	}
+
+	for _, tt := range tests {
+		if got := hashRecoveryCode(tt.code) == want; got != tt.same {
+			t.Errorf("hashRecoveryCode(%q) matches = %v, want %v", tt.code, got, tt.same)
+		}
+	}
+}
+
+// amr отражает фактор, которым подтверждён вход: recovery code не otp.
+func TestSecondFactorAMR(t *testing.T) {
+	tests := []struct {
+		factor     secondFactor
+		wantAMR    []string
+		wantReason string
+	}{
+		{factorTOTP, []string{model.AMRPassword, model.AMROTP, model.AMRMultiFactor}, model.ReasonTOTPVerified},
+		{factorRecoveryCode, []string{model.AMRPassword, model.AMRMultiFactor}, model.ReasonRecoveryCodeUsed},
+	}
+
+	for _, tt := range tests {
+		if got := tt.factor.amr(); !slices.Equal(got, tt.wantAMR) {
+			t.Errorf("factor %d: amr() = %v, want %v", tt.factor, got, tt.wantAMR)
+		}
+		if got := tt.factor.reason(); got != tt.wantReason {
+			t.Errorf("factor %d: reason() = %q, want %q", tt.factor, got, tt.wantReason)
+		}
+	}
+}
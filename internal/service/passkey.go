@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/internal/webauthn"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPasskey  = errors.New("passkey не прошёл проверку")
+	ErrPasskeyCloned   = errors.New("счётчик подписей passkey не вырос, вход отклонён")
+	ErrPasskeyExists   = errors.New("passkey уже зарегистрирован")
+	ErrPasskeyNotFound = errors.New("passkey не найден")
+	ErrReauthRequired  = errors.New("для этого действия войдите заново")
+)
+
+// Значения typ в JWT с challenge: challenge регистрации нельзя предъявить при входе и наоборот.
+const (
+	passkeyRegistrationType = "webauthn_registration"
+	passkeyLoginType        = "webauthn_login"
+)
+
+// BeginPasskeyRegistration возвращает параметры для navigator.credentials.create() и
+// подписанный challenge_token, который клиент вернёт вместе с ответом аутентификатора.
+// authTime — время входа в сессию из claim auth_time.
+func (s *UserService) BeginPasskeyRegistration(ctx context.Context, userID string, authTime time.Time) (*webauthn.CreationOptions, string, error) {
+	if err := s.requireRecentLogin(authTime); err != nil {
+		return nil, "", err
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	passkeys, err := s.userRepository.ListPasskeys(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// аутентификатор не должен создавать второй ключ, если на нём уже есть ключ этого пользователя
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		exclude = append(exclude, webauthn.CredentialDescriptor{Type: "public-key", ID: passkey.CredentialID, Transports: passkey.Transports})
+	}
+
+	challenge, challengeToken, err := s.newPasskeyChallenge(passkeyRegistrationType, user.ID.String())
+	if err != nil {
+		return nil, "", err
+	}
+
+	name := user.Email
+	if name == "" {
+		name = user.Username
+	}
+
+	options := s.relyingParty.CreationOptions(challenge, webauthn.UserEntity{
+		ID:          user.ID[:],
+		Name:        name,
+		DisplayName: name,
+	}, exclude)
+
+	return &options, challengeToken, nil
+}
+
+// FinishPasskeyRegistration проверяет ответ аутентификатора и сохраняет ключ.
+func (s *UserService) FinishPasskeyRegistration(ctx context.Context, userID, sessionID string, authTime time.Time, challengeToken, name string, credential webauthn.AttestationCredential, ip, userAgent string) (*model.Passkey, error) {
+	if err := s.requireRecentLogin(authTime); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.parsePasskeyChallenge(challengeToken, passkeyRegistrationType)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.subject != user.ID.String() {
+		return nil, ErrInvalidPasskey
+	}
+
+	verified, err := s.relyingParty.VerifyRegistration(challenge.value, credential)
+	if err != nil {
+		log.Println("passkey registration:", err)
+		return nil, ErrInvalidPasskey
+	}
+
+	aaguid, err := uuid.FromBytes(verified.AAGUID)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	transports := verified.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	var created *model.Passkey
+	err = s.userRepository.WithTx(ctx, func(users *repository.UserRepository, tokens *repository.TokenRepository) error {
+		if err := s.useChallenge(ctx, users, challenge); err != nil {
+			return err
+		}
+
+		created, err = users.CreatePasskey(ctx, model.Passkey{
+			UserID:         user.ID,
+			CredentialID:   verified.ID,
+			PublicKey:      verified.PublicKey,
+			Algorithm:      verified.Algorithm,
+			SignCount:      int64(verified.SignCount),
+			AAGUID:         aaguid,
+			Transports:     transports,
+			BackupEligible: verified.BackupEligible,
+			BackupState:    verified.BackupState,
+			Name:           name,
+		})
+		if errors.Is(err, repository.ErrPasskeyExists) {
+			return ErrPasskeyExists
+		}
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, tokens, userEvent(model.SecurityEventPasskeyAdded, model.ReasonUserRequest, user.ID, sessionID, ip, userAgent))
+	})
+	if err != nil && !errors.Is(err, ErrInvalidPasskey) && !errors.Is(err, ErrPasskeyExists) {
+		return nil, fmt.Errorf("не удалось сохранить passkey: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// BeginPasskeyLogin возвращает параметры для navigator.credentials.get(). Пользователь
+// заранее не известен: его определяет выбранный на аутентификаторе passkey.
+func (s *UserService) BeginPasskeyLogin() (*webauthn.RequestOptions, string, error) {
+	challenge, challengeToken, err := s.newPasskeyChallenge(passkeyLoginType, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	options := s.relyingParty.RequestOptions(challenge)
+
+	return &options, challengeToken, nil
+}
+
+// FinishPasskeyLogin проверяет подпись passkey и открывает сессию через TokenService.GetTokens.
+// Passkey с проверкой пользователя — самостоятельный фактор, поэтому ни пароль, ни TOTP не нужны.
+func (s *UserService) FinishPasskeyLogin(ctx context.Context, challengeToken string, credential webauthn.AssertionCredential, ip, userAgent string) (*model.TokenPair, error) {
+	challenge, err := s.parsePasskeyChallenge(challengeToken, passkeyLoginType)
+	if err != nil {
+		return nil, err
+	}
+
+	passkey, err := s.userRepository.GetPasskeyByCredentialID(ctx, credential.RawID)
+	if errors.Is(err, repository.ErrPasskeyNotFound) {
+		return nil, ErrInvalidPasskey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// userHandle — user.id, записанный в ключ при регистрации
+	if len(credential.Response.UserHandle) > 0 && string(credential.Response.UserHandle) != string(passkey.UserID[:]) {
+		return nil, ErrInvalidPasskey
+	}
+
+	assertion, err := s.relyingParty.VerifyAssertion(challenge.value, credential, passkey.PublicKey, uint32(passkey.SignCount))
+	if errors.Is(err, webauthn.ErrSignCountRollback) {
+		return nil, ErrPasskeyCloned
+	}
+	if err != nil {
+		log.Println("passkey login:", err)
+		return nil, ErrInvalidPasskey
+	}
+
+	err = s.userRepository.WithTx(ctx, func(users *repository.UserRepository, tokens *repository.TokenRepository) error {
+		if err := s.useChallenge(ctx, users, challenge); err != nil {
+			return err
+		}
+
+		used, err := users.UsePasskey(ctx, passkey.ID, passkey.SignCount, int64(assertion.SignCount), assertion.BackupState)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidPasskey
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenService.GetTokens(ctx, model.SessionParams{
+		UserID:    passkey.UserID.String(),
+		SessionID: uuid.New().String(),
+		UserAgent: userAgent,
+		IPAddress: ip,
+		Reason:    model.ReasonPasskeyVerified,
+		AMR:       []string{model.AMRHardwareKey},
+	})
+}
+
+func (s *UserService) ListPasskeys(ctx context.Context, userID string) ([]model.Passkey, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userRepository.ListPasskeys(ctx, user.ID)
+}
+
+func (s *UserService) DeletePasskey(ctx context.Context, userID, sessionID, passkeyID, ip, userAgent string) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(passkeyID)
+	if err != nil {
+		return ErrPasskeyNotFound
+	}
+
+	err = s.userRepository.WithTx(ctx, func(users *repository.UserRepository, tokens *repository.TokenRepository) error {
+		if err := users.DeletePasskey(ctx, user.ID, id); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, tokens, userEvent(model.SecurityEventPasskeyRemoved, model.ReasonUserRequest, user.ID, sessionID, ip, userAgent))
+	})
+	if errors.Is(err, repository.ErrPasskeyNotFound) {
+		return ErrPasskeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось удалить passkey: %w", err)
+	}
+
+	return nil
+}
+
+// requireRecentLogin пропускает только сессию, в которую пользователь вошёл не раньше
+// WEBAUTHN_REAUTH_MAX_AGE. Passkey — самостоятельный фактор входа, поэтому его нельзя
+// добавить по одному перехваченному токену: сессия, продлённая через /refresh, или токены,
+// выпущенные клиентом через /tokens (без auth_time), требуют нового входа.
+func (s *UserService) requireRecentLogin(authTime time.Time) error {
+	if authTime.IsZero() || time.Since(authTime) > s.cfg.WebAuthnReauthMaxAge {
+		return ErrReauthRequired
+	}
+
+	return nil
+}
+
+type passkeyChallenge struct {
+	value     []byte
+	subject   string
+	jti       string
+	expiresAt time.Time
+}
+
+// newPasskeyChallenge создаёт challenge и подписывает его в JWT: сервер ничего не хранит
+// до ответа аутентификатора.
+func (s *UserService) newPasskeyChallenge(typ, subject string) ([]byte, string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"typ":       typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"jti":       uuid.NewString(),
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.cfg.WebAuthnTimeout).Unix(),
+	}
+	if subject != "" {
+		claims["sub"] = subject
+	}
+
+	challengeToken, err := s.tokenManager.Sign(claims)
+	if err != nil {
+		return nil, "", fmt.Errorf("не удалось подписать challenge: %w", err)
+	}
+
+	return challenge, challengeToken, nil
+}
+
+func (s *UserService) parsePasskeyChallenge(challengeToken, typ string) (*passkeyChallenge, error) {
+	claims, err := s.tokenManager.ParseClaims(challengeToken)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	claimType, _ := claims["typ"].(string)
+	encoded, _ := claims["challenge"].(string)
+	jti, _ := claims["jti"].(string)
+	subject, _ := claims["sub"].(string)
+	expiresAt, err := claims.GetExpirationTime()
+	if claimType != typ || jti == "" || err != nil || expiresAt == nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(value) == 0 {
+		return nil, ErrInvalidPasskey
+	}
+
+	return &passkeyChallenge{value: value, subject: subject, jti: jti, expiresAt: expiresAt.Time}, nil
+}
+
+// useChallenge делает challenge одноразовым, иначе перехваченный ответ аутентификатора
+// можно было бы предъявить ещё раз, пока не истёк challenge_token.
+func (s *UserService) useChallenge(ctx context.Context, users *repository.UserRepository, challenge *passkeyChallenge) error {
+	used, err := users.UseWebAuthnChallenge(ctx, challenge.jti, challenge.expiresAt)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidPasskey
+	}
+
+	return nil
+}
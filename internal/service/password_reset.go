@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hh/internal/mail"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/internal/token"
+	"log"
+	"net/url"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("ссылка сброса пароля недействительна, истекла или уже использована")
+
+const (
+	// passwordResetQueueSize — сколько запросов сброса ждут отправки; сверх этого они
+	// отбрасываются, чтобы поток запросов не копил горутины и память
+	passwordResetQueueSize = 100
+
+	// passwordResetTimeout — сколько даётся на поиск пользователя и отправку одного письма,
+	// а при остановке — на отправку всех писем, оставшихся в очереди
+	passwordResetTimeout = 30 * time.Second
+)
+
+// ForgotPassword ставит отправку ссылки сброса в очередь RunPasswordResets и не ждёт её:
+// ни ответ, ни время ответа не зависят от того, есть ли такой пользователь. Когда очередь
+// полна, запрос отбрасывается.
+func (s *UserService) ForgotPassword(ctx context.Context, email string) {
+	select {
+	case s.passwordResets <- email:
+	default:
+		log.Println("password reset email: очередь переполнена, запрос отброшен")
+	}
+}
+
+// RunPasswordResets отправляет письма из очереди ForgotPassword, пока не отменён ctx, каждое
+// не дольше passwordResetTimeout. После отмены досылает уже принятые письма за общий
+// passwordResetTimeout и возвращается, поэтому main дожидается его при остановке.
+func (s *UserService) RunPasswordResets(ctx context.Context) {
+	for {
+		select {
+		case email := <-s.passwordResets:
+			s.processPasswordReset(context.WithoutCancel(ctx), email)
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetTimeout)
+			defer cancel()
+
+			for {
+				select {
+				case email := <-s.passwordResets:
+					s.processPasswordReset(drainCtx, email)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *UserService) processPasswordReset(ctx context.Context, email string) {
+	ctx, cancel := context.WithTimeout(ctx, passwordResetTimeout)
+	defer cancel()
+
+	if err := s.sendPasswordReset(ctx, email); err != nil {
+		log.Println("password reset email:", err)
+	}
+}
+
+func (s *UserService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepository.GetUserByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	resetToken, selector, verifierHash, err := token.NewSplitToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = s.userRepository.CreatePasswordReset(ctx, model.PasswordReset{
+		UserID:       user.ID,
+		Selector:     selector,
+		VerifierHash: verifierHash,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.cfg.PasswordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.cfg.PasswordResetURL + "?token=" + url.QueryEscape(resetToken)
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: "Чтобы задать новый пароль, откройте ссылку:\n\n" + link +
+			"\n\nСсылка одноразовая и действует " + s.cfg.PasswordResetTTL.String() + ". Если вы не запрашивали сброс, просто проигнорируйте это письмо.\n",
+	})
+}
+
+// ResetPassword меняет пароль по токену из письма, отзывает все сессии пользователя
+// и пишет событие password_reset в одной транзакции.
+func (s *UserService) ResetPassword(ctx context.Context, resetToken, newPassword, ip, userAgent string) error {
+	selector, _, ok := token.SplitRefreshToken(resetToken)
+	if !ok {
+		return ErrInvalidResetToken
+	}
+
+	reset, err := s.userRepository.GetPasswordResetBySelector(ctx, selector)
+	if errors.Is(err, repository.ErrPasswordResetNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if token.VerifySplitToken(reset.VerifierHash, resetToken) != nil || reset.UsedAt != nil || !time.Now().Before(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepository.WithTx(ctx, func(users *repository.UserRepository, tokens *repository.TokenRepository) error {
+		consumed, err := users.ConsumePasswordReset(ctx, reset.ID, reset.UserID)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return ErrInvalidResetToken
+		}
+
+		if err := users.SetPasswordHash(ctx, reset.UserID, hash); err != nil {
+			return err
+		}
+
+		if err := tokens.RevokeUserTokens(ctx, reset.UserID.String()); err != nil {
+			return err
+		}
+
+		// сброс — это восстановление после возможного захвата учётной записи: passkey,
+		// добавленный захватчиком, иначе пережил бы смену пароля и отзыв сессий
+		removed, err := users.DeleteAllPasskeys(ctx, reset.UserID)
+		if err != nil {
+			return err
+		}
+		if removed > 0 {
+			if err := recordEvent(ctx, tokens, userEvent(model.SecurityEventPasskeyRemoved, model.ReasonResetTokenVerified, reset.UserID, "", ip, userAgent)); err != nil {
+				return err
+			}
+		}
+
+		return recordEvent(ctx, tokens, userEvent(model.SecurityEventPasswordReset, model.ReasonResetTokenVerified, reset.UserID, "", ip, userAgent))
+	})
+	if err != nil && !errors.Is(err, ErrInvalidResetToken) {
+		return fmt.Errorf("не удалось сбросить пароль: %w", err)
+	}
+
+	return err
+}
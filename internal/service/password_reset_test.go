@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Токен не в формате <selector>.<verifier> отклоняется до поиска в БД и до хэширования пароля.
+func TestResetPasswordRejectsMalformedToken(t *testing.T) {
+	s := newTestUserService(t)
+
+	for _, resetToken := range []string{"", "selector", ".verifier", "selector."} {
+		err := s.ResetPassword(context.Background(), resetToken, "new password", "10.0.0.1", "curl")
+		if !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("ResetPassword(%q) error = %v, want %v", resetToken, err, ErrInvalidResetToken)
+		}
+	}
+}
+
+// ForgotPassword не ждёт отправки и не копит запросы сверх очереди.
+func TestForgotPasswordQueueIsBounded(t *testing.T) {
+	s := newTestUserService(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range passwordResetQueueSize + 10 {
+			s.ForgotPassword(context.Background(), "user@example.com")
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ForgotPassword() blocked on a full queue")
+	}
+
+	if got := len(s.passwordResets); got != passwordResetQueueSize {
+		t.Errorf("queue length = %d, want %d", got, passwordResetQueueSize)
+	}
+}
+
+// После отмены ctx воркер разбирает очередь и возвращается, чтобы main мог завершиться.
+func TestRunPasswordResetsStopsOnCancel(t *testing.T) {
+	s := newTestUserService(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.RunPasswordResets(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPasswordResets() did not return after cancel")
+	}
+}
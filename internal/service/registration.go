@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hh/internal/mail"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/internal/token"
+	"log"
+	"net/url"
+	"time"
+)
+
+var ErrInvalidVerificationToken = errors.New("ссылка подтверждения недействительна или уже использована")
+
+// Register создаёт пользователя с неподтверждённым email и отправляет ссылку подтверждения.
+// Если письмо не ушло, пользователь всё равно создан: ссылку можно запросить повторно.
+func (s *UserService) Register(ctx context.Context, req model.RegisterRequest) (*model.User, error) {
+	user, err := s.createUser(ctx, model.User{Email: req.Email, Username: req.Username}, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sendVerification(ctx, user); err != nil {
+		log.Println("verification email:", err)
+	}
+
+	return user, nil
+}
+
+// ResendVerification отправляет новую ссылку. Для неизвестного или уже подтверждённого адреса
+// ничего не делает и не сообщает об этом, чтобы по ответу нельзя было перебирать адреса.
+func (s *UserService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.userRepository.GetUserByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+// VerifyEmail подтверждает адрес по токену из ссылки. Ссылка одноразовая и подтверждает
+// только тот адрес, на который ушла: если email с тех пор сменился, она недействительна.
+func (s *UserService) VerifyEmail(ctx context.Context, verificationToken string) (*model.User, error) {
+	selector, _, ok := token.SplitRefreshToken(verificationToken)
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	verification, err := s.userRepository.GetEmailVerificationBySelector(ctx, selector)
+	if errors.Is(err, repository.ErrEmailVerificationNotFound) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if token.VerifySplitToken(verification.VerifierHash, verificationToken) != nil || verification.UsedAt != nil || !time.Now().Before(verification.ExpiresAt) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	err = s.userRepository.WithTx(ctx, func(users *repository.UserRepository, tokens *repository.TokenRepository) error {
+		consumed, err := users.ConsumeEmailVerification(ctx, verification.ID, verification.UserID)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return ErrInvalidVerificationToken
+		}
+
+		verified, err := users.VerifyEmail(ctx, verification.UserID, verification.Email)
+		if err != nil {
+			return err
+		}
+		if !verified {
+			return ErrInvalidVerificationToken
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrInvalidVerificationToken) {
+		return nil, fmt.Errorf("не удалось подтвердить email: %w", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userRepository.GetUser(ctx, verification.UserID)
+}
+
+func (s *UserService) sendVerification(ctx context.Context, user *model.User) error {
+	verificationToken, selector, verifierHash, err := token.NewSplitToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = s.userRepository.CreateEmailVerification(ctx, model.EmailVerification{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Selector:     selector,
+		VerifierHash: verifierHash,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.cfg.EmailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.cfg.EmailVerificationURL + "?token=" + url.QueryEscape(verificationToken)
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: "Чтобы подтвердить адрес и войти, откройте ссылку:\n\n" + link +
+			"\n\nСсылка одноразовая и действует " + s.cfg.EmailVerificationTTL.String() + ". Если вы не регистрировались, просто проигнорируйте это письмо.\n",
+	})
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Токен не в формате <selector>.<verifier> отклоняется до поиска в БД.
+func TestVerifyEmailRejectsMalformedToken(t *testing.T) {
+	s := newTestUserService(t)
+
+	for _, verificationToken := range []string{"", "selector", ".verifier", "selector."} {
+		if _, err := s.VerifyEmail(context.Background(), verificationToken); !errors.Is(err, ErrInvalidVerificationToken) {
+			t.Errorf("VerifyEmail(%q) error = %v, want %v", verificationToken, err, ErrInvalidVerificationToken)
+		}
+	}
+}
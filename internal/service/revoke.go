@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"hh/internal/model"
+	"hh/internal/token"
+)
+
+// Revoke реализует RFC 7009: отзывается сессия, к которой относится токен. Неизвестные,
+// невалидные и чужие токены молча игнорируются, чтобы запрос был идемпотентным.
+func (s *TokenService) Revoke(ctx context.Context, client *model.Client, tokenString, tokenTypeHint, ip, userAgent string) error {
+	lookups := []func(context.Context, string) (*model.RefreshTokenRecord, error){
+		s.sessionByAccessToken,
+		s.sessionByRefreshToken,
+	}
+	if tokenTypeHint == "refresh_token" {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		storedToken, err := lookup(ctx, tokenString)
+		if err != nil {
+			return err
+		}
+		if storedToken == nil {
+			continue
+		}
+
+		if !client.Trusted && stringValue(storedToken.ClientID) != client.ID {
+			return nil
+		}
+
+		event := tokenEvent(storedToken, model.SecurityEventSessionRevoked, model.ReasonClientRevocation, ip, userAgent)
+		event.ClientID = client.ID
+
+		return s.revokeSession(ctx, event)
+	}
+
+	return nil
+}
+
+func (s *TokenService) sessionByAccessToken(ctx context.Context, tokenString string) (*model.RefreshTokenRecord, error) {
+	claims, err := s.tokenManager.ParseExpiredClaims(tokenString)
+	if err != nil {
+		return nil, nil
+	}
+
+	userID, _ := claims["sub"].(string)
+	sessionID, _ := claims["session_id"].(string)
+
+	storedToken, err := s.tokenRepository.GetActiveRefreshToken(ctx, sessionID)
+	if err != nil || storedToken.UserID.String() != userID {
+		return nil, nil
+	}
+
+	return storedToken, nil
+}
+
+func (s *TokenService) sessionByRefreshToken(ctx context.Context, tokenString string) (*model.RefreshTokenRecord, error) {
+	selector, _, ok := token.SplitRefreshToken(tokenString)
+	if !ok {
+		return nil, nil
+	}
+
+	storedToken, err := s.tokenRepository.GetRefreshTokenBySelector(ctx, selector)
+	if err != nil {
+		return nil, nil
+	}
+
+	if err := s.tokenManager.VerifyRefreshToken(storedToken.RefreshTokenHash, tokenString); err != nil {
+		return nil, nil
+	}
+
+	// уже отозванный токен, в том числе сменённый при /refresh, отзывать нечего: повторный
+	// запрос не должен снова писать событие и слать webhook
+	if storedToken.Revoked {
+		return nil, nil
+	}
+
+	return storedToken, nil
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+	"hh/internal/model"
+	"testing"
+)
+
+// RFC 7009: на невалидный токен сервер отвечает так же, как на отозванный, и в БД не ходит.
+func TestRevokeIgnoresUnparseableToken(t *testing.T) {
+	s := &TokenService{tokenManager: newTestTokenManager(t)}
+
+	for _, hint := range []string{"", "access_token", "refresh_token"} {
+		if err := s.Revoke(context.Background(), &model.Client{ID: "billing"}, "garbage", hint, "10.0.0.1", "curl"); err != nil {
+			t.Errorf("Revoke(hint=%q) error = %v", hint, err)
+		}
+	}
+}
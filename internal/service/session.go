@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("сессия не найдена")
+
+func (s *TokenService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]model.Session, error) {
+	sessions, err := s.tokenRepository.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID.String() == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession отзывает сессию пользователя; reason отличает запрос самого пользователя от поддержки.
+func (s *TokenService) RevokeSession(ctx context.Context, userID, sessionID, reason, ip, userAgent string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	err = s.tokenRepository.WithTx(ctx, func(repo *repository.TokenRepository) error {
+		if err := repo.RevokeUserSession(ctx, userID, sessionID); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, repo, newSecurityEvent(model.SecurityEventSessionRevoked, reason, userUUID, sessionUUID, "", ip, userAgent))
+	})
+	if errors.Is(err, repository.ErrSessionNotActive) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TokenService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID, ip, userAgent string) (int64, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, fmt.Errorf("невалидный user_id: %w", err)
+	}
+
+	var revoked []uuid.UUID
+
+	err = s.tokenRepository.WithTx(ctx, func(repo *repository.TokenRepository) error {
+		var err error
+		revoked, err = repo.RevokeOtherSessions(ctx, userID, currentSessionID)
+		if err != nil {
+			return err
+		}
+
+		for _, sessionID := range revoked {
+			event := newSecurityEvent(model.SecurityEventSessionRevoked, model.ReasonOtherSessionsRevoked, userUUID, sessionID, "", ip, userAgent)
+			if err := recordEvent(ctx, repo, event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return int64(len(revoked)), nil
+}
+
+func deviceFromUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case strings.Contains(ua, "iphone"):
+		return "iPhone"
+	case strings.Contains(ua, "ipad"):
+		return "iPad"
+	case strings.Contains(ua, "android"):
+		return "Android"
+	case strings.Contains(ua, "windows"):
+		return "Windows"
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		return "macOS"
+	case strings.Contains(ua, "linux"):
+		return "Linux"
+	case userAgent == "":
+		return "unknown"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hh/internal/model"
+	"testing"
+)
+
+func TestDeviceFromUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15", "iPhone"},
+		{"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15", "iPad"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36", "Android"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36", "Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15", "macOS"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", "Linux"},
+		{"curl/8.4.0", "other"},
+		{"", "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := deviceFromUserAgent(tt.userAgent); got != tt.want {
+			t.Errorf("deviceFromUserAgent(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+// Чужой или битый session id не должен доходить до БД.
+func TestRevokeSessionRejectsMalformedID(t *testing.T) {
+	s := &TokenService{}
+
+	for _, sessionID := range []string{"", "current", "123"} {
+		if err := s.RevokeSession(context.Background(), "user", sessionID, model.ReasonUserRequest, "10.0.0.1", "curl"); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("RevokeSession(%q) error = %v, want %v", sessionID, err, ErrSessionNotFound)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"hh/config"
+	"hh/internal/geoip"
 	"hh/internal/model"
 	"hh/internal/repository"
 	"hh/internal/token"
@@ -12,117 +14,281 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidRefreshToken      = errors.New("невалидный refresh token")
+	ErrRefreshTokenUsed         = errors.New("refresh token уже использован")
+	ErrRefreshTokenExpired      = errors.New("refresh token истёк")
+	ErrSessionRevoked           = errors.New("сессия отозвана")
+	ErrReauthenticationRequired = errors.New("требуется повторная аутентификация")
+)
+
 type TokenService struct {
-	tokenManager    *token.Manager
-	tokenRepository *repository.TokenRepository
-	cfg             *config.Config
+	tokenManager     *token.Manager
+	tokenRepository  *repository.TokenRepository
+	clientRepository *repository.ClientRepository
+	cfg              *config.Config
+	bindingPolicy    model.BindingPolicy
+	geo              geoip.Resolver
+}
+
+// NewTokenService принимает политику привязки по умолчанию из ParseBindingPolicy;
+// geo может быть nil, тогда сигналы ASN и страны не оцениваются.
+func NewTokenService(tokenManager *token.Manager, tokenRepository *repository.TokenRepository, clientRepository *repository.ClientRepository, cfg *config.Config, bindingPolicy model.BindingPolicy, geo geoip.Resolver) *TokenService {
+	return &TokenService{
+		tokenManager:     tokenManager,
+		tokenRepository:  tokenRepository,
+		clientRepository: clientRepository,
+		cfg:              cfg,
+		bindingPolicy:    bindingPolicy,
+		geo:              geo,
+	}
 }
 
-func NewTokenService(tokenManager *token.Manager, tokenRepository *repository.TokenRepository) *TokenService {
-	return &TokenService{tokenManager: tokenManager, tokenRepository: tokenRepository}
+func (s *TokenService) JWKS() token.JWKS {
+	return s.tokenManager.JWKS()
 }
 
-func (s *TokenService) GetTokens(ctx context.Context, userID, userAgent, sessionID, ip string) (*model.TokenPair, error) {
-	accessToken, err := s.tokenManager.NewJWT(userID, sessionID, 15*time.Minute)
+func (s *TokenService) GetTokens(ctx context.Context, params model.SessionParams) (*model.TokenPair, error) {
+	familyID, err := uuid.Parse(params.SessionID)
 	if err != nil {
-		return &model.TokenPair{}, err
+		return nil, fmt.Errorf("невалидный session_id: %w", err)
 	}
 
-	refreshToken, hashToken, err := s.tokenManager.NewRefreshToken()
+	lifetimes, err := s.lifetimesFor(ctx, params.ClientID)
 	if err != nil {
-		return &model.TokenPair{}, err
+		return nil, err
 	}
 
 	tokenID, _ := uuid.NewUUID()
+	now := time.Now()
+
+	// auth_time есть только у сессий, которые открыл сам пользователь, а не клиент по user_id
+	var authTime *time.Time
+	if len(params.AMR) > 0 {
+		authTime = &now
+	}
+
+	accessToken, err := s.tokenManager.NewJWT(token.Claims{
+		UserID:    params.UserID,
+		SessionID: params.SessionID,
+		TokenID:   tokenID.String(),
+		ClientID:  params.ClientID,
+		Scope:     params.Scope,
+		AMR:       params.AMR,
+		AuthTime:  timeValue(authTime),
+	}, lifetimes.accessTokenTTL)
+	if err != nil {
+		return &model.TokenPair{}, err
+	}
+
+	refreshToken, selector, hashToken, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return &model.TokenPair{}, err
+	}
 
-	userUUID, _ := uuid.Parse(userID)
+	userUUID, _ := uuid.Parse(params.UserID)
+	sessionExpiresAt := now.Add(lifetimes.sessionMaxLifetime)
 
 	refreshTokenRecord := model.RefreshTokenRecord{
 		ID:               tokenID,
+		FamilyID:         familyID,
 		UserID:           userUUID,
+		Selector:         selector,
 		RefreshTokenHash: hashToken,
-		UserAgent:        userAgent,
-		IPAddress:        ip,
+		UserAgent:        params.UserAgent,
+		IPAddress:        params.IPAddress,
+		Device:           deviceFromUserAgent(params.UserAgent),
+		Scope:            params.Scope,
+		AMR:              amrOrEmpty(params.AMR),
+		AuthTime:         authTime,
 		Revoked:          false,
-		CreatedAt:        time.Now(),
+		CreatedAt:        now,
+		ExpiresAt:        lifetimes.refreshExpiresAt(now, sessionExpiresAt),
+		SessionExpiresAt: sessionExpiresAt,
+	}
+	if params.ClientID != "" {
+		refreshTokenRecord.ClientID = &params.ClientID
 	}
 
-	_, err = s.tokenRepository.GetTokens(ctx, refreshTokenRecord)
+	err = s.tokenRepository.WithTx(ctx, func(repo *repository.TokenRepository) error {
+		if _, err := repo.GetTokens(ctx, refreshTokenRecord); err != nil {
+			return err
+		}
+
+		reason := params.Reason
+		if reason == "" {
+			reason = model.ReasonTokenIssued
+		}
+
+		return recordEvent(ctx, repo, newSecurityEvent(
+			model.SecurityEventLogin,
+			reason,
+			userUUID,
+			familyID,
+			params.ClientID,
+			params.IPAddress,
+			params.UserAgent,
+		))
+	})
 	if err != nil {
 		return nil, fmt.Errorf("ошибка сохранения refresh token: %w", err)
 	}
 
-	return &model.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(lifetimes.accessTokenTTL.Seconds()),
+		Scope:        params.Scope,
+	}, nil
 }
 
 func (s *TokenService) RefreshTokens(ctx context.Context, oldAccessToken, oldRefreshToken, userAgent, ip string) (*model.RefreshRequest, error) {
-	claims, err := s.tokenManager.Parse(oldAccessToken)
+	storedToken, err := s.findRefreshToken(ctx, oldAccessToken, oldRefreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		auditErr := s.tokenRepository.RecordAudit(ctx, model.AuditEntry{
+			ID:         uuid.New(),
+			OccurredAt: time.Now(),
+			Action:     model.SecurityEventRefresh,
+			ActorType:  model.AuditActorAnonymous,
+			IPAddress:  ip,
+			UserAgent:  userAgent,
+			Outcome:    model.AuditOutcomeFailure,
+			Reason:     model.ReasonRefreshTokenNotFound,
+		})
+		if auditErr != nil {
+			return nil, auditErr
+		}
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("невалидный токен: %w", err)
+		return nil, err
 	}
 
-	userID := claims
+	userID := storedToken.UserID.String()
+	sessionID := storedToken.FamilyID.String()
 
-	storedToken, err := s.tokenRepository.GetRefreshToken(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при получении refresh token: %w", err)
+	if err := s.tokenManager.VerifyRefreshToken(storedToken.RefreshTokenHash, oldRefreshToken); err != nil {
+		event := tokenEvent(storedToken, model.SecurityEventInvalidRefreshToken, model.ReasonVerifierMismatch, ip, userAgent)
+		if err := s.revokeSession(ctx, event); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
 	}
 
 	if storedToken.Revoked {
-		return nil, fmt.Errorf("token использован")
-	}
+		// предъявлен уже ротированный refresh token: это признак кражи,
+		// поэтому отзывается вся семья токенов
+		if storedToken.Rotated {
+			event := tokenEvent(storedToken, model.SecurityEventTokenReuse, model.ReasonRotatedTokenReused, ip, userAgent)
+			if err := s.revokeSession(ctx, event); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenUsed
+		}
 
-	if time.Now().After(storedToken.CreatedAt.Add(24 * time.Hour)) {
-		return nil, fmt.Errorf("token истек")
+		event := tokenEvent(storedToken, model.SecurityEventRefreshDenied, model.ReasonSessionRevoked, ip, userAgent)
+		if err := recordEvent(ctx, s.tokenRepository, event); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenUsed
 	}
 
-	if err := s.tokenManager.VerifyRefreshToken(storedToken.RefreshTokenHash, oldRefreshToken); err != nil {
-		if err := s.tokenRepository.RevokeUserTokens(ctx, userID); err != nil {
-			return nil, fmt.Errorf("не удалось отозвать токены: %w", err)
+	if s.refreshTokenExpired(storedToken) {
+		event := tokenEvent(storedToken, model.SecurityEventRefreshDenied, model.ReasonRefreshTokenExpired, ip, userAgent)
+		if err := recordEvent(ctx, s.tokenRepository, event); err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("невалидный refresh token")
+		return nil, ErrRefreshTokenExpired
 	}
 
-	if storedToken.UserAgent != userAgent {
-		if err := s.tokenRepository.RevokeUserTokens(ctx, userID); err != nil {
-			return nil, fmt.Errorf("не удалось отозвать токены: %w", err)
-		}
-		return nil, fmt.Errorf("несоответствие user agent")
+	bindingEvents, bindingAction, err := s.evaluateBinding(ctx, storedToken, ip, userAgent)
+	if err != nil {
+		return nil, err
 	}
 
-	if storedToken.IPAddress != ip && s.cfg.WebhookURL != "" {
-		go s.tokenRepository.SendIpChangeWebhook(ctx, userID, storedToken.IPAddress, userAgent, ip)
+	switch bindingAction {
+	case model.BindingActionRevoke:
+		if err := s.revokeSession(ctx, bindingEvents...); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", ErrSessionRevoked, bindingReasons(bindingEvents, bindingAction))
+	case model.BindingActionReauth:
+		err := s.tokenRepository.WithTx(ctx, func(repo *repository.TokenRepository) error {
+			return recordEvents(ctx, repo, bindingEvents)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", ErrReauthenticationRequired, bindingReasons(bindingEvents, bindingAction))
 	}
 
-	if err := s.tokenRepository.RevokeUserTokens(ctx, userID); err != nil {
-		return nil, fmt.Errorf("не удалось удалить старые токены: %w", err)
+	lifetimes, err := s.lifetimesFor(ctx, stringValue(storedToken.ClientID))
+	if err != nil {
+		return nil, err
 	}
 
 	tokenID, _ := uuid.NewUUID()
 
-	accessToken, err := s.tokenManager.NewJWT(userID, tokenID.String(), 15*time.Minute)
+	accessToken, err := s.tokenManager.NewJWT(token.Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenID:   tokenID.String(),
+		ClientID:  stringValue(storedToken.ClientID),
+		Scope:     storedToken.Scope,
+		AMR:       storedToken.AMR,
+		AuthTime:  timeValue(storedToken.AuthTime),
+	}, lifetimes.accessTokenTTL)
 	if err != nil {
 		return &model.RefreshRequest{}, err
 	}
 
-	refreshToken, hashToken, err := s.tokenManager.NewRefreshToken()
+	refreshToken, selector, hashToken, err := s.tokenManager.NewRefreshToken()
 	if err != nil {
 		return &model.RefreshRequest{}, err
 	}
 
-	userUUID, _ := uuid.Parse(userID)
+	now := time.Now()
 
 	refreshTokenRecord := model.RefreshTokenRecord{
 		ID:               tokenID,
-		UserID:           userUUID,
+		FamilyID:         storedToken.FamilyID,
+		ParentID:         &storedToken.ID,
+		UserID:           storedToken.UserID,
+		Selector:         selector,
 		RefreshTokenHash: hashToken,
 		UserAgent:        userAgent,
 		IPAddress:        ip,
+		Device:           storedToken.Device,
+		ClientID:         storedToken.ClientID,
+		Scope:            storedToken.Scope,
+		AMR:              amrOrEmpty(storedToken.AMR),
+		AuthTime:         storedToken.AuthTime,
 		Revoked:          false,
-		CreatedAt:        time.Now(),
+		CreatedAt:        now,
+		ExpiresAt:        lifetimes.refreshExpiresAt(now, storedToken.SessionExpiresAt),
+		SessionExpiresAt: storedToken.SessionExpiresAt,
 	}
 
-	_, err = s.tokenRepository.GetTokens(ctx, refreshTokenRecord)
+	err = s.tokenRepository.WithTx(ctx, func(repo *repository.TokenRepository) error {
+		if err := repo.RotateRefreshToken(ctx, storedToken.ID, refreshTokenRecord); err != nil {
+			return err
+		}
+
+		event := tokenEvent(storedToken, model.SecurityEventRefresh, model.ReasonTokenRotated, ip, userAgent)
+		if err := recordEvent(ctx, repo, event); err != nil {
+			return err
+		}
+
+		return recordEvents(ctx, repo, bindingEvents)
+	})
+	if errors.Is(err, repository.ErrSessionNotActive) {
+		event := tokenEvent(storedToken, model.SecurityEventRefreshDenied, model.ReasonConcurrentRotation, ip, userAgent)
+		if err := recordEvent(ctx, s.tokenRepository, event); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenUsed
+	}
 	if err != nil {
 		return nil, fmt.Errorf("ошибка сохранения refresh token: %w", err)
 	}
@@ -130,10 +296,123 @@ func (s *TokenService) RefreshTokens(ctx context.Context, oldAccessToken, oldRef
 	return &model.RefreshRequest{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
-func (s *TokenService) Logout(ctx context.Context, userID string) error {
-	if err := s.tokenRepository.RevokeUserTokens(ctx, userID); err != nil {
+// findRefreshToken ищет строку по selector из refresh token. Access token нужен только для
+// refresh token старого формата (bcrypt): без него такую строку не найти. Для selector.verifier
+// он не проверяется, иначе после вывода ключа подписи из keyring клиенты, которые присылают
+// прежний access token, не смогли бы обновить сессию.
+func (s *TokenService) findRefreshToken(ctx context.Context, accessToken, refreshToken string) (*model.RefreshTokenRecord, error) {
+	if selector, _, ok := token.SplitRefreshToken(refreshToken); ok {
+		storedToken, err := s.tokenRepository.GetRefreshTokenBySelector(ctx, selector)
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при получении refresh token: %w", err)
+		}
+
+		return storedToken, nil
+	}
+
+	if accessToken == "" {
+		return nil, fmt.Errorf("%w: для refresh token старого формата нужен access token", ErrInvalidRefreshToken)
+	}
+
+	claims, err := s.tokenManager.ParseExpiredClaims(accessToken)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	userID, _ := claims["sub"].(string)
+	sessionID, _ := claims["session_id"].(string)
+
+	var storedToken *model.RefreshTokenRecord
+	if tokenID, _ := claims["jti"].(string); tokenID != "" {
+		storedToken, err = s.tokenRepository.GetRefreshToken(ctx, tokenID)
+	} else {
+		storedToken, err = s.tokenRepository.GetActiveRefreshToken(ctx, sessionID)
+	}
+	if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении refresh token: %w", err)
+	}
+
+	if storedToken.UserID.String() != userID || storedToken.FamilyID.String() != sessionID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return storedToken, nil
+}
+
+// revokeSession отзывает семью токенов из SessionID событий и записывает события в одной
+// транзакции. Все события должны относиться к одной сессии.
+func (s *TokenService) revokeSession(ctx context.Context, events ...model.SecurityEvent) error {
+	err := s.tokenRepository.WithTx(ctx, func(repo *repository.TokenRepository) error {
+		if err := repo.RevokeFamily(ctx, events[0].SessionID.String()); err != nil {
+			return err
+		}
+
+		return recordEvents(ctx, repo, events)
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось отозвать токены: %w", err)
+	}
+
+	return nil
+}
+
+// Logout отзывает все сессии пользователя; событие относится к сессии, из которой вышли.
+func (s *TokenService) Logout(ctx context.Context, userID, sessionID, ip, userAgent string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("невалидный user_id: %w", err)
+	}
+	sessionUUID, _ := uuid.Parse(sessionID)
+
+	err = s.tokenRepository.WithTx(ctx, func(repo *repository.TokenRepository) error {
+		if err := repo.RevokeUserTokens(ctx, userID); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, repo, newSecurityEvent(
+			model.SecurityEventLogout,
+			model.ReasonUserLogout,
+			userUUID,
+			sessionUUID,
+			"",
+			ip,
+			userAgent,
+		))
+	})
+	if err != nil {
 		return fmt.Errorf("failed to revoke tokens: %w", err)
 	}
 
 	return nil
 }
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return *t
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+// amrOrEmpty нужен, потому что колонка amr NOT NULL, а nil-срез pgx пишет как NULL.
+func amrOrEmpty(amr []string) []string {
+	if amr == nil {
+		return []string{}
+	}
+
+	return amr
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Refresh token, который нельзя сопоставить со строкой без БД, — неверные учётные данные,
+// а не внутренняя ошибка: их считает блокировка /refresh.
+func TestFindRefreshTokenInvalidCredentials(t *testing.T) {
+	s := &TokenService{tokenManager: newTestTokenManager(t)}
+
+	tests := []struct {
+		name         string
+		accessToken  string
+		refreshToken string
+	}{
+		{"legacy token without access token", "", "legacy-refresh-token"},
+		{"legacy token with garbage access token", "garbage", "legacy-refresh-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.findRefreshToken(context.Background(), tt.accessToken, tt.refreshToken)
+			if !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("findRefreshToken() error = %v, want %v", err, ErrInvalidRefreshToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hh/config"
+	"hh/internal/mail"
+	"hh/internal/model"
+	"hh/internal/password"
+	"hh/internal/repository"
+	"hh/internal/secretbox"
+	"hh/internal/token"
+	"hh/internal/webauthn"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("неверный логин или пароль")
+	ErrInvalidUser        = errors.New("невалидный пользователь")
+	ErrUserExists         = errors.New("пользователь с таким email или username уже существует")
+	ErrEmailNotVerified   = errors.New("email не подтверждён")
+	ErrUserNotFound       = errors.New("пользователь не найден")
+)
+
+type UserService struct {
+	userRepository *repository.UserRepository
+	tokenService   *TokenService
+	tokenManager   *token.Manager
+	hasher         *password.Hasher
+	mailer         mail.Mailer
+	cfg            *config.Config
+
+	// secretBox шифрует TOTP-секреты; nil, если MFA_ENCRYPTION_KEY не задан
+	secretBox *secretbox.Box
+
+	// relyingParty проверяет церемонии WebAuthn для passkey
+	relyingParty *webauthn.RelyingParty
+
+	// dummyHash проверяется, когда пользователя нет: иначе по времени ответа видно,
+	// существует ли логин
+	dummyHash string
+
+	// passwordResets — очередь адресов для писем сброса пароля, её разбирает RunPasswordResets
+	passwordResets chan string
+}
+
+func NewUserService(userRepository *repository.UserRepository, tokenService *TokenService, tokenManager *token.Manager, hasher *password.Hasher, mailer mail.Mailer, cfg *config.Config) (*UserService, error) {
+	dummyHash, err := hasher.Hash(uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
+
+	var secretBox *secretbox.Box
+	if cfg.MFAEncryptionKey != "" {
+		secretBox, err = secretbox.NewFromBase64(cfg.MFAEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("MFA_ENCRYPTION_KEY: %w", err)
+		}
+	}
+
+	return &UserService{
+		userRepository: userRepository,
+		tokenService:   tokenService,
+		tokenManager:   tokenManager,
+		hasher:         hasher,
+		mailer:         mailer,
+		cfg:            cfg,
+		secretBox:      secretBox,
+		relyingParty: &webauthn.RelyingParty{
+			ID:      cfg.WebAuthnRPID,
+			Name:    cfg.WebAuthnRPName,
+			Origins: cfg.WebAuthnOrigins,
+			Timeout: cfg.WebAuthnTimeout,
+		},
+		dummyHash:      dummyHash,
+		passwordResets: make(chan string, passwordResetQueueSize),
+	}, nil
+}
+
+// CreateUser заводит пользователя от имени администратора, email считается подтверждённым.
+func (s *UserService) CreateUser(ctx context.Context, req model.CreateUserRequest) (*model.User, error) {
+	if req.Email == "" && req.Username == "" {
+		return nil, fmt.Errorf("%w: нужен email или username", ErrInvalidUser)
+	}
+
+	var verifiedAt *time.Time
+	if req.Email != "" {
+		now := time.Now()
+		verifiedAt = &now
+	}
+
+	return s.createUser(ctx, model.User{Email: req.Email, Username: req.Username, EmailVerifiedAt: verifiedAt}, req.Password)
+}
+
+func (s *UserService) createUser(ctx context.Context, user model.User, pass string) (*model.User, error) {
+	hash, err := s.hasher.Hash(pass)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = hash
+
+	created, err := s.userRepository.CreateUser(ctx, user)
+	if errors.Is(err, repository.ErrUserExists) {
+		return nil, ErrUserExists
+	}
+
+	return created, err
+}
+
+// Login проверяет пароль и открывает сессию через TokenService.GetTokens. Если у пользователя
+// подключён TOTP, сессия не открывается: возвращается mfa_token для CompleteMFALogin.
+func (s *UserService) Login(ctx context.Context, login, pass, ip, userAgent string) (*model.TokenPair, *model.MFAChallenge, error) {
+	user, err := s.authenticate(ctx, login, pass)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	required, err := s.mfaRequired(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if required {
+		challenge, err := s.mfaChallenge(user)
+		return nil, challenge, err
+	}
+
+	tokenPair, err := s.tokenService.GetTokens(ctx, model.SessionParams{
+		UserID:    user.ID.String(),
+		SessionID: uuid.New().String(),
+		UserAgent: userAgent,
+		IPAddress: ip,
+		Reason:    model.ReasonPasswordVerified,
+		AMR:       []string{model.AMRPassword},
+	})
+
+	return tokenPair, nil, err
+}
+
+func (s *UserService) authenticate(ctx context.Context, login, pass string) (*model.User, error) {
+	user, err := s.userRepository.GetUserByLogin(ctx, login)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		s.hasher.Verify(pass, s.dummyHash)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, needsRehash, err := s.hasher.Verify(pass, user.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("пользователь %s: %w", user.ID, err)
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	// статус проверяется только после пароля, иначе по ответу видно, что адрес зарегистрирован
+	if user.Email != "" && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
+	// параметры Argon2id поменялись: пароль известен только сейчас, поэтому хэш пересчитывается при входе
+	if needsRehash {
+		s.rehash(ctx, user, pass)
+	}
+
+	return user, nil
+}
+
+func (s *UserService) rehash(ctx context.Context, user *model.User, pass string) {
+	hash, err := s.hasher.Hash(pass)
+	if err == nil {
+		err = s.userRepository.UpdatePasswordHash(ctx, user.ID, user.PasswordHash, hash)
+	}
+	if err != nil {
+		log.Println("rehash:", err)
+		return
+	}
+
+	user.PasswordHash = hash
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hh/config"
+	"hh/internal/mail"
+	"hh/internal/model"
+	"hh/internal/password"
+	"testing"
+	"time"
+)
+
+func newTestUserService(t *testing.T) *UserService {
+	t.Helper()
+
+	hasher, err := password.NewHasher(password.Params{Memory: 64, Iterations: 1, Parallelism: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{PublicURL: "https://auth.example.com", EmailVerificationTTL: time.Hour}
+	s, err := NewUserService(nil, nil, newTestTokenManager(t), hasher, mail.NewMemoryMailer(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// Для несуществующего логина проверяется настоящий Argon2id хэш, иначе ответ выдаёт себя по времени.
+func TestNewUserServiceDummyHash(t *testing.T) {
+	s := newTestUserService(t)
+
+	ok, _, err := s.hasher.Verify("password", s.dummyHash)
+	if err != nil || ok {
+		t.Errorf("Verify(dummyHash) = %v, %v, want false without error", ok, err)
+	}
+}
+
+func TestCreateUserRequiresLogin(t *testing.T) {
+	s := newTestUserService(t)
+
+	_, err := s.CreateUser(context.Background(), model.CreateUserRequest{Password: "correct horse battery staple"})
+	if !errors.Is(err, ErrInvalidUser) {
+		t.Errorf("CreateUser() error = %v, want %v", err, ErrInvalidUser)
+	}
+}
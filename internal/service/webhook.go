@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hh/internal/model"
+	"hh/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var ErrWebhookEventNotFound = errors.New("dead событие не найдено")
+
+type WebhookService struct {
+	webhookRepository *repository.WebhookRepository
+}
+
+func NewWebhookService(webhookRepository *repository.WebhookRepository) *WebhookService {
+	return &WebhookService{webhookRepository: webhookRepository}
+}
+
+func (s *WebhookService) ListDead(ctx context.Context, limit, offset int) ([]model.WebhookOutboxEvent, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.webhookRepository.ListDead(ctx, limit, offset)
+}
+
+func (s *WebhookService) Replay(ctx context.Context, id string) error {
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrWebhookEventNotFound
+	}
+
+	err = s.webhookRepository.Replay(ctx, eventID)
+	if errors.Is(err, repository.ErrWebhookEventNotFound) {
+		return ErrWebhookEventNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWebhookSubscriptionNotFound = errors.New("подписка не найдена")
+	ErrInvalidWebhookSubscription  = errors.New("невалидная подписка")
+)
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error) {
+	return s.webhookRepository.ListSubscriptions(ctx)
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	subID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrWebhookSubscriptionNotFound
+	}
+
+	sub, err := s.webhookRepository.GetSubscription(ctx, subID)
+	if errors.Is(err, repository.ErrWebhookSubscriptionNotFound) {
+		return nil, ErrWebhookSubscriptionNotFound
+	}
+
+	return sub, err
+}
+
+// CreateSubscription возвращает подписку и её secret: если secret не передан, он генерируется
+// и больше нигде не показывается.
+func (s *WebhookService) CreateSubscription(ctx context.Context, req model.WebhookSubscriptionRequest) (*model.WebhookSubscription, string, error) {
+	if err := validateEventTypes(req.EventTypes); err != nil {
+		return nil, "", err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		secret = base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	sub, err := s.webhookRepository.CreateSubscription(ctx, model.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: eventTypesOrEmpty(req.EventTypes),
+		Format:     formatOrLegacy(req.Format),
+		Active:     req.Active == nil || *req.Active,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return sub, secret, nil
+}
+
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id string, req model.WebhookSubscriptionRequest) (*model.WebhookSubscription, error) {
+	if err := validateEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.URL = req.URL
+	sub.EventTypes = eventTypesOrEmpty(req.EventTypes)
+	if req.Secret != "" {
+		sub.Secret = req.Secret
+	}
+	if req.Format != "" {
+		sub.Format = req.Format
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+
+	sub, err = s.webhookRepository.UpdateSubscription(ctx, *sub)
+	if errors.Is(err, repository.ErrWebhookSubscriptionNotFound) {
+		return nil, ErrWebhookSubscriptionNotFound
+	}
+
+	return sub, err
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id string) error {
+	subID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrWebhookSubscriptionNotFound
+	}
+
+	err = s.webhookRepository.DeleteSubscription(ctx, subID)
+	if errors.Is(err, repository.ErrWebhookSubscriptionNotFound) {
+		return ErrWebhookSubscriptionNotFound
+	}
+
+	return err
+}
+
+// EnsureLegacySubscription превращает WEBHOOK_URL/WEBHOOK_SECRET/WEBHOOK_FORMAT в подписку на те события,
+// которые уходили на этот адрес до появления подписок.
+func (s *WebhookService) EnsureLegacySubscription(ctx context.Context, url, secret, format string) error {
+	if url == "" {
+		return nil
+	}
+
+	format = formatOrLegacy(format)
+	if !slices.Contains(webhookFormats, format) {
+		return fmt.Errorf("%w: неизвестный формат %q", ErrInvalidWebhookSubscription, format)
+	}
+
+	return s.webhookRepository.EnsureSubscription(ctx, model.WebhookSubscription{
+		URL:        url,
+		Secret:     secret,
+		EventTypes: []string{model.SecurityEventIPChange, model.SecurityEventTokenReuse},
+		Format:     format,
+	})
+}
+
+func validateEventTypes(eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		if !slices.Contains(model.SecurityEventTypes, eventType) {
+			return fmt.Errorf("%w: неизвестный тип события %q", ErrInvalidWebhookSubscription, eventType)
+		}
+	}
+
+	return nil
+}
+
+var webhookFormats = []string{
+	model.WebhookFormatLegacy,
+	model.WebhookFormatCloudEventsStructured,
+	model.WebhookFormatCloudEventsBinary,
+}
+
+func formatOrLegacy(format string) string {
+	if format == "" {
+		return model.WebhookFormatLegacy
+	}
+
+	return format
+}
+
+func eventTypesOrEmpty(eventTypes []string) []string {
+	if eventTypes == nil {
+		return []string{}
+	}
+
+	return eventTypes
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hh/internal/model"
+	"testing"
+)
+
+func TestValidateEventTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		eventTypes []string
+		wantErr    bool
+	}{
+		{"all events", nil, false},
+		{"known events", []string{model.SecurityEventIPChange, model.SecurityEventTokenReuse}, false},
+		{"unknown event", []string{model.SecurityEventIPChange, "user.deleted"}, true},
+		{"empty name", []string{""}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateEventTypes(tt.eventTypes)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateEventTypes() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidWebhookSubscription) {
+			t.Errorf("%s: error %v is not ErrInvalidWebhookSubscription", tt.name, err)
+		}
+	}
+}
+
+// nil в event_types означает подписку на всё и не должен уходить в БД как NULL.
+func TestEventTypesOrEmpty(t *testing.T) {
+	if got := eventTypesOrEmpty(nil); got == nil || len(got) != 0 {
+		t.Errorf("eventTypesOrEmpty(nil) = %#v, want empty slice", got)
+	}
+
+	types := []string{model.SecurityEventIPChange}
+	if got := eventTypesOrEmpty(types); len(got) != 1 || got[0] != model.SecurityEventIPChange {
+		t.Errorf("eventTypesOrEmpty(%v) = %v", types, got)
+	}
+}
+
+func TestFormatOrLegacy(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", model.WebhookFormatLegacy},
+		{model.WebhookFormatLegacy, model.WebhookFormatLegacy},
+		{model.WebhookFormatCloudEventsBinary, model.WebhookFormatCloudEventsBinary},
+	}
+
+	for _, tt := range tests {
+		if got := formatOrLegacy(tt.format); got != tt.want {
+			t.Errorf("formatOrLegacy(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+// опечатка в WEBHOOK_FORMAT должна останавливать старт, а не превращаться в legacy подписку.
+func TestEnsureLegacySubscriptionRejectsUnknownFormat(t *testing.T) {
+	s := &WebhookService{}
+
+	err := s.EnsureLegacySubscription(context.Background(), "https://siem.example.com/hooks/auth", "secret", "cloudevents")
+	if !errors.Is(err, ErrInvalidWebhookSubscription) {
+		t.Errorf("EnsureLegacySubscription() error = %v, want %v", err, ErrInvalidWebhookSubscription)
+	}
+
+	if err := s.EnsureLegacySubscription(context.Background(), "", "", "cloudevents"); err != nil {
+		t.Errorf("EnsureLegacySubscription() without url error = %v", err)
+	}
+}
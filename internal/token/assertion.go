@@ -0,0 +1,80 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// maxClientAssertionLifetime — предел срока жизни client_assertion. Assertion подписывается
+// под один запрос; долгоживущий перехваченный assertion работал бы как статический секрет,
+// а его jti пришлось бы хранить до exp.
+const maxClientAssertionLifetime = 5 * time.Minute
+
+// PeekIssuer достаёт iss без проверки подписи, чтобы найти ключ клиента для private_key_jwt.
+func PeekIssuer(assertion string) (string, error) {
+	claims := jwt.MapClaims{}
+
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, claims); err != nil {
+		return "", err
+	}
+
+	iss, err := claims.GetIssuer()
+	if err != nil || iss == "" {
+		return "", errors.New("в assertion нет iss")
+	}
+
+	return iss, nil
+}
+
+// VerifyClientAssertion проверяет client_assertion по RFC 7523 и возвращает его jti и exp.
+func VerifyClientAssertion(key *Key, assertion, clientID string, audiences []string) (string, time.Time, error) {
+	claims, err := key.ParseJWT(
+		assertion,
+		jwt.WithIssuer(clientID),
+		jwt.WithSubject(clientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if !slices.ContainsFunc(aud, func(a string) bool { return slices.Contains(audiences, a) }) {
+		return "", time.Time{}, fmt.Errorf("неверный aud у assertion")
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return "", time.Time{}, errors.New("в assertion нет jti")
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if iat == nil {
+		return "", time.Time{}, errors.New("в assertion нет iat")
+	}
+
+	// iat в будущем тоже отсекается: тогда exp дальше maxClientAssertionLifetime от текущего момента
+	if exp.Sub(iat.Time) > maxClientAssertionLifetime || time.Until(exp.Time) > maxClientAssertionLifetime {
+		return "", time.Time{}, fmt.Errorf("срок жизни assertion больше %s", maxClientAssertionLifetime)
+	}
+
+	return jti, exp.Time, nil
+}
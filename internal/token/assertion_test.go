@@ -0,0 +1,117 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testAudience = "https://auth.example.com/tokens"
+
+func signAssertion(t *testing.T, key *Key, claims jwt.MapClaims) string {
+	t.Helper()
+
+	signed, err := jwt.NewWithClaims(key.Method, claims).SignedString(key.signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func assertionClaims(edit func(jwt.MapClaims)) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss": "billing",
+		"sub": "billing",
+		"aud": testAudience,
+		"jti": "assertion-1",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	if edit != nil {
+		edit(claims)
+	}
+
+	return claims
+}
+
+func TestVerifyClientAssertion(t *testing.T) {
+	clientKey := mustPrivateKey(t, "", "", "p256")
+	public, err := ParsePublicKeyPEM("", "", publicPEM(t, clientKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := mustPrivateKey(t, "", "", "p256")
+	hmacKey, err := NewHMACKey("", "HS256", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		assertion string
+		wantErr   bool
+	}{
+		{"valid", signAssertion(t, clientKey, assertionClaims(nil)), false},
+		{"audience list", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) {
+			c["aud"] = []string{"https://other.example.com", testAudience}
+		})), false},
+		{"wrong issuer", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) { c["iss"] = "other" })), true},
+		{"wrong subject", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) { c["sub"] = "other" })), true},
+		{"wrong audience", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) { c["aud"] = "https://other.example.com" })), true},
+		{"no audience", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) { delete(c, "aud") })), true},
+		{"no jti", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) { delete(c, "jti") })), true},
+		{"no exp", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) { delete(c, "exp") })), true},
+		{"expired", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })), true},
+		{"no iat", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) { delete(c, "iat") })), true},
+		{"long lifetime", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) {
+			c["iat"] = time.Now().Add(-time.Hour).Unix()
+			c["exp"] = time.Now().Add(time.Minute).Unix()
+		})), true},
+		{"exp far ahead", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) {
+			c["iat"] = time.Now().Add(time.Hour).Unix()
+			c["exp"] = time.Now().Add(time.Hour + time.Minute).Unix()
+		})), true},
+		{"max lifetime", signAssertion(t, clientKey, assertionClaims(func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(maxClientAssertionLifetime - time.Second).Unix()
+		})), false},
+		{"other key", signAssertion(t, otherKey, assertionClaims(nil)), true},
+		{"hmac", signAssertion(t, hmacKey, assertionClaims(nil)), true},
+		{"garbage", "not.a.jwt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jti, expiresAt, err := VerifyClientAssertion(public, tt.assertion, "billing", []string{"https://auth.example.com", testAudience})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyClientAssertion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (jti != "assertion-1" || expiresAt.IsZero()) {
+				t.Errorf("VerifyClientAssertion() = %q, %v", jti, expiresAt)
+			}
+		})
+	}
+}
+
+func TestPeekIssuer(t *testing.T) {
+	key := mustPrivateKey(t, "", "", "ed25519")
+
+	tests := []struct {
+		name      string
+		assertion string
+		want      string
+		wantErr   bool
+	}{
+		{"issuer", signAssertion(t, key, assertionClaims(nil)), "billing", false},
+		{"no issuer", signAssertion(t, key, assertionClaims(func(c jwt.MapClaims) { delete(c, "iss") })), "", true},
+		{"garbage", "garbage", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := PeekIssuer(tt.assertion)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s: PeekIssuer() = %q, %v, want %q, wantErr %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
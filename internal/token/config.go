@@ -0,0 +1,74 @@
+package token
+
+import (
+	"errors"
+	"hh/config"
+)
+
+// LoadKeyRing собирает keyring из конфига: текущий ключ из JWT_PRIVATE_KEY_PATH или JWT_SECRET
+// и ключи для проверки из JWT_PREVIOUS_SECRETS и JWT_PREVIOUS_KEY_PATHS.
+func LoadKeyRing(cfg *config.Config) (*KeyRing, error) {
+	var current *Key
+	var err error
+
+	if cfg.JWTPrivateKeyPath != "" {
+		current, err = LoadPrivateKeyFile(cfg.JWTKeyID, cfg.JWTSigningAlg, cfg.JWTPrivateKeyPath)
+	} else {
+		current, err = NewHMACKey(cfg.JWTKeyID, cfg.JWTSigningAlg, cfg.JWTSecret)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []RetiringKey
+
+	for _, secret := range cfg.JWTPreviousSecrets {
+		key, err := NewHMACKey("", "", secret.Value)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, RetiringKey{Key: key, RetireAt: secret.RetireAt})
+	}
+
+	for _, path := range cfg.JWTPreviousKeyPaths {
+		key, err := LoadVerificationKeyFile("", "", path.Value)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, RetiringKey{Key: key, RetireAt: path.RetireAt})
+	}
+
+	return NewKeyRing(current, previous...)
+}
+
+// LoadAuditSigningKey загружает ключ контрольных точек аудита из AUDIT_SIGNING_KEY_PATH.
+// Ключ не входит в keyring JWT и не ротируется вместе с ним. Без ключа контрольные точки
+// не пишутся и цепочку аудита можно незаметно переписать целиком, поэтому путь обязателен.
+func LoadAuditSigningKey(cfg *config.Config) (*Key, error) {
+	if cfg.AuditSigningKeyPath == "" {
+		return nil, errors.New("AUDIT_SIGNING_KEY_PATH не задан: без него контрольные точки аудита не подписываются")
+	}
+
+	return LoadPrivateKeyFile("", "", cfg.AuditSigningKeyPath)
+}
+
+// LoadAuditVerificationKeys загружает открытые ключи для проверки контрольных точек:
+// AUDIT_VERIFY_KEY_PATHS со всеми ключами, которыми когда-либо подписывали, а без него —
+// ключ из AUDIT_SIGNING_KEY_PATH.
+func LoadAuditVerificationKeys(cfg *config.Config) ([]*Key, error) {
+	paths := cfg.AuditVerifyKeyPaths
+	if len(paths) == 0 && cfg.AuditSigningKeyPath != "" {
+		paths = []string{cfg.AuditSigningKeyPath}
+	}
+
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		key, err := LoadVerificationKeyFile("", "", path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,121 @@
+package token
+
+import (
+	"hh/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestLoadKeyRing(t *testing.T) {
+	dir := t.TempDir()
+
+	currentPath := filepath.Join(dir, "current.pem")
+	if err := os.WriteFile(currentPath, generatePEM(t, "p256"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := mustPrivateKey(t, "", "", "rsa")
+	previousPath := filepath.Join(dir, "previous.pem")
+	if err := os.WriteFile(previousPath, publicPEM(t, previous), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	active := time.Now().Add(time.Hour)
+	retired := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		wantAlg  string
+		wantKeys int
+		wantErr  bool
+	}{
+		{"hmac secret", config.Config{JWTSecret: "secret"}, "HS512", 1, false},
+		{"hmac with previous secrets", config.Config{JWTSecret: "secret", JWTPreviousSecrets: []config.PreviousKey{{Value: "old", RetireAt: active}, {Value: "older", RetireAt: active}}}, "HS512", 3, false},
+		{"retired previous secret", config.Config{JWTSecret: "secret", JWTPreviousSecrets: []config.PreviousKey{{Value: "old", RetireAt: active}, {Value: "older", RetireAt: retired}}}, "HS512", 2, false},
+		{"private key file", config.Config{JWTPrivateKeyPath: currentPath, JWTPreviousKeyPaths: []config.PreviousKey{{Value: previousPath, RetireAt: active}}}, "ES256", 2, false},
+		{"missing key file", config.Config{JWTPrivateKeyPath: filepath.Join(dir, "missing.pem")}, "", 0, true},
+		{"missing previous key file", config.Config{JWTSecret: "secret", JWTPreviousKeyPaths: []config.PreviousKey{{Value: filepath.Join(dir, "missing.pem"), RetireAt: active}}}, "", 0, true},
+		{"no secret", config.Config{}, "", 0, true},
+		{"previous secret equals current", config.Config{JWTSecret: "secret", JWTPreviousSecrets: []config.PreviousKey{{Value: "secret", RetireAt: active}}}, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := LoadKeyRing(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadKeyRing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if alg := ring.Current().Method.Alg(); alg != tt.wantAlg {
+				t.Errorf("current alg = %s, want %s", alg, tt.wantAlg)
+			}
+			if keys := ring.VerificationKeys(); len(keys) != tt.wantKeys {
+				t.Errorf("VerificationKeys() = %d keys, want %d", len(keys), tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestLoadAuditKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	previous := mustPrivateKey(t, "", "", "ed25519")
+	currentPath := filepath.Join(dir, "audit.pem")
+	if err := os.WriteFile(currentPath, generatePEM(t, "ed25519"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	previousPath := filepath.Join(dir, "audit-2023.pub")
+	if err := os.WriteFile(previousPath, publicPEM(t, previous), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// без ключа контрольные точки не пишутся, поэтому сервис не должен стартовать
+	if _, err := LoadAuditSigningKey(&config.Config{}); err == nil {
+		t.Error("LoadAuditSigningKey() without path succeeded, want error")
+	}
+	if _, err := LoadAuditSigningKey(&config.Config{AuditSigningKeyPath: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("LoadAuditSigningKey() with missing file succeeded, want error")
+	}
+
+	key, err := LoadAuditSigningKey(&config.Config{AuditSigningKeyPath: currentPath})
+	if err != nil || key == nil {
+		t.Fatalf("LoadAuditSigningKey() = %v, %v", key, err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		wantKeys int
+		wantErr  bool
+	}{
+		{"nothing configured", config.Config{}, 0, false},
+		{"signing key only", config.Config{AuditSigningKeyPath: currentPath}, 1, false},
+		{"explicit verify keys", config.Config{AuditSigningKeyPath: currentPath, AuditVerifyKeyPaths: []string{currentPath, previousPath}}, 2, false},
+		{"missing verify key", config.Config{AuditVerifyKeyPaths: []string{filepath.Join(dir, "missing.pem")}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		keys, err := LoadAuditVerificationKeys(&tt.cfg)
+		if (err != nil) != tt.wantErr || len(keys) != tt.wantKeys {
+			t.Errorf("%s: LoadAuditVerificationKeys() = %d keys, %v, want %d keys, wantErr %v", tt.name, len(keys), err, tt.wantKeys, tt.wantErr)
+		}
+	}
+
+	// открытый ключ, выведенный из подписанного, проверяет его подпись
+	signed, err := key.SignJWT(jwt.MapClaims{"typ": "audit_checkpoint"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := LoadAuditVerificationKeys(&config.Config{AuditSigningKeyPath: currentPath})
+	if _, err := ParseJWTWithKeys(keys, signed, jwt.WithoutClaimsValidation()); err != nil {
+		t.Errorf("ParseJWTWithKeys() error = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package token
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type KeyRing struct {
+	mu       sync.RWMutex
+	current  *Key
+	previous []RetiringKey
+}
+
+// RetiringKey — предыдущий ключ, который проверяет подписи до RetireAt.
+type RetiringKey struct {
+	Key      *Key
+	RetireAt time.Time
+}
+
+func NewKeyRing(current *Key, previous ...RetiringKey) (*KeyRing, error) {
+	if current == nil || current.signingKey == nil {
+		return nil, errors.New("empty signing key")
+	}
+
+	ring := &KeyRing{current: current}
+
+	for _, prev := range previous {
+		if prev.Key.ID == current.ID {
+			return nil, errors.New("kid предыдущего ключа совпадает с текущим")
+		}
+		ring.previous = append(ring.previous, prev)
+	}
+
+	return ring, nil
+}
+
+func (r *KeyRing) Current() *Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.current
+}
+
+func (r *KeyRing) Lookup(kid string) (*Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if kid == "" || kid == r.current.ID {
+		return r.current, true
+	}
+
+	now := time.Now()
+	for _, prev := range r.previous {
+		if prev.Key.ID == kid && now.Before(prev.RetireAt) {
+			return prev.Key, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *KeyRing) VerificationKeys() []*Key {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneLocked(time.Now())
+
+	keys := []*Key{r.current}
+	for _, prev := range r.previous {
+		keys = append(keys, prev.Key)
+	}
+
+	return keys
+}
+
+func (r *KeyRing) pruneLocked(now time.Time) {
+	active := r.previous[:0]
+	for _, prev := range r.previous {
+		if now.Before(prev.RetireAt) {
+			active = append(active, prev)
+		}
+	}
+	r.previous = active
+}
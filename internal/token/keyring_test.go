@@ -0,0 +1,143 @@
+package token
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestNewKeyRingRejects(t *testing.T) {
+	current := mustPrivateKey(t, "current", "", "ed25519")
+	public, err := ParsePublicKeyPEM("current", "", publicPEM(t, mustPrivateKey(t, "", "", "ed25519")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		current  *Key
+		previous []RetiringKey
+	}{
+		{"no current key", nil, nil},
+		{"current key without private part", public, nil},
+		{"previous key with current kid", current, []RetiringKey{{Key: mustPrivateKey(t, "current", "", "ed25519"), RetireAt: time.Now().Add(time.Hour)}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewKeyRing(tt.current, tt.previous...); err == nil {
+			t.Errorf("%s: NewKeyRing() accepted invalid keys", tt.name)
+		}
+	}
+}
+
+func TestKeyRingLookup(t *testing.T) {
+	current := mustPrivateKey(t, "current", "", "ed25519")
+	previous := mustPrivateKey(t, "previous", "", "p256")
+	retired := mustPrivateKey(t, "retired", "", "p256")
+
+	ring, err := NewKeyRing(current,
+		RetiringKey{Key: previous, RetireAt: time.Now().Add(time.Hour)},
+		RetiringKey{Key: retired, RetireAt: time.Now().Add(-time.Second)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		kid    string
+		want   *Key
+		wantOK bool
+	}{
+		{"current", current, true},
+		{"previous", previous, true},
+		// токены без kid выпускались до keyring и подписаны текущим ключом
+		{"", current, true},
+		{"retired", nil, false},
+		{"unknown", nil, false},
+	}
+
+	for _, tt := range tests {
+		key, ok := ring.Lookup(tt.kid)
+		if key != tt.want || ok != tt.wantOK {
+			t.Errorf("Lookup(%q) = %v, %v, want %v, %v", tt.kid, key, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestKeyRingVerificationKeysPrunesRetired(t *testing.T) {
+	current := mustPrivateKey(t, "current", "", "ed25519")
+	previous := mustPrivateKey(t, "previous", "", "ed25519")
+	retired := mustPrivateKey(t, "retired", "", "ed25519")
+
+	ring, err := NewKeyRing(current,
+		RetiringKey{Key: previous, RetireAt: time.Now().Add(time.Hour)},
+		RetiringKey{Key: retired, RetireAt: time.Now().Add(-time.Second)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := ring.VerificationKeys()
+	if len(keys) != 2 || keys[0] != current || keys[1] != previous {
+		t.Errorf("VerificationKeys() = %v, want current and previous", keys)
+	}
+	if len(ring.previous) != 1 {
+		t.Errorf("retired key is still in the ring: %d previous keys", len(ring.previous))
+	}
+}
+
+// Токены, подписанные предыдущим ключом, проверяются, пока ключ не выведен, и его публичная
+// часть остаётся в JWKS.
+func TestManagerAcceptsPreviousKeyUntilRetired(t *testing.T) {
+	current := mustPrivateKey(t, "current", "", "ed25519")
+	previous := mustPrivateKey(t, "previous", "", "p256")
+
+	signed := signTestToken(t, newTestManager(t, previous), time.Minute)
+
+	verification, err := ParsePublicKeyPEM("previous", "", publicPEM(t, previous))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		retireAt  time.Time
+		wantValid bool
+		wantKeys  int
+	}{
+		{"active", time.Now().Add(time.Hour), true, 2},
+		{"retired", time.Now().Add(-time.Second), false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := NewKeyRing(current, RetiringKey{Key: verification, RetireAt: tt.retireAt})
+			if err != nil {
+				t.Fatal(err)
+			}
+			manager, err := NewManager(ring)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := manager.Parse(signed); (err == nil) != tt.wantValid {
+				t.Errorf("Parse() error = %v, want valid %v", err, tt.wantValid)
+			}
+			if keys := manager.JWKS().Keys; len(keys) != tt.wantKeys {
+				t.Errorf("JWKS() = %d keys, want %d", len(keys), tt.wantKeys)
+			}
+		})
+	}
+}
+
+func publicPEM(t *testing.T, key *Key) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key.verifyingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
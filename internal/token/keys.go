@@ -0,0 +1,325 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	signingKey   interface{}
+	verifyingKey interface{}
+}
+
+type JWK struct {
+	Kty string `json:"kty" example:"RSA"`
+	Use string `json:"use,omitempty" example:"sig"`
+	Alg string `json:"alg,omitempty" example:"RS256"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewHMACKey(id, alg, secret string) (*Key, error) {
+	if secret == "" {
+		return nil, errors.New("empty signing key")
+	}
+
+	if alg == "" {
+		alg = jwt.SigningMethodHS512.Alg()
+	}
+
+	method, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, fmt.Errorf("алгоритм %s не подходит для HMAC ключа", alg)
+	}
+
+	if id == "" {
+		sum := sha256.Sum256([]byte("hmac:" + secret))
+		id = base64.RawURLEncoding.EncodeToString(sum[:])[:16]
+	}
+
+	return &Key{ID: id, Method: method, signingKey: []byte(secret), verifyingKey: []byte(secret)}, nil
+}
+
+func LoadPrivateKeyFile(id, alg, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать ключ %s: %w", path, err)
+	}
+
+	return ParsePrivateKeyPEM(id, alg, data)
+}
+
+func ParsePrivateKeyPEM(id, alg string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("ключ не в формате PEM")
+	}
+
+	var private interface{}
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип PEM блока: %s", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать приватный ключ: %w", err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("неподдерживаемый тип приватного ключа")
+	}
+
+	method, err := methodForKey(signer.Public(), alg)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id, Method: method, signingKey: private, verifyingKey: signer.Public()}
+	if key.ID == "" {
+		key.ID, err = key.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+func LoadVerificationKeyFile(id, alg, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать ключ %s: %w", path, err)
+	}
+
+	return ParsePublicKeyPEM(id, alg, data)
+}
+
+func ParsePublicKeyPEM(id, alg string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("ключ не в формате PEM")
+	}
+
+	var public interface{}
+	var err error
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		private, err := ParsePrivateKeyPEM(id, alg, data)
+		if err != nil {
+			return nil, err
+		}
+		private.signingKey = nil
+		return private, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать публичный ключ: %w", err)
+	}
+
+	method, err := methodForKey(public, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id, Method: method, verifyingKey: public}
+	if key.ID == "" {
+		key.ID, err = key.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+func methodForKey(public crypto.PublicKey, alg string) (jwt.SigningMethod, error) {
+	var allowed []string
+
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		allowed = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			allowed = []string{"ES256"}
+		case elliptic.P384():
+			allowed = []string{"ES384"}
+		case elliptic.P521():
+			allowed = []string{"ES512"}
+		default:
+			return nil, errors.New("неподдерживаемая кривая ECDSA")
+		}
+	case ed25519.PublicKey:
+		allowed = []string{"EdDSA"}
+	default:
+		return nil, errors.New("неподдерживаемый тип публичного ключа")
+	}
+
+	if alg == "" {
+		alg = allowed[0]
+	}
+
+	for _, a := range allowed {
+		if a == alg {
+			return jwt.GetSigningMethod(alg), nil
+		}
+	}
+
+	return nil, fmt.Errorf("алгоритм %s не подходит для ключа", alg)
+}
+
+// ParseJWT проверяет JWT, подписанный этим ключом (например client_assertion клиента).
+func (k *Key) ParseJWT(tokenString string, opts ...jwt.ParserOption) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	opts = append(opts, jwt.WithValidMethods([]string{k.Method.Alg()}))
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return k.verifyingKey, nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// SignJWT подписывает claims этим ключом с kid в заголовке.
+func (k *Key) SignJWT(claims jwt.MapClaims) (string, error) {
+	if k.signingKey == nil {
+		return "", errors.New("ключ годится только для проверки подписи")
+	}
+
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.ID
+
+	return token.SignedString(k.signingKey)
+}
+
+// ParseJWTWithKeys проверяет JWT тем ключом из keys, kid которого указан в заголовке.
+func ParseJWTWithKeys(keys []*Key, tokenString string, opts ...jwt.ParserOption) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		for _, key := range keys {
+			if key.ID != kid {
+				continue
+			}
+			if t.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method")
+			}
+
+			return key.verifyingKey, nil
+		}
+
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (k *Key) JWK() (JWK, error) {
+	jwk := JWK{Use: "sig", Alg: k.Method.Alg(), Kid: k.ID}
+
+	switch pub := k.verifyingKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, errors.New("симметричный ключ нельзя публиковать в JWKS")
+	}
+
+	return jwk, nil
+}
+
+// Thumbprint считает RFC 7638 отпечаток публичного ключа, он же kid по умолчанию.
+func (k *Key) Thumbprint() (string, error) {
+	jwk, err := k.JWK()
+	if err != nil {
+		return "", err
+	}
+
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
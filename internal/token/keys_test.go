@@ -0,0 +1,369 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// generatePEM создаёт приватный ключ и возвращает его в PKCS#8 PEM, как его читает LoadPrivateKeyFile.
+func generatePEM(t *testing.T, kind string) []byte {
+	t.Helper()
+
+	var private crypto.Signer
+	var err error
+	switch kind {
+	case "rsa":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "p256":
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "p384":
+		private, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "p521":
+		private, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case "ed25519":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		t.Fatalf("unknown key kind %q", kind)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func mustPrivateKey(t *testing.T, id, alg, kind string) *Key {
+	t.Helper()
+
+	key, err := ParsePrivateKeyPEM(id, alg, generatePEM(t, kind))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	tests := []struct {
+		kind    string
+		alg     string
+		wantAlg string
+		wantErr bool
+	}{
+		{"rsa", "", "RS256", false},
+		{"rsa", "RS512", "RS512", false},
+		{"rsa", "PS256", "PS256", false},
+		{"rsa", "ES256", "", true},
+		{"rsa", "HS256", "", true},
+		{"p256", "", "ES256", false},
+		{"p256", "ES384", "", true},
+		{"p384", "", "ES384", false},
+		{"p521", "", "ES512", false},
+		{"ed25519", "", "EdDSA", false},
+		{"ed25519", "RS256", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind+"/"+tt.alg, func(t *testing.T) {
+			key, err := ParsePrivateKeyPEM("", tt.alg, generatePEM(t, tt.kind))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePrivateKeyPEM() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if key.Method.Alg() != tt.wantAlg {
+				t.Errorf("Method = %s, want %s", key.Method.Alg(), tt.wantAlg)
+			}
+
+			// без явного id kid — RFC 7638 отпечаток
+			thumbprint, err := key.Thumbprint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.ID != thumbprint {
+				t.Errorf("ID = %q, want thumbprint %q", key.ID, thumbprint)
+			}
+		})
+	}
+}
+
+func TestParsePrivateKeyPEMRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not pem", []byte("secret")},
+		{"unknown block", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})},
+		{"broken der", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}})},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParsePrivateKeyPEM("", "", tt.data); err == nil {
+			t.Errorf("%s: ParsePrivateKeyPEM() accepted invalid key", tt.name)
+		}
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	hmacKey, err := NewHMACKey("", "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := map[string]*Key{
+		"HS512": hmacKey,
+		"RS256": mustPrivateKey(t, "rsa", "RS256", "rsa"),
+		"PS384": mustPrivateKey(t, "pss", "PS384", "rsa"),
+		"ES256": mustPrivateKey(t, "ec", "", "p256"),
+		"ES512": mustPrivateKey(t, "ec521", "", "p521"),
+		"EdDSA": mustPrivateKey(t, "ed", "", "ed25519"),
+	}
+
+	for alg, key := range keys {
+		t.Run(alg, func(t *testing.T) {
+			manager := newTestManager(t, key)
+
+			signed := signTestToken(t, manager, time.Minute)
+			token, _, err := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Method.Alg() != alg || token.Header["kid"] != key.ID {
+				t.Errorf("header alg=%v kid=%v, want %s %s", token.Header["alg"], token.Header["kid"], alg, key.ID)
+			}
+
+			sub, err := manager.Parse(signed)
+			if err != nil || sub != "user" {
+				t.Fatalf("Parse() = %q, %v", sub, err)
+			}
+
+			if _, err := manager.Parse(signTestToken(t, manager, -time.Minute)); err == nil {
+				t.Error("Parse() accepted an expired token")
+			}
+		})
+	}
+}
+
+// Токен, подписанный не тем ключом или не тем алгоритмом, должен отклоняться, даже если kid совпадает.
+func TestKeyFuncRejectsForeignTokens(t *testing.T) {
+	key := mustPrivateKey(t, "main", "RS256", "rsa")
+	manager := newTestManager(t, key)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(key.verifyingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	sign := func(method jwt.SigningMethod, kid string, signingKey interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub":        "user",
+			"session_id": "session",
+			"exp":        time.Now().Add(time.Minute).Unix(),
+		})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return signed
+	}
+
+	other := mustPrivateKey(t, "main", "RS256", "rsa")
+	ecKey := mustPrivateKey(t, "main", "ES256", "p256")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// alg confusion: публичный RSA ключ как секрет HMAC
+		{"hs256 with public key pem", sign(jwt.SigningMethodHS256, key.ID, publicKeyPEM)},
+		{"hs256 with public key der", sign(jwt.SigningMethodHS256, key.ID, publicDER)},
+		{"alg none", sign(jwt.SigningMethodNone, key.ID, jwt.UnsafeAllowNoneSignatureType)},
+		{"other rsa key with same kid", sign(jwt.SigningMethodRS256, key.ID, other.signingKey)},
+		{"other algorithm with same kid", sign(jwt.SigningMethodES256, key.ID, ecKey.signingKey)},
+		{"ps256 with the right key", sign(jwt.SigningMethodPS256, key.ID, key.signingKey)},
+		{"unknown kid", sign(jwt.SigningMethodRS256, "unknown", key.signingKey)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := manager.ParseClaims(tt.token); err == nil {
+				t.Error("ParseClaims() accepted a forged token")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	hmacKey, err := NewHMACKey("hmac", "HS256", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hmacKey.JWK(); err == nil {
+		t.Error("JWK() published an HMAC key")
+	}
+	if set := newTestManager(t, hmacKey).JWKS(); len(set.Keys) != 0 {
+		t.Errorf("JWKS() = %+v, want no keys for HMAC", set.Keys)
+	}
+
+	tests := []struct {
+		kind    string
+		wantKty string
+		wantCrv string
+	}{
+		{"rsa", "RSA", ""},
+		{"p256", "EC", "P-256"},
+		{"p384", "EC", "P-384"},
+		{"p521", "EC", "P-521"},
+		{"ed25519", "OKP", "Ed25519"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			key := mustPrivateKey(t, "", "", tt.kind)
+
+			set := newTestManager(t, key).JWKS()
+			if len(set.Keys) != 1 {
+				t.Fatalf("JWKS() = %d keys, want 1", len(set.Keys))
+			}
+
+			jwk := set.Keys[0]
+			if jwk.Kty != tt.wantKty || jwk.Crv != tt.wantCrv || jwk.Use != "sig" || jwk.Alg != key.Method.Alg() || jwk.Kid != key.ID {
+				t.Errorf("JWK = %+v", jwk)
+			}
+			if tt.wantKty == "EC" {
+				size := (key.verifyingKey.(*ecdsa.PublicKey).Curve.Params().BitSize + 7) / 8
+				x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+				y, _ := base64.RawURLEncoding.DecodeString(jwk.Y)
+				if len(x) != size || len(y) != size {
+					t.Errorf("EC coordinates are %d and %d bytes, want %d", len(x), len(y), size)
+				}
+			}
+		})
+	}
+}
+
+// Пример из RFC 7638, раздел 3.1.
+func TestThumbprintRFC7638(t *testing.T) {
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := &Key{
+		ID:           "2011-04-29",
+		Method:       jwt.SigningMethodRS256,
+		verifyingKey: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537},
+	}
+
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Thumbprint() = %q", thumbprint)
+	}
+}
+
+func newTestManager(t *testing.T, key *Key) *Manager {
+	t.Helper()
+
+	ring, err := NewKeyRing(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewManager(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return manager
+}
+
+func signTestToken(t *testing.T, manager *Manager, ttl time.Duration) string {
+	t.Helper()
+
+	signed, err := manager.NewJWT(Claims{UserID: "user", SessionID: "session", TokenID: "token"}, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestSignJWTAndParseJWTWithKeys(t *testing.T) {
+	audit := mustPrivateKey(t, "audit-2024", "", "ed25519")
+	previous := mustPrivateKey(t, "audit-2023", "", "p256")
+	unknown := mustPrivateKey(t, "audit-2022", "", "ed25519")
+
+	keys := []*Key{audit, previous}
+	claims := jwt.MapClaims{"typ": "audit_checkpoint", "seq": 7, "hash": "abc"}
+
+	sign := func(key *Key) string {
+		signed, err := key.SignJWT(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	// чужой ключ подставляет kid доверенного
+	impostor := mustPrivateKey(t, "audit-2024", "", "ed25519")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"current key", sign(audit), false},
+		{"previous key", sign(previous), false},
+		{"unknown kid", sign(unknown), true},
+		{"impostor with same kid", sign(impostor), true},
+		{"garbage", "garbage", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseJWTWithKeys(keys, tt.token, jwt.WithoutClaimsValidation())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseJWTWithKeys() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got["hash"] != "abc" {
+			t.Errorf("%s: claims = %v", tt.name, got)
+		}
+	}
+
+	// открытый ключ подписывать не может
+	verifyOnly, err := ParsePublicKeyPEM("", "", publicPEM(t, audit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyOnly.SignJWT(claims); err == nil {
+		t.Error("SignJWT() signed with a public key")
+	}
+}
@@ -2,9 +2,13 @@ package token
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,66 +16,191 @@ import (
 )
 
 type Manager struct {
-	signingKey string
+	keys *KeyRing
 }
 
-func NewManager(signingKey string) (*Manager, error) {
-	if signingKey == "" {
+func NewManager(keys *KeyRing) (*Manager, error) {
+	if keys == nil {
 		return nil, errors.New("empty signing key")
 	}
 
-	return &Manager{signingKey: signingKey}, nil
+	return &Manager{keys: keys}, nil
 }
 
-func (m *Manager) NewJWT(userId string, sessionID string, ttl time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
-		"sub":        userId,
-		"session_id": sessionID,
-		"exp":        time.Now().Add(ttl).Unix(),
-	})
+type Claims struct {
+	UserID    string
+	SessionID string
+	TokenID   string
+	ClientID  string
+	Scope     string
+	AMR       []string
+	// AuthTime — когда пользователь входил в сессию, нулевое значение — claim не ставится
+	AuthTime time.Time
+}
+
+func (m *Manager) NewJWT(claims Claims, ttl time.Duration) (string, error) {
+	key := m.keys.Current()
+	now := time.Now()
+
+	mapClaims := jwt.MapClaims{
+		"sub":        claims.UserID,
+		"session_id": claims.SessionID,
+		"jti":        claims.TokenID,
+		"iat":        now.Unix(),
+		"exp":        now.Add(ttl).Unix(),
+	}
+	if claims.ClientID != "" {
+		mapClaims["client_id"] = claims.ClientID
+	}
+	if claims.Scope != "" {
+		mapClaims["scope"] = claims.Scope
+	}
+	if len(claims.AMR) > 0 {
+		mapClaims["amr"] = claims.AMR
+	}
+	if !claims.AuthTime.IsZero() {
+		mapClaims["auth_time"] = claims.AuthTime.Unix()
+	}
 
-	return token.SignedString([]byte(m.signingKey))
+	token := jwt.NewWithClaims(key.Method, mapClaims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.signingKey)
 }
 
-func (m *Manager) Parse(accessToken string) (string, error) {
-	token, err := jwt.Parse(accessToken, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method")
-		}
-		return []byte(m.signingKey), nil
-	})
+// Sign подписывает произвольные claims текущим ключом, например контрольные точки аудита.
+func (m *Manager) Sign(claims jwt.MapClaims) (string, error) {
+	key := m.keys.Current()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.signingKey)
+}
+
+// ParseSigned проверяет только подпись JWT из Sign: у таких JWT нет срока жизни.
+func (m *Manager) ParseSigned(tokenString string) (jwt.MapClaims, error) {
+	return m.parseClaims(tokenString, jwt.WithoutClaimsValidation())
+}
+
+func (m *Manager) ParseClaims(accessToken string) (jwt.MapClaims, error) {
+	return m.parseClaims(accessToken)
+}
+
+// ParseExpiredClaims проверяет подпись, но не срок жизни: при refresh access токен обычно уже истёк.
+func (m *Manager) ParseExpiredClaims(accessToken string) (jwt.MapClaims, error) {
+	return m.parseClaims(accessToken, jwt.WithoutClaimsValidation())
+}
+
+func (m *Manager) parseClaims(accessToken string, opts ...jwt.ParserOption) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
 
+	_, err := jwt.ParseWithClaims(accessToken, claims, m.keyFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (m *Manager) Parse(accessToken string) (string, error) {
+	claims, err := m.ParseClaims(accessToken)
 	if err != nil {
 		return "", err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+	sub, ok := claims["sub"].(string)
 	if !ok {
 		return "", fmt.Errorf("error get user claims from token")
 	}
 
-	return claims["sub"].(string), nil
+	return sub, nil
 }
 
-func (m *Manager) NewRefreshToken() (string, string, error) {
-	b := make([]byte, 32)
+func (m *Manager) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
 
-	_, err := rand.Read(b)
-	if err != nil {
-		return "", "", err
+	key, ok := m.keys.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
 	}
 
-	token := base64.StdEncoding.EncodeToString(b)
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method")
+	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
-	if err != nil {
-		return "", "", err
+	return key.verifyingKey, nil
+}
+
+func (m *Manager) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+
+	for _, key := range m.keys.VerificationKeys() {
+		if jwk, err := key.JWK(); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
 	}
 
-	return token, string(hashed), nil
+	return set
+}
+
+// NewRefreshToken возвращает токен вида <selector>.<verifier>, selector для поиска строки в БД
+// и SHA-256 хэш verifier для хранения.
+func (m *Manager) NewRefreshToken() (string, string, string, error) {
+	return NewSplitToken()
+}
+
+// NewSplitToken создаёт одноразовый токен в формате refresh token: <selector>.<verifier>.
+// Возвращает токен, selector и хэш verifier; в БД хранятся только последние два.
+func NewSplitToken() (string, string, string, error) {
+	selector := make([]byte, 16)
+	if _, err := rand.Read(selector); err != nil {
+		return "", "", "", err
+	}
+
+	verifier := make([]byte, 32)
+	if _, err := rand.Read(verifier); err != nil {
+		return "", "", "", err
+	}
+
+	encodedSelector := base64.RawURLEncoding.EncodeToString(selector)
+	encodedVerifier := base64.RawURLEncoding.EncodeToString(verifier)
+
+	return encodedSelector + "." + encodedVerifier, encodedSelector, hashVerifier(encodedVerifier), nil
+}
+
+func SplitRefreshToken(refreshToken string) (string, string, bool) {
+	selector, verifier, ok := strings.Cut(refreshToken, ".")
+	if !ok || selector == "" || verifier == "" {
+		return "", "", false
+	}
+
+	return selector, verifier, true
 }
 
 func (m *Manager) VerifyRefreshToken(storedHash, providedToken string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(providedToken))
-	return err
+	if strings.HasPrefix(storedHash, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(providedToken))
+	}
+
+	return VerifySplitToken(storedHash, providedToken)
+}
+
+// VerifySplitToken сравнивает verifier токена из NewSplitToken с хэшем за постоянное время.
+func VerifySplitToken(storedHash, providedToken string) error {
+	_, verifier, ok := SplitRefreshToken(providedToken)
+	if !ok {
+		return errors.New("неверный формат токена")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashVerifier(verifier)), []byte(storedHash)) != 1 {
+		return errors.New("токен не совпадает")
+	}
+
+	return nil
+}
+
+func hashVerifier(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return hex.EncodeToString(sum[:])
 }
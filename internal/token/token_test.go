@@ -0,0 +1,250 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Истёкший access token нужен /refresh, чтобы найти сессию, но подпись у него всё равно проверяется.
+func TestParseExpiredClaims(t *testing.T) {
+	manager := newTestManager(t, mustPrivateKey(t, "current", "", "ed25519"))
+	foreign := newTestManager(t, mustPrivateKey(t, "current", "", "ed25519"))
+
+	tests := []struct {
+		name        string
+		token       string
+		wantClaims  bool
+		wantExpired bool
+	}{
+		{"valid", signTestToken(t, manager, time.Minute), true, true},
+		{"expired", signTestToken(t, manager, -time.Minute), false, true},
+		{"foreign key", signTestToken(t, foreign, -time.Minute), false, false},
+		{"garbage", "not.a.jwt", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := manager.ParseClaims(tt.token); (err == nil) != tt.wantClaims {
+				t.Errorf("ParseClaims() error = %v, want ok %v", err, tt.wantClaims)
+			}
+
+			claims, err := manager.ParseExpiredClaims(tt.token)
+			if (err == nil) != tt.wantExpired {
+				t.Fatalf("ParseExpiredClaims() error = %v, want ok %v", err, tt.wantExpired)
+			}
+			if err == nil && (claims["sub"] != "user" || claims["session_id"] != "session") {
+				t.Errorf("ParseExpiredClaims() = %v", claims)
+			}
+		})
+	}
+}
+
+// По jti /refresh находит строку токена в семье, поэтому он обязан попадать в access token.
+func TestNewJWTClaims(t *testing.T) {
+	manager := newTestManager(t, mustPrivateKey(t, "current", "", "ed25519"))
+
+	tests := []struct {
+		name   string
+		claims Claims
+		want   map[string]interface{}
+	}{
+		{
+			"user session",
+			Claims{UserID: "user", SessionID: "session", TokenID: "token"},
+			map[string]interface{}{"sub": "user", "session_id": "session", "jti": "token", "client_id": nil, "scope": nil},
+		},
+		{
+			"client token",
+			Claims{UserID: "user", SessionID: "session", TokenID: "token", ClientID: "billing", Scope: "profile"},
+			map[string]interface{}{"sub": "user", "session_id": "session", "jti": "token", "client_id": "billing", "scope": "profile"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := manager.NewJWT(tt.claims, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims, err := manager.ParseClaims(signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, value := range tt.want {
+				if claims[name] != value {
+					t.Errorf("claim %s = %v, want %v", name, claims[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRefreshToken(t *testing.T) {
+	manager := &Manager{}
+
+	refreshToken, selector, hash, err := manager.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotSelector, verifier, ok := SplitRefreshToken(refreshToken)
+	if !ok || gotSelector != selector {
+		t.Fatalf("SplitRefreshToken(%q) = %q, %v, want selector %q", refreshToken, gotSelector, ok, selector)
+	}
+	if strings.Contains(hash, verifier) {
+		t.Error("hash contains the verifier itself")
+	}
+	if err := manager.VerifyRefreshToken(hash, refreshToken); err != nil {
+		t.Errorf("VerifyRefreshToken() error = %v", err)
+	}
+
+	other, otherSelector, _, err := manager.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == refreshToken || otherSelector == selector {
+		t.Error("NewRefreshToken() returned the same token twice")
+	}
+}
+
+func TestSplitRefreshToken(t *testing.T) {
+	tests := []struct {
+		token        string
+		wantSelector string
+		wantVerifier string
+		wantOK       bool
+	}{
+		{"abc.def", "abc", "def", true},
+		{"abc.def.ghi", "abc", "def.ghi", true},
+		{"abc", "", "", false},
+		{".def", "", "", false},
+		{"abc.", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		selector, verifier, ok := SplitRefreshToken(tt.token)
+		if selector != tt.wantSelector || verifier != tt.wantVerifier || ok != tt.wantOK {
+			t.Errorf("SplitRefreshToken(%q) = %q, %q, %v, want %q, %q, %v", tt.token, selector, verifier, ok, tt.wantSelector, tt.wantVerifier, tt.wantOK)
+		}
+	}
+}
+
+// Verifier сверяется с хэшем: подобранный selector без verifier не подходит.
+func TestVerifyRefreshToken(t *testing.T) {
+	manager := &Manager{}
+
+	refreshToken, selector, hash, err := manager.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, _, err := manager.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherVerifier, _ := SplitRefreshToken(other)
+
+	legacy := "legacy-refresh-token"
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte(legacy), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		hash    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", hash, refreshToken, false},
+		{"foreign verifier", hash, selector + "." + otherVerifier, true},
+		{"other token", hash, other, true},
+		{"selector only", hash, selector, true},
+		{"empty", hash, "", true},
+		{"legacy bcrypt", string(legacyHash), legacy, false},
+		{"legacy bcrypt mismatch", string(legacyHash), "another-token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.VerifyRefreshToken(tt.hash, tt.token); (err != nil) != tt.wantErr {
+				t.Errorf("VerifyRefreshToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Подпись контрольной точки проверяется без срока жизни, но только ключами keyring.
+func TestSignAndParseSigned(t *testing.T) {
+	manager := newTestManager(t, mustPrivateKey(t, "main", "", "p256"))
+
+	signed, err := manager.Sign(jwt.MapClaims{"typ": "audit_checkpoint", "seq": 42, "hash": "abc", "iat": time.Now().Add(-24 * time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := manager.ParseSigned(signed)
+	if err != nil {
+		t.Fatalf("ParseSigned() error = %v", err)
+	}
+	if seq, _ := claims["seq"].(float64); seq != 42 || claims["hash"] != "abc" {
+		t.Errorf("claims = %v", claims)
+	}
+
+	other := newTestManager(t, mustPrivateKey(t, "main", "", "p256"))
+	if _, err := other.ParseSigned(signed); err == nil {
+		t.Error("ParseSigned() accepted a signature by a foreign key")
+	}
+
+	if _, err := manager.ParseSigned(signed[:len(signed)-4] + "AAAA"); err == nil {
+		t.Error("ParseSigned() accepted a tampered signature")
+	}
+}
+
+func TestNewSplitToken(t *testing.T) {
+	splitToken, selector, hash, err := NewSplitToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotSelector, verifier, ok := SplitRefreshToken(splitToken)
+	if !ok || gotSelector != selector {
+		t.Fatalf("SplitRefreshToken(%q) = %q, %v, want selector %q", splitToken, gotSelector, ok, selector)
+	}
+	if strings.Contains(hash, verifier) {
+		t.Error("hash contains the verifier itself")
+	}
+
+	other, otherSelector, _, err := NewSplitToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == splitToken || otherSelector == selector {
+		t.Error("NewSplitToken() returned the same token twice")
+	}
+	_, otherVerifier, _ := SplitRefreshToken(other)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", splitToken, false},
+		{"foreign verifier", selector + "." + otherVerifier, true},
+		{"other token", other, true},
+		{"selector only", selector, true},
+		{"empty", "", true},
+	}
+
+	for _, tt := range tests {
+		if err := VerifySplitToken(hash, tt.token); (err != nil) != tt.wantErr {
+			t.Errorf("%s: VerifySplitToken() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Секрет и моменты из тестовых векторов RFC 6238 (SHA-1). В RFC коды из 8 цифр,
+// здесь их последние 6.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := Code(rfcSecret, Step(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("Code(t=%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", Code(rfcSecret, current), 0, current, true},
+		{"previous step within skew", Code(rfcSecret, current-1), 1, current - 1, true},
+		{"next step within skew", Code(rfcSecret, current+1), 1, current + 1, true},
+		{"previous step without skew", Code(rfcSecret, current-1), 0, 0, false},
+		{"outside skew", Code(rfcSecret, current-2), 1, 0, false},
+		{"wrong code", "000000", 1, 0, false},
+		{"too short", Code(rfcSecret, current)[:5], 1, 0, false},
+		{"too long", Code(rfcSecret, current) + "0", 1, 0, false},
+		{"empty", "", 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Auth Service", "alice@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Auth Service:alice@example.com" {
+		t.Errorf("URI() = %s, want otpauth://totp/<issuer>:<account>", uri)
+	}
+
+	query := uri.Query()
+	want := map[string]string{
+		"secret":    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"issuer":    "Auth Service",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("URI() %s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != secretLength || string(first) == string(second) {
+		t.Errorf("GenerateSecret() = %x, %x, want two different %d-byte secrets", first, second, secretLength)
+	}
+	if strings.Contains(EncodeSecret(first), "=") {
+		t.Errorf("EncodeSecret() = %q, want no padding", EncodeSecret(first))
+	}
+}
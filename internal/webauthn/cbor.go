@@ -0,0 +1,199 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Декодер покрывает подмножество CBOR (RFC 8949), которое встречается в attestationObject
+// и COSE_Key: целые, байтовые и текстовые строки, массивы, map-ы, true/false/null.
+// Неопределённая длина и числа с плавающей точкой отвергаются.
+
+const cborMaxDepth = 16
+
+var errCBORTruncated = errors.New("cbor: неожиданный конец данных")
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decodeCBOR декодирует первый элемент data и возвращает остаток. Целые декодируются
+// в int64, map-ы — в map[any]any с ключами int64 или string.
+func decodeCBOR(data []byte) (any, []byte, error) {
+	d := &cborDecoder{data: data}
+
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return value, data[d.pos:], nil
+}
+
+// decodeCBORMap декодирует data целиком и требует, чтобы это был map.
+func decodeCBORMap(data []byte) (map[any]any, error) {
+	value, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("cbor: лишние данные после элемента")
+	}
+
+	m, ok := value.(map[any]any)
+	if !ok {
+		return nil, errors.New("cbor: ожидался map")
+	}
+
+	return m, nil
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > cborMaxDepth {
+		return nil, errors.New("cbor: слишком глубокая вложенность")
+	}
+
+	if d.pos >= len(d.data) {
+		return nil, errCBORTruncated
+	}
+
+	initial := d.data[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("cbor: неподдерживаемое простое значение %d", info)
+		}
+	}
+
+	arg, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			return nil, errors.New("cbor: целое вне диапазона int64")
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > 1<<63-1 {
+			return nil, errors.New("cbor: целое вне диапазона int64")
+		}
+		return -1 - int64(arg), nil
+	case 2:
+		return d.bytes(arg)
+	case 3:
+		b, err := d.bytes(arg)
+		return string(b), err
+	case 4:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errCBORTruncated
+		}
+
+		items := make([]any, 0, arg)
+		for range arg {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+
+		return items, nil
+	case 5:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, errCBORTruncated
+		}
+
+		m := make(map[any]any, arg)
+		for range arg {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, errors.New("cbor: ключ map должен быть целым или строкой")
+			}
+			if _, ok := m[key]; ok {
+				return nil, fmt.Errorf("cbor: повторяющийся ключ %v", key)
+			}
+
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+
+		return m, nil
+	case 6:
+		// теги не несут смысла для WebAuthn, берётся помеченное значение
+		return d.decode(depth + 1)
+	}
+
+	return nil, fmt.Errorf("cbor: неизвестный major type %d", major)
+}
+
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+
+	var size int
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default:
+		return 0, errors.New("cbor: неопределённая длина не поддерживается")
+	}
+
+	if len(d.data)-d.pos < size {
+		return 0, errCBORTruncated
+	}
+
+	b := d.data[d.pos : d.pos+size]
+	d.pos += size
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *cborDecoder) bytes(length uint64) ([]byte, error) {
+	if length > uint64(len(d.data)-d.pos) {
+		return nil, errCBORTruncated
+	}
+
+	b := d.data[d.pos : d.pos+int(length)]
+	d.pos += int(length)
+
+	return b, nil
+}
@@ -0,0 +1,141 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Алгоритмы COSE (RFC 9053), которые принимает сервер, в порядке предпочтения.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// параметры COSE_Key
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1
+	coseX         = -2
+	coseY         = -3
+	coseRSAN      = -1
+	coseRSAE      = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+
+	minRSABits = 2048
+)
+
+var ErrBadSignature = errors.New("подпись не прошла проверку")
+
+// PublicKey — открытый ключ из COSE_Key с алгоритмом, которым им подписывают.
+type PublicKey struct {
+	Algorithm int64
+	key       crypto.PublicKey
+}
+
+// ParsePublicKey разбирает COSE_Key из attestedCredentialData. Принимаются только
+// ES256 на P-256, EdDSA на Ed25519 и RS256 с ключом от 2048 бит.
+func ParsePublicKey(data []byte) (*PublicKey, error) {
+	m, err := decodeCBORMap(data)
+	if err != nil {
+		return nil, fmt.Errorf("COSE_Key: %w", err)
+	}
+
+	kty, _ := m[int64(coseKeyType)].(int64)
+	alg, ok := m[int64(coseAlgorithm)].(int64)
+	if !ok {
+		return nil, errors.New("COSE_Key: не указан алгоритм")
+	}
+
+	switch {
+	case alg == AlgES256 && kty == coseKeyTypeEC2:
+		return parseEC2Key(m)
+	case alg == AlgEdDSA && kty == coseKeyTypeOKP:
+		return parseOKPKey(m)
+	case alg == AlgRS256 && kty == coseKeyTypeRSA:
+		return parseRSAKey(m)
+	}
+
+	return nil, fmt.Errorf("COSE_Key: неподдерживаемый алгоритм %d с типом ключа %d", alg, kty)
+}
+
+func parseEC2Key(m map[any]any) (*PublicKey, error) {
+	crv, _ := m[int64(coseCurve)].(int64)
+	x, _ := m[int64(coseX)].([]byte)
+	y, _ := m[int64(coseY)].([]byte)
+	if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+		return nil, errors.New("COSE_Key: ES256 требует точку P-256")
+	}
+
+	curve := elliptic.P256()
+	pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("COSE_Key: точка не лежит на кривой P-256")
+	}
+
+	return &PublicKey{Algorithm: AlgES256, key: pub}, nil
+}
+
+func parseOKPKey(m map[any]any) (*PublicKey, error) {
+	crv, _ := m[int64(coseCurve)].(int64)
+	x, _ := m[int64(coseX)].([]byte)
+	if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("COSE_Key: EdDSA требует ключ Ed25519")
+	}
+
+	return &PublicKey{Algorithm: AlgEdDSA, key: ed25519.PublicKey(x)}, nil
+}
+
+func parseRSAKey(m map[any]any) (*PublicKey, error) {
+	n, _ := m[int64(coseRSAN)].([]byte)
+	e, _ := m[int64(coseRSAE)].([]byte)
+	if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("COSE_Key: неверный ключ RSA")
+	}
+
+	exponent := int(new(big.Int).SetBytes(e).Int64())
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}
+	if pub.N.BitLen() < minRSABits || exponent < 3 || exponent%2 == 0 {
+		return nil, errors.New("COSE_Key: слабый ключ RSA")
+	}
+
+	return &PublicKey{Algorithm: AlgRS256, key: pub}, nil
+}
+
+// Verify проверяет подпись data. ES256 ожидает подпись в DER, как её отдают аутентификаторы.
+func (k *PublicKey) Verify(data, signature []byte) error {
+	var ok bool
+
+	switch pub := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		ok = ecdsa.VerifyASN1(pub, digest[:], signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !ok {
+		return ErrBadSignature
+	}
+
+	return nil
+}
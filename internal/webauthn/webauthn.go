@@ -0,0 +1,427 @@
+// Package webauthn реализует серверную часть WebAuthn Level 2 для passkey: параметры
+// церемоний регистрации и входа и проверку ответов аутентификатора. Аттестация не
+// запрашивается (attestation "none"), проверка пользователя (UV) обязательна, поэтому
+// passkey годится как единственный фактор входа.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrVerification      = errors.New("ответ аутентификатора не прошёл проверку")
+	ErrSignCountRollback = errors.New("счётчик подписей не вырос: возможно, ключ скопирован")
+)
+
+const (
+	challengeLength = 32
+
+	publicKeyCredentialType = "public-key"
+	clientDataTypeCreate    = "webauthn.create"
+	clientDataTypeGet       = "webauthn.get"
+)
+
+// флаги authenticatorData
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagBackupState    = 0x10
+	flagAttestedData   = 0x40
+	flagExtensionData  = 0x80
+)
+
+// URLEncodedBytes в JSON — base64url без паддинга, как в PublicKeyCredential.toJSON().
+type URLEncodedBytes []byte
+
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return fmt.Errorf("ожидался base64url: %w", err)
+	}
+	*b = decoded
+
+	return nil
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id" example:"auth.example.com"`
+	Name string `json:"name" example:"hh auth"`
+}
+
+type UserEntity struct {
+	ID          URLEncodedBytes `json:"id" swaggertype:"string"`
+	Name        string          `json:"name" example:"user@example.com"`
+	DisplayName string          `json:"displayName" example:"user@example.com"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type" example:"public-key"`
+	Alg  int64  `json:"alg" example:"-7"`
+}
+
+type CredentialDescriptor struct {
+	Type       string          `json:"type" example:"public-key"`
+	ID         URLEncodedBytes `json:"id" swaggertype:"string"`
+	Transports []string        `json:"transports,omitempty" example:"internal"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey" example:"required"`
+	RequireResidentKey bool   `json:"requireResidentKey" example:"true"`
+	UserVerification   string `json:"userVerification" example:"required"`
+}
+
+// CreationOptions — PublicKeyCredentialCreationOptions для navigator.credentials.create().
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              URLEncodedBytes        `json:"challenge" swaggertype:"string"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout" example:"300000"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation" example:"none"`
+}
+
+// RequestOptions — PublicKeyCredentialRequestOptions для navigator.credentials.get().
+// allowCredentials пуст: аутентификатор сам предлагает passkey для этого RP.
+type RequestOptions struct {
+	Challenge        URLEncodedBytes        `json:"challenge" swaggertype:"string"`
+	Timeout          int64                  `json:"timeout" example:"300000"`
+	RPID             string                 `json:"rpId" example:"auth.example.com"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification" example:"required"`
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    URLEncodedBytes `json:"clientDataJSON" binding:"required" swaggertype:"string"`
+	AttestationObject URLEncodedBytes `json:"attestationObject" binding:"required" swaggertype:"string"`
+	Transports        []string        `json:"transports" example:"internal"`
+}
+
+// AttestationCredential — результат navigator.credentials.create() в JSON.
+type AttestationCredential struct {
+	ID       string              `json:"id"`
+	RawID    URLEncodedBytes     `json:"rawId" binding:"required" swaggertype:"string"`
+	Type     string              `json:"type" binding:"required" example:"public-key"`
+	Response AttestationResponse `json:"response" binding:"required"`
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    URLEncodedBytes `json:"clientDataJSON" binding:"required" swaggertype:"string"`
+	AuthenticatorData URLEncodedBytes `json:"authenticatorData" binding:"required" swaggertype:"string"`
+	Signature         URLEncodedBytes `json:"signature" binding:"required" swaggertype:"string"`
+	UserHandle        URLEncodedBytes `json:"userHandle" swaggertype:"string"`
+}
+
+// AssertionCredential — результат navigator.credentials.get() в JSON.
+type AssertionCredential struct {
+	ID       string            `json:"id"`
+	RawID    URLEncodedBytes   `json:"rawId" binding:"required" swaggertype:"string"`
+	Type     string            `json:"type" binding:"required" example:"public-key"`
+	Response AssertionResponse `json:"response" binding:"required"`
+}
+
+// Credential — проверенный при регистрации ключ, который нужно сохранить.
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte
+	Algorithm      int64
+	SignCount      uint32
+	AAGUID         []byte
+	Transports     []string
+	BackupEligible bool
+	BackupState    bool
+}
+
+// Assertion — результат проверенного входа.
+type Assertion struct {
+	SignCount   uint32
+	BackupState bool
+}
+
+// RelyingParty — сервис, для которого создаются passkey. ID — домен (RP ID), Origins —
+// допустимые origin страниц, вызывающих WebAuthn API.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+	Timeout time.Duration
+}
+
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (rp *RelyingParty) CreationOptions(challenge []byte, user UserEntity, exclude []CredentialDescriptor) CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: publicKeyCredentialType, Alg: alg})
+	}
+
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return CreationOptions{
+		RP:                 RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+func (rp *RelyingParty) RequestOptions(challenge []byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: "required",
+	}
+}
+
+// VerifyRegistration проверяет ответ navigator.credentials.create() на challenge
+// (WebAuthn §7.1) и возвращает ключ для сохранения.
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, credential AttestationCredential) (*Credential, error) {
+	if credential.Type != publicKeyCredentialType {
+		return nil, fmt.Errorf("%w: тип %q", ErrVerification, credential.Type)
+	}
+
+	if err := rp.verifyClientData(credential.Response.ClientDataJSON, clientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	attestation, err := decodeCBORMap(credential.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: attestationObject: %v", ErrVerification, err)
+	}
+
+	// attestation "none": аутентификатор не доказывает свою модель, attStmt пуст
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[any]any)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if format != "none" || len(statement) != 0 {
+		return nil, fmt.Errorf("%w: поддерживается только attestation none, получено %q", ErrVerification, format)
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	if authData.flags&flagAttestedData == 0 {
+		return nil, fmt.Errorf("%w: нет данных ключа", ErrVerification)
+	}
+
+	if !bytes.Equal(authData.credentialID, credential.RawID) {
+		return nil, fmt.Errorf("%w: rawId не совпадает с ID ключа", ErrVerification)
+	}
+
+	publicKey, err := ParsePublicKey(authData.credentialPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+
+	return &Credential{
+		ID:             authData.credentialID,
+		PublicKey:      authData.credentialPublicKey,
+		Algorithm:      publicKey.Algorithm,
+		SignCount:      authData.signCount,
+		AAGUID:         authData.aaguid,
+		Transports:     credential.Response.Transports,
+		BackupEligible: authData.flags&flagBackupEligible != 0,
+		BackupState:    authData.flags&flagBackupState != 0,
+	}, nil
+}
+
+// VerifyAssertion проверяет ответ navigator.credentials.get() на challenge ключом
+// publicKey (COSE_Key) (WebAuthn §7.2). storedSignCount — счётчик с прошлого входа:
+// если аутентификатор ведёт счётчик, он обязан расти, иначе ключ мог быть скопирован.
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, credential AssertionCredential, publicKey []byte, storedSignCount uint32) (*Assertion, error) {
+	if credential.Type != publicKeyCredentialType {
+		return nil, fmt.Errorf("%w: тип %q", ErrVerification, credential.Type)
+	}
+
+	if err := rp.verifyClientData(credential.Response.ClientDataJSON, clientDataTypeGet, challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := parseAuthenticatorData(credential.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(credential.Response.ClientDataJSON)
+	signed := append(slices.Clip(credential.Response.AuthenticatorData), clientDataHash[:]...)
+	if err := key.Verify(signed, credential.Response.Signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+
+	if (authData.signCount != 0 || storedSignCount != 0) && authData.signCount <= storedSignCount {
+		return nil, ErrSignCountRollback
+	}
+
+	return &Assertion{
+		SignCount:   authData.signCount,
+		BackupState: authData.flags&flagBackupState != 0,
+	}, nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, expectedType string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: clientDataJSON: %v", ErrVerification, err)
+	}
+
+	if data.Type != expectedType {
+		return fmt.Errorf("%w: clientData.type %q", ErrVerification, data.Type)
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return fmt.Errorf("%w: challenge не совпадает", ErrVerification)
+	}
+
+	if !slices.Contains(rp.Origins, data.Origin) || data.CrossOrigin {
+		return fmt.Errorf("%w: недопустимый origin %q", ErrVerification, data.Origin)
+	}
+
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rpIDHash[:]) != 1 {
+		return fmt.Errorf("%w: ключ создан для другого RP ID", ErrVerification)
+	}
+
+	if authData.flags&flagUserPresent == 0 {
+		return fmt.Errorf("%w: пользователь не подтвердил присутствие", ErrVerification)
+	}
+
+	if authData.flags&flagUserVerified == 0 {
+		return fmt.Errorf("%w: аутентификатор не проверил пользователя", ErrVerification)
+	}
+
+	// BS без BE по спецификации невозможен
+	if authData.flags&flagBackupState != 0 && authData.flags&flagBackupEligible == 0 {
+		return fmt.Errorf("%w: неверные флаги резервной копии", ErrVerification)
+	}
+
+	return nil
+}
+
+type authenticatorData struct {
+	rpIDHash            []byte
+	flags               byte
+	signCount           uint32
+	aaguid              []byte
+	credentialID        []byte
+	credentialPublicKey []byte
+}
+
+// parseAuthenticatorData разбирает authenticatorData (WebAuthn §6.1): хэш RP ID, флаги,
+// счётчик и, если стоит флаг AT, данные нового ключа.
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("%w: authenticatorData короче 37 байт", ErrVerification)
+	}
+
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.flags&flagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: обрезаны данные ключа", ErrVerification)
+		}
+
+		authData.aaguid = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+
+		if idLength > 1023 || len(rest) < idLength {
+			return nil, fmt.Errorf("%w: неверная длина ID ключа", ErrVerification)
+		}
+		authData.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, afterKey, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: COSE_Key: %v", ErrVerification, err)
+		}
+		authData.credentialPublicKey = rest[:len(rest)-len(afterKey)]
+		rest = afterKey
+	}
+
+	if authData.flags&flagExtensionData != 0 {
+		if _, err := decodeCBORMap(rest); err != nil {
+			return nil, fmt.Errorf("%w: расширения: %v", ErrVerification, err)
+		}
+		rest = nil
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: лишние данные в authenticatorData", ErrVerification)
+	}
+
+	return authData, nil
+}
@@ -0,0 +1,526 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testOrigin = "https://auth.example.com"
+
+func testRelyingParty() *RelyingParty {
+	return &RelyingParty{ID: "auth.example.com", Name: "hh auth", Origins: []string{testOrigin}, Timeout: time.Minute}
+}
+
+// Минимальный CBOR-кодировщик: ровно то, что нужно для attestationObject и COSE_Key.
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	}
+
+	return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+}
+
+func cborInt(v int64) []byte {
+	if v >= 0 {
+		return cborHead(0, uint64(v))
+	}
+
+	return cborHead(1, uint64(-1-v))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+func cborString(s string) []byte {
+	return append(cborHead(3, uint64(len(s))), s...)
+}
+
+// cborMap кодирует map из уже закодированных пар ключ-значение.
+func cborMap(pairs ...[]byte) []byte {
+	out := cborHead(5, uint64(len(pairs)/2))
+	for _, item := range pairs {
+		out = append(out, item...)
+	}
+
+	return out
+}
+
+// softAuthenticator — программный аутентификатор с одним ключом: создаёт и подписывает
+// ответы так же, как это делает браузер с платформенным аутентификатором.
+type softAuthenticator struct {
+	signer       crypto.Signer
+	coseKey      []byte
+	credentialID []byte
+	signCount    uint32
+	// counting == false — аутентификатор не ведёт счётчик и всегда присылает 0
+	counting bool
+}
+
+func newSoftAuthenticator(t *testing.T, alg int64) *softAuthenticator {
+	t.Helper()
+
+	a := &softAuthenticator{credentialID: make([]byte, 16), counting: true}
+	if _, err := rand.Read(a.credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	switch alg {
+	case AlgES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y := make([]byte, 32), make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+
+		a.signer = key
+		a.coseKey = cborMap(
+			cborInt(coseKeyType), cborInt(coseKeyTypeEC2),
+			cborInt(coseAlgorithm), cborInt(AlgES256),
+			cborInt(coseCurve), cborInt(coseCurveP256),
+			cborInt(coseX), cborBytes(x),
+			cborInt(coseY), cborBytes(y),
+		)
+	case AlgEdDSA:
+		public, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a.signer = key
+		a.coseKey = cborMap(
+			cborInt(coseKeyType), cborInt(coseKeyTypeOKP),
+			cborInt(coseAlgorithm), cborInt(AlgEdDSA),
+			cborInt(coseCurve), cborInt(coseCurveEd25519),
+			cborInt(coseX), cborBytes(public),
+		)
+	case AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a.signer = key
+		a.coseKey = cborMap(
+			cborInt(coseKeyType), cborInt(coseKeyTypeRSA),
+			cborInt(coseAlgorithm), cborInt(AlgRS256),
+			cborInt(coseRSAN), cborBytes(key.N.Bytes()),
+			cborInt(coseRSAE), cborBytes(big32(key.E)),
+		)
+	default:
+		t.Fatalf("неизвестный алгоритм %d", alg)
+	}
+
+	return a
+}
+
+func big32(v int) []byte {
+	return bytes.TrimLeft(binary.BigEndian.AppendUint32(nil, uint32(v)), "\x00")
+}
+
+// ceremony — то, что браузер и аутентификатор вкладывают в ответ. Тесты портят одно поле.
+type ceremony struct {
+	clientDataType string
+	challenge      []byte
+	origin         string
+	rpID           string
+	flags          byte
+}
+
+func (a *softAuthenticator) authenticatorData(c ceremony, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(c.rpID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, c.flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey...)
+	}
+
+	return data
+}
+
+func clientDataJSON(c ceremony) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"type":      c.clientDataType,
+		"challenge": base64.RawURLEncoding.EncodeToString(c.challenge),
+		"origin":    c.origin,
+	})
+
+	return data
+}
+
+func (a *softAuthenticator) create(c ceremony) AttestationCredential {
+	attestationObject := cborMap(
+		cborString("fmt"), cborString("none"),
+		cborString("attStmt"), cborMap(),
+		cborString("authData"), cborBytes(a.authenticatorData(c, true)),
+	)
+
+	return AttestationCredential{
+		RawID: a.credentialID,
+		Type:  publicKeyCredentialType,
+		Response: AttestationResponse{
+			ClientDataJSON:    clientDataJSON(c),
+			AttestationObject: attestationObject,
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+func (a *softAuthenticator) get(t *testing.T, c ceremony) AssertionCredential {
+	t.Helper()
+
+	if a.counting {
+		a.signCount++
+	}
+
+	authData := a.authenticatorData(c, false)
+	clientData := clientDataJSON(c)
+	clientDataHash := sha256.Sum256(clientData)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	var signature []byte
+	var err error
+	switch key := a.signer.(type) {
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, signed)
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(signed)
+		signature, err = ecdsa.SignASN1(rand.Reader, key, digest[:])
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(signed)
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return AssertionCredential{
+		RawID: a.credentialID,
+		Type:  publicKeyCredentialType,
+		Response: AssertionResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: authData,
+			Signature:         signature,
+		},
+	}
+}
+
+func newChallenge(t *testing.T) []byte {
+	t.Helper()
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return challenge
+}
+
+func validCeremony(rp *RelyingParty, clientDataType string, challenge []byte, flags byte) ceremony {
+	return ceremony{clientDataType: clientDataType, challenge: challenge, origin: testOrigin, rpID: rp.ID, flags: flags}
+}
+
+const (
+	registrationFlags = flagUserPresent | flagUserVerified | flagBackupEligible | flagAttestedData
+	assertionFlags    = flagUserPresent | flagUserVerified | flagBackupEligible
+)
+
+func register(t *testing.T, rp *RelyingParty, a *softAuthenticator) *Credential {
+	t.Helper()
+
+	challenge := newChallenge(t)
+	credential, err := rp.VerifyRegistration(challenge, a.create(validCeremony(rp, clientDataTypeCreate, challenge, registrationFlags)))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+
+	return credential
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := testRelyingParty()
+
+	for _, alg := range SupportedAlgorithms {
+		t.Run(strconv.FormatInt(alg, 10), func(t *testing.T) {
+			a := newSoftAuthenticator(t, alg)
+
+			credential := register(t, rp, a)
+			if credential.Algorithm != alg {
+				t.Errorf("Algorithm = %d, want %d", credential.Algorithm, alg)
+			}
+			if !bytes.Equal(credential.ID, a.credentialID) {
+				t.Errorf("ID не совпадает с rawId")
+			}
+			if !credential.BackupEligible || credential.BackupState {
+				t.Errorf("BackupEligible = %v, BackupState = %v", credential.BackupEligible, credential.BackupState)
+			}
+			if _, err := ParsePublicKey(credential.PublicKey); err != nil {
+				t.Errorf("сохранённый ключ не разбирается: %v", err)
+			}
+
+			storedSignCount := credential.SignCount
+			for i := 1; i <= 2; i++ {
+				challenge := newChallenge(t)
+				assertion, err := rp.VerifyAssertion(challenge, a.get(t, validCeremony(rp, clientDataTypeGet, challenge, assertionFlags)), credential.PublicKey, storedSignCount)
+				if err != nil {
+					t.Fatalf("вход %d: %v", i, err)
+				}
+				if assertion.SignCount != uint32(i) {
+					t.Errorf("вход %d: SignCount = %d", i, assertion.SignCount)
+				}
+				storedSignCount = assertion.SignCount
+			}
+		})
+	}
+}
+
+// Аутентификаторы без счётчика (например, синхронизируемые passkey) всегда присылают 0.
+func TestAssertionWithoutSignCounter(t *testing.T) {
+	rp := testRelyingParty()
+	a := newSoftAuthenticator(t, AlgES256)
+	a.counting = false
+	credential := register(t, rp, a)
+
+	for i := 0; i < 2; i++ {
+		challenge := newChallenge(t)
+		if _, err := rp.VerifyAssertion(challenge, a.get(t, validCeremony(rp, clientDataTypeGet, challenge, assertionFlags)), credential.PublicKey, 0); err != nil {
+			t.Fatalf("вход %d: %v", i, err)
+		}
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	rp := testRelyingParty()
+	a := newSoftAuthenticator(t, AlgES256)
+
+	tests := []struct {
+		name       string
+		ceremony   func(c *ceremony)
+		credential func(credential *AttestationCredential)
+	}{
+		{name: "wrong origin", ceremony: func(c *ceremony) { c.origin = "https://evil.example.com" }},
+		{name: "wrong rp id", ceremony: func(c *ceremony) { c.rpID = "evil.example.com" }},
+		{name: "wrong challenge", ceremony: func(c *ceremony) { c.challenge = []byte("другой challenge") }},
+		{name: "assertion client data", ceremony: func(c *ceremony) { c.clientDataType = clientDataTypeGet }},
+		{name: "missing user verification", ceremony: func(c *ceremony) { c.flags &^= flagUserVerified }},
+		{name: "missing user presence", ceremony: func(c *ceremony) { c.flags &^= flagUserPresent }},
+		{name: "backup state without eligibility", ceremony: func(c *ceremony) { c.flags = c.flags&^flagBackupEligible | flagBackupState }},
+		{name: "rawId mismatch", credential: func(credential *AttestationCredential) { credential.RawID = []byte("другой ключ") }},
+		{name: "wrong credential type", credential: func(credential *AttestationCredential) { credential.Type = "password" }},
+		{name: "packed attestation", credential: func(credential *AttestationCredential) {
+			credential.Response.AttestationObject = bytes.Replace(credential.Response.AttestationObject, cborString("none"), cborString("pckd"), 1)
+		}},
+		{name: "malformed attestation object", credential: func(credential *AttestationCredential) {
+			credential.Response.AttestationObject = credential.Response.AttestationObject[:len(credential.Response.AttestationObject)-1]
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := newChallenge(t)
+			c := validCeremony(rp, clientDataTypeCreate, challenge, registrationFlags)
+			if tt.ceremony != nil {
+				tt.ceremony(&c)
+			}
+
+			credential := a.create(c)
+			if tt.credential != nil {
+				tt.credential(&credential)
+			}
+
+			if _, err := rp.VerifyRegistration(challenge, credential); !errors.Is(err, ErrVerification) {
+				t.Errorf("err = %v, want ErrVerification", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	rp := testRelyingParty()
+	a := newSoftAuthenticator(t, AlgEdDSA)
+	credential := register(t, rp, a)
+
+	tests := []struct {
+		name       string
+		ceremony   func(c *ceremony)
+		credential func(credential *AssertionCredential)
+		// storedSignCount — счётчик, сохранённый при прошлом входе
+		storedSignCount uint32
+		want            error
+	}{
+		{name: "wrong origin", ceremony: func(c *ceremony) { c.origin = "https://evil.example.com" }, want: ErrVerification},
+		{name: "wrong rp id", ceremony: func(c *ceremony) { c.rpID = "evil.example.com" }, want: ErrVerification},
+		{name: "wrong challenge", ceremony: func(c *ceremony) { c.challenge = []byte("другой challenge") }, want: ErrVerification},
+		{name: "registration client data", ceremony: func(c *ceremony) { c.clientDataType = clientDataTypeCreate }, want: ErrVerification},
+		{name: "missing user verification", ceremony: func(c *ceremony) { c.flags &^= flagUserVerified }, want: ErrVerification},
+		{name: "missing user presence", ceremony: func(c *ceremony) { c.flags &^= flagUserPresent }, want: ErrVerification},
+		{name: "tampered signature", credential: func(credential *AssertionCredential) {
+			credential.Response.Signature[len(credential.Response.Signature)-1] ^= 1
+		}, want: ErrVerification},
+		{name: "tampered client data", credential: func(credential *AssertionCredential) {
+			credential.Response.ClientDataJSON = append(credential.Response.ClientDataJSON[:len(credential.Response.ClientDataJSON)-1], ' ', '}')
+		}, want: ErrVerification},
+		{name: "truncated authenticator data", credential: func(credential *AssertionCredential) {
+			credential.Response.AuthenticatorData = credential.Response.AuthenticatorData[:36]
+		}, want: ErrVerification},
+		{name: "sign count rollback", storedSignCount: 100, want: ErrSignCountRollback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := newChallenge(t)
+			c := validCeremony(rp, clientDataTypeGet, challenge, assertionFlags)
+			if tt.ceremony != nil {
+				tt.ceremony(&c)
+			}
+
+			assertion := a.get(t, c)
+			if tt.credential != nil {
+				tt.credential(&assertion)
+			}
+
+			if _, err := rp.VerifyAssertion(challenge, assertion, credential.PublicKey, tt.storedSignCount); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Повтор перехваченного ответа приходит с тем же счётчиком, что уже сохранён.
+func TestVerifyAssertionRejectsReplay(t *testing.T) {
+	rp := testRelyingParty()
+	a := newSoftAuthenticator(t, AlgES256)
+	credential := register(t, rp, a)
+
+	challenge := newChallenge(t)
+	response := a.get(t, validCeremony(rp, clientDataTypeGet, challenge, assertionFlags))
+
+	assertion, err := rp.VerifyAssertion(challenge, response, credential.PublicKey, credential.SignCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rp.VerifyAssertion(challenge, response, credential.PublicKey, assertion.SignCount); !errors.Is(err, ErrSignCountRollback) {
+		t.Errorf("err = %v, want ErrSignCountRollback", err)
+	}
+}
+
+func TestParsePublicKeyRejects(t *testing.T) {
+	weakRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"weak rsa", cborMap(
+			cborInt(coseKeyType), cborInt(coseKeyTypeRSA),
+			cborInt(coseAlgorithm), cborInt(AlgRS256),
+			cborInt(coseRSAN), cborBytes(weakRSA.N.Bytes()),
+			cborInt(coseRSAE), cborBytes(big32(weakRSA.E)),
+		)},
+		{"point not on curve", cborMap(
+			cborInt(coseKeyType), cborInt(coseKeyTypeEC2),
+			cborInt(coseAlgorithm), cborInt(AlgES256),
+			cborInt(coseCurve), cborInt(coseCurveP256),
+			cborInt(coseX), cborBytes(make([]byte, 32)),
+			cborInt(coseY), cborBytes(make([]byte, 32)),
+		)},
+		{"algorithm does not match key type", cborMap(
+			cborInt(coseKeyType), cborInt(coseKeyTypeOKP),
+			cborInt(coseAlgorithm), cborInt(AlgES256),
+		)},
+		{"unsupported algorithm", cborMap(
+			cborInt(coseKeyType), cborInt(coseKeyTypeEC2),
+			cborInt(coseAlgorithm), cborInt(-35),
+		)},
+		{"missing algorithm", cborMap(cborInt(coseKeyType), cborInt(coseKeyTypeEC2))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePublicKey(tt.key); err == nil {
+				t.Error("ключ принят")
+			}
+		})
+	}
+}
+
+func TestDecodeCBORRejectsMalformed(t *testing.T) {
+	deep := bytes.Repeat([]byte{0x81}, cborMaxDepth+2)
+	deep = append(deep, 0x00)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"indefinite length map", []byte{0xbf, 0xff}},
+		{"indefinite length bytes", []byte{0x5f, 0xff}},
+		{"byte string longer than data", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"array count larger than data", []byte{0x9a, 0xff, 0xff, 0xff, 0xff}},
+		{"map without value", []byte{0xa1, 0x01}},
+		{"duplicate map key", []byte{0xa2, 0x01, 0x01, 0x01, 0x02}},
+		{"byte string map key", []byte{0xa1, 0x41, 0x00, 0x01}},
+		{"half float", []byte{0xf9, 0x3c, 0x00}},
+		{"integer out of int64 range", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"nesting too deep", deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.data); err == nil {
+				t.Error("данные приняты")
+			}
+		})
+	}
+}
+
+func TestDecodeCBORMap(t *testing.T) {
+	m, err := decodeCBORMap(cborMap(cborInt(-1), cborBytes([]byte{1, 2}), cborString("fmt"), cborString("none")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := m[int64(-1)].([]byte); !bytes.Equal(b, []byte{1, 2}) {
+		t.Errorf("m[-1] = %v", m[int64(-1)])
+	}
+	if s, _ := m["fmt"].(string); s != "none" {
+		t.Errorf("m[fmt] = %v", m["fmt"])
+	}
+
+	if _, err := decodeCBORMap(append(cborMap(), 0x00)); err == nil {
+		t.Error("лишние данные после map приняты")
+	}
+	if _, err := decodeCBORMap(cborInt(1)); err == nil {
+		t.Error("не-map принят")
+	}
+}
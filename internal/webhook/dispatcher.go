@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hh/config"
+	"hh/internal/repository"
+	"hh/pkg/webhooksig"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	batchSize = 10
+	// deliveryTimeout ограничивает одну доставку, если у httpClient нет своего Timeout
+	deliveryTimeout = 10 * time.Second
+	// leaseMargin — запас аренды на запись результатов в БД
+	leaseMargin = 30 * time.Second
+)
+
+type Dispatcher struct {
+	repo       *repository.WebhookRepository
+	httpClient *http.Client
+	cfg        *config.Config
+
+	// timeout — предел одной доставки; lease — аренда пачки, которая переживает
+	// batchSize доставок подряд по timeout, иначе событие заберёт и отправит второй раз
+	// другой инстанс, пока этот ещё не дошёл до него
+	timeout time.Duration
+	lease   time.Duration
+}
+
+func NewDispatcher(repo *repository.WebhookRepository, httpClient *http.Client, cfg *config.Config) *Dispatcher {
+	timeout := deliveryTimeout
+	if httpClient.Timeout > 0 {
+		timeout = httpClient.Timeout
+	}
+
+	return &Dispatcher{
+		repo:       repo,
+		httpClient: httpClient,
+		cfg:        cfg,
+		timeout:    timeout,
+		lease:      batchSize*timeout + leaseMargin,
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.WebhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.dispatchBatch(ctx); err != nil {
+			log.Println("webhook dispatcher:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchBatch(ctx context.Context) error {
+	deliveries, err := d.repo.ClaimDue(ctx, batchSize, d.lease)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		event := delivery.Event
+
+		switch {
+		case !delivery.Found:
+			err = d.repo.MarkFailed(ctx, event.ID, "подписка не найдена", time.Now(), true)
+		case !delivery.Active:
+			err = d.repo.MarkFailed(ctx, event.ID, "подписка отключена", time.Now(), true)
+		default:
+			deliveryErr := d.deliver(ctx, delivery)
+			if deliveryErr == nil {
+				err = d.repo.MarkDelivered(ctx, event.ID)
+			} else {
+				attempt := event.Attempts + 1
+				dead := attempt >= d.cfg.WebhookMaxAttempts
+				err = d.repo.MarkFailed(ctx, event.ID, deliveryErr.Error(), time.Now().Add(d.backoff(attempt)), dead)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery repository.WebhookDelivery) error {
+	event := delivery.Event
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	body, header, err := render(delivery.Format, d.cfg.PublicURL, event)
+	if err != nil {
+		return fmt.Errorf("не удалось собрать тело webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		delivery.URL,
+		bytes.NewBuffer(body),
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось создать webhook запрос")
+	}
+
+	req.Header = header
+	req.Header.Set(webhooksig.EventTypeHeader, event.EventType)
+	for name, value := range event.Headers {
+		req.Header.Set(name, value)
+	}
+
+	deliveryID := event.ID.String()
+	req.Header.Set(webhooksig.DeliveryIDHeader, deliveryID)
+	if delivery.Secret != "" {
+		// тип события, ce-* binary режима CloudEvents и заголовки самого события, например
+		// X-Old-IP, подписываются вместе с телом
+		names := webhooksig.SignedHeaderNames(req.Header)
+		for name := range event.Headers {
+			names = append(names, name)
+		}
+		req.Header.Set(webhooksig.SignatureHeader, webhooksig.SignWithHeaders([]byte(delivery.Secret), time.Now(), deliveryID, req.Header, names, body))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка отправки webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook вернул статус %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// backoff растёт экспоненциально от WEBHOOK_RETRY_BASE до WEBHOOK_RETRY_MAX,
+// половина задержки случайная, чтобы повторы разных событий не шли пачкой.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.WebhookRetryBase
+	for i := 1; i < attempt && delay < d.cfg.WebhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.WebhookRetryMax {
+		delay = d.cfg.WebhookRetryMax
+	}
+
+	half := delay / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
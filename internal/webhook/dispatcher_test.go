@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"hh/config"
+	"hh/internal/model"
+	"hh/internal/repository"
+	"hh/pkg/webhooksig"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: &config.Config{WebhookRetryBase: time.Second, WebhookRetryMax: time.Minute}}
+
+	tests := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{6, 32 * time.Second},
+		{7, time.Minute},
+		{100, time.Minute},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			got := d.backoff(tt.attempt)
+			if got < tt.delay/2 || got > tt.delay {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.delay/2, tt.delay)
+			}
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"delivered", http.StatusNoContent, false},
+		{"client error", http.StatusBadRequest, true},
+		{"server error", http.StatusServiceUnavailable, true},
+		{"redirect is not delivery", http.StatusFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotType, gotCustom string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotType = r.Header.Get("X-Event-Type")
+				gotCustom = r.Header.Get("X-Custom")
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			d := NewDispatcher(nil, client, &config.Config{})
+
+			err := d.deliver(context.Background(), repository.WebhookDelivery{
+				URL: server.URL,
+				Event: model.WebhookOutboxEvent{
+					EventType: "token.revoked",
+					Payload:   []byte(`{}`),
+					Headers:   map[string]string{"X-Custom": "value"},
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deliver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotType != "token.revoked" || gotCustom != "value" {
+				t.Errorf("headers X-Event-Type=%q X-Custom=%q", gotType, gotCustom)
+			}
+		})
+	}
+}
+
+// Получатель проверяет подпись через webhooksig в любом формате: тип события, X-Old-IP
+// и ce-* binary режима подписаны вместе с телом.
+func TestDeliverSigned(t *testing.T) {
+	secret := []byte("whsec_test")
+
+	payload, err := json.Marshal(testSecurityEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format     string
+		headers    map[string]string
+		wantSigned string
+	}{
+		{model.WebhookFormatLegacy, nil, "h=x-event-type,"},
+		{model.WebhookFormatLegacy, map[string]string{webhooksig.OldIPHeader: "203.0.113.7"}, "h=x-event-type x-old-ip,"},
+		{model.WebhookFormatCloudEventsStructured, nil, "h=x-event-type,"},
+		{model.WebhookFormatCloudEventsBinary, nil, " x-event-type,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var signature string
+			var verifyErr error
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				signature = r.Header.Get(webhooksig.SignatureHeader)
+				_, _, verifyErr = webhooksig.VerifyRequest(r, secret, time.Minute)
+			}))
+			defer server.Close()
+
+			d := NewDispatcher(nil, http.DefaultClient, &config.Config{PublicURL: "https://auth.example.com"})
+			delivery := repository.WebhookDelivery{
+				URL:    server.URL,
+				Secret: string(secret),
+				Format: tt.format,
+				Event:  model.WebhookOutboxEvent{ID: uuid.New(), EventType: "security.ip_change", Payload: payload, Headers: tt.headers},
+			}
+
+			if err := d.deliver(context.Background(), delivery); err != nil {
+				t.Fatal(err)
+			}
+			if verifyErr != nil {
+				t.Errorf("receiver rejected the signature: %v", verifyErr)
+			}
+			if !strings.Contains(signature, tt.wantSigned) || !strings.Contains(signature, ",v2=") {
+				t.Errorf("%s = %q, want v2 signature over %q", webhooksig.SignatureHeader, signature, tt.wantSigned)
+			}
+		})
+	}
+}
+
+// Аренда пачки должна пережить batchSize доставок подряд, каждая до таймаута.
+func TestNewDispatcherLease(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *http.Client
+		wantTimeout time.Duration
+	}{
+		{"client without timeout", &http.Client{}, deliveryTimeout},
+		{"client timeout", &http.Client{Timeout: 3 * time.Second}, 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		d := NewDispatcher(nil, tt.client, &config.Config{})
+		if d.timeout != tt.wantTimeout {
+			t.Errorf("%s: timeout = %v, want %v", tt.name, d.timeout, tt.wantTimeout)
+		}
+		if d.lease < batchSize*d.timeout+leaseMargin {
+			t.Errorf("%s: lease %v is shorter than a batch of deliveries", tt.name, d.lease)
+		}
+	}
+}
+
+func TestDeliverTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	d := NewDispatcher(nil, &http.Client{}, &config.Config{})
+	d.timeout = 50 * time.Millisecond
+
+	err := d.deliver(context.Background(), repository.WebhookDelivery{
+		URL:   server.URL,
+		Event: model.WebhookOutboxEvent{ID: uuid.New(), Payload: []byte(`{}`)},
+	})
+	if err == nil {
+		t.Error("deliver() did not time out")
+	}
+}
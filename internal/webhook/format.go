@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"encoding/json"
+	"hh/internal/model"
+	"net/http"
+	"time"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsTypePrefix  = "ru.hh.auth.security."
+)
+
+// cloudEvent — конверт CloudEvents 1.0 в structured режиме, data — сам SecurityEvent.
+type cloudEvent struct {
+	SpecVersion     string              `json:"specversion"`
+	ID              string              `json:"id"`
+	Source          string              `json:"source"`
+	Type            string              `json:"type"`
+	Subject         string              `json:"subject"`
+	Time            string              `json:"time"`
+	DataContentType string              `json:"datacontenttype"`
+	Data            model.SecurityEvent `json:"data"`
+}
+
+// render собирает тело и заголовки webhook в формате подписки. События, поставленные
+// в outbox до появления SecurityEvent, уже лежат в формате WebhookPayload и отправляются как есть.
+func render(format, source string, event model.WebhookOutboxEvent) ([]byte, http.Header, error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	var securityEvent model.SecurityEvent
+	if err := json.Unmarshal(event.Payload, &securityEvent); err != nil || securityEvent.Type == "" {
+		return event.Payload, header, nil
+	}
+
+	switch format {
+	case model.WebhookFormatCloudEventsStructured:
+		body, err := json.Marshal(cloudEvent{
+			SpecVersion:     cloudEventsSpecVersion,
+			ID:              securityEvent.ID.String(),
+			Source:          source,
+			Type:            cloudEventsTypePrefix + securityEvent.Type,
+			Subject:         securityEvent.UserID.String(),
+			Time:            securityEvent.CreatedAt.UTC().Format(time.RFC3339Nano),
+			DataContentType: "application/json",
+			Data:            securityEvent,
+		})
+		header.Set("Content-Type", "application/cloudevents+json")
+
+		return body, header, err
+	case model.WebhookFormatCloudEventsBinary:
+		// атрибуты события в заголовках, dispatcher подписывает их вместе с телом (webhooksig v2)
+		body, err := json.Marshal(securityEvent)
+		header.Set("ce-specversion", cloudEventsSpecVersion)
+		header.Set("ce-id", securityEvent.ID.String())
+		header.Set("ce-source", source)
+		header.Set("ce-type", cloudEventsTypePrefix+securityEvent.Type)
+		header.Set("ce-subject", securityEvent.UserID.String())
+		header.Set("ce-time", securityEvent.CreatedAt.UTC().Format(time.RFC3339Nano))
+
+		return body, header, err
+	default:
+		body, err := json.Marshal(model.NewWebhookPayload(securityEvent))
+
+		return body, header, err
+	}
+}
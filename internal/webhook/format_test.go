@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"encoding/json"
+	"hh/internal/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testSecurityEvent() model.SecurityEvent {
+	sessionID := uuid.New()
+
+	return model.SecurityEvent{
+		ID:            uuid.New(),
+		SchemaVersion: model.SecurityEventSchemaVersion,
+		Type:          model.SecurityEventIPChange,
+		UserID:        uuid.New(),
+		SessionID:     &sessionID,
+		IPAddress:     "10.0.0.2",
+		UserAgent:     "curl",
+		Reason:        model.ReasonIPAddressChanged,
+		CreatedAt:     time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60)),
+	}
+}
+
+func TestRenderLegacy(t *testing.T) {
+	securityEvent := testSecurityEvent()
+	structured, err := json.Marshal(securityEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reuse := securityEvent
+	reuse.Type = model.SecurityEventTokenReuse
+	reuseJSON, err := json.Marshal(reuse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logout := securityEvent
+	logout.Type = model.SecurityEventLogout
+	logoutJSON, err := json.Marshal(logout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := []byte(`{"user_id":"u","ip_address":"10.0.0.2","user_agent":"curl","event":"ip_address_changed"}`)
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		wantEvent string
+	}{
+		// для старых получателей сохраняются прежние имена событий
+		{"ip change", structured, "ip_address_changed"},
+		{"token reuse", reuseJSON, "refresh_token_reused"},
+		{"new event type", logoutJSON, model.SecurityEventLogout},
+		{"legacy outbox row", legacy, "ip_address_changed"},
+	}
+
+	for _, tt := range tests {
+		body, header, err := render(model.WebhookFormatLegacy, "https://auth.example.com", model.WebhookOutboxEvent{Payload: tt.payload})
+		if err != nil {
+			t.Fatalf("%s: render() error = %v", tt.name, err)
+		}
+		if header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s: Content-Type = %q", tt.name, header.Get("Content-Type"))
+		}
+
+		var payload model.WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if payload.Event != tt.wantEvent || payload.IPAddress != "10.0.0.2" || payload.UserAgent != "curl" {
+			t.Errorf("%s: payload = %+v", tt.name, payload)
+		}
+	}
+
+	body, _, _ := render(model.WebhookFormatLegacy, "", model.WebhookOutboxEvent{Payload: structured})
+	var payload model.WebhookPayload
+	_ = json.Unmarshal(body, &payload)
+	if payload.EventID != securityEvent.ID || payload.SessionID == nil || *payload.SessionID != *securityEvent.SessionID ||
+		payload.UserID != securityEvent.UserID.String() || payload.Reason != model.ReasonIPAddressChanged || !payload.OccurredAt.Equal(securityEvent.CreatedAt) {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	// строки outbox в старом формате уходят как есть в любом формате подписки
+	for _, format := range []string{model.WebhookFormatLegacy, model.WebhookFormatCloudEventsStructured, model.WebhookFormatCloudEventsBinary} {
+		if body, _, _ := render(format, "", model.WebhookOutboxEvent{Payload: legacy}); string(body) != string(legacy) {
+			t.Errorf("%s: legacy payload rewritten: %s", format, body)
+		}
+	}
+}
+
+func TestRenderCloudEvents(t *testing.T) {
+	securityEvent := testSecurityEvent()
+	payload, err := json.Marshal(securityEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := model.WebhookOutboxEvent{Payload: payload}
+
+	wantType := "ru.hh.auth.security.ip_change"
+	wantTime := "2024-01-01T09:00:00Z"
+
+	t.Run("structured", func(t *testing.T) {
+		body, header, err := render(model.WebhookFormatCloudEventsStructured, "https://auth.example.com", event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Get("Content-Type") != "application/cloudevents+json" {
+			t.Errorf("Content-Type = %q", header.Get("Content-Type"))
+		}
+		if len(header.Values("ce-id")) != 0 {
+			t.Error("structured mode sent ce-* headers")
+		}
+
+		var got cloudEvent
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.SpecVersion != "1.0" || got.ID != securityEvent.ID.String() || got.Source != "https://auth.example.com" ||
+			got.Type != wantType || got.Subject != securityEvent.UserID.String() || got.Time != wantTime || got.DataContentType != "application/json" {
+			t.Errorf("envelope = %+v", got)
+		}
+		if got.Data.ID != securityEvent.ID || got.Data.Reason != securityEvent.Reason || got.Data.SchemaVersion != model.SecurityEventSchemaVersion {
+			t.Errorf("data = %+v", got.Data)
+		}
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		body, header, err := render(model.WebhookFormatCloudEventsBinary, "https://auth.example.com", event)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]string{
+			"Content-Type":   "application/json",
+			"ce-specversion": "1.0",
+			"ce-id":          securityEvent.ID.String(),
+			"ce-source":      "https://auth.example.com",
+			"ce-type":        wantType,
+			"ce-subject":     securityEvent.UserID.String(),
+			"ce-time":        wantTime,
+		}
+		for name, value := range want {
+			if got := header.Get(name); got != value {
+				t.Errorf("%s = %q, want %q", name, got, value)
+			}
+		}
+
+		var got model.SecurityEvent
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != securityEvent.ID || got.Type != securityEvent.Type || got.IPAddress != securityEvent.IPAddress {
+			t.Errorf("body = %+v", got)
+		}
+	})
+}
@@ -0,0 +1,5 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP NOT NULL DEFAULT now();
+
+UPDATE refresh_tokens SET last_used_at = created_at WHERE created_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_active_idx ON refresh_tokens (user_id) WHERE revoked = false;
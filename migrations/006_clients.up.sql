@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS clients (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    secret_hash TEXT,
+    public_key TEXT,
+    trusted BOOLEAN NOT NULL DEFAULT FALSE,
+    allowed_subjects UUID[] NOT NULL DEFAULT '{}',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK (secret_hash IS NOT NULL OR public_key IS NOT NULL)
+);
+
+CREATE TABLE IF NOT EXISTS client_assertion_jtis (
+    client_id TEXT NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+    jti TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (client_id, jti)
+);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS client_id TEXT REFERENCES clients (id);
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS scope TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_expires_at TIMESTAMP;
+
+UPDATE refresh_tokens t
+SET session_expires_at = (SELECT min(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id) + interval '30 days'
+WHERE session_expires_at IS NULL;
+
+UPDATE refresh_tokens
+SET expires_at = LEAST(created_at + interval '24 hours', session_expires_at)
+WHERE expires_at IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN expires_at SET NOT NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN session_expires_at SET NOT NULL;
+
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS access_token_ttl_seconds INTEGER CHECK (access_token_ttl_seconds > 0);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS refresh_idle_timeout_seconds INTEGER CHECK (refresh_idle_timeout_seconds > 0);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS session_max_lifetime_seconds INTEGER CHECK (session_max_lifetime_seconds > 0);
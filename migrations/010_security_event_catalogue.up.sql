@@ -0,0 +1,6 @@
+ALTER TABLE security_events ADD COLUMN IF NOT EXISTS client_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE security_events ADD COLUMN IF NOT EXISTS previous_ip_address TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS security_events_type_idx ON security_events (event_type, created_at);
+
+UPDATE security_events SET event_type = 'token_reuse', reason = 'rotated_token_reused' WHERE event_type = 'refresh_token_reused';
@@ -0,0 +1,2 @@
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS format TEXT NOT NULL DEFAULT 'legacy'
+    CHECK (format IN ('legacy', 'cloudevents-structured', 'cloudevents-binary'));
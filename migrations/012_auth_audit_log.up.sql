@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS auth_audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    occurred_at TIMESTAMP NOT NULL DEFAULT now(),
+    action TEXT NOT NULL,
+    actor_type TEXT NOT NULL,
+    actor_id TEXT NOT NULL DEFAULT '',
+    subject_id UUID,
+    session_id UUID,
+    client_id TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL CHECK (outcome IN ('success', 'failure')),
+    reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS auth_audit_log_occurred_idx ON auth_audit_log (occurred_at);
+CREATE INDEX IF NOT EXISTS auth_audit_log_subject_idx ON auth_audit_log (subject_id, occurred_at);
+CREATE INDEX IF NOT EXISTS auth_audit_log_session_idx ON auth_audit_log (session_id);
+
+CREATE OR REPLACE FUNCTION auth_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'auth_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS auth_audit_log_no_modify ON auth_audit_log;
+CREATE TRIGGER auth_audit_log_no_modify
+    BEFORE UPDATE OR DELETE ON auth_audit_log
+    FOR EACH ROW EXECUTE FUNCTION auth_audit_log_append_only();
+
+DROP TRIGGER IF EXISTS auth_audit_log_no_truncate ON auth_audit_log;
+CREATE TRIGGER auth_audit_log_no_truncate
+    BEFORE TRUNCATE ON auth_audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION auth_audit_log_append_only();
@@ -0,0 +1,17 @@
+ALTER TABLE auth_audit_log ADD COLUMN IF NOT EXISTS seq BIGINT GENERATED ALWAYS AS IDENTITY;
+ALTER TABLE auth_audit_log ADD COLUMN IF NOT EXISTS prev_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE auth_audit_log ADD COLUMN IF NOT EXISTS hash TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS auth_audit_log_seq_idx ON auth_audit_log (seq);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    seq BIGINT PRIMARY KEY,
+    hash TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+DROP TRIGGER IF EXISTS audit_checkpoints_no_modify ON audit_checkpoints;
+CREATE TRIGGER audit_checkpoints_no_modify
+    BEFORE UPDATE OR DELETE ON audit_checkpoints
+    FOR EACH ROW EXECUTE FUNCTION auth_audit_log_append_only();
@@ -0,0 +1,3 @@
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS binding_policy JSONB;
+
+ALTER TABLE security_events ADD COLUMN IF NOT EXISTS action TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,18 @@
+-- записи аудита из бизнес-транзакций сначала попадают сюда, без блокировки цепочки;
+-- в auth_audit_log их короткими транзакциями переносит writer цепочки
+CREATE TABLE IF NOT EXISTS auth_audit_pending (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    id UUID NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    action TEXT NOT NULL,
+    actor_type TEXT NOT NULL,
+    actor_id TEXT NOT NULL DEFAULT '',
+    subject_id UUID,
+    session_id UUID,
+    client_id TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL CHECK (outcome IN ('success', 'failure')),
+    reason TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,3 @@
+-- время входа пользователя в сессию (claim auth_time, OIDC Core §2): переносится в каждый
+-- следующий токен семьи, в отличие от iat, который обновляется при каждом /refresh
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP;
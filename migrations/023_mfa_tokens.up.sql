@@ -0,0 +1,6 @@
+-- mfa_token подписан и не хранится до использования; здесь запоминаются использованные,
+-- чтобы после второго шага входа тот же mfa_token нельзя было обменять ещё раз
+CREATE TABLE IF NOT EXISTS mfa_token_uses (
+    jti TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
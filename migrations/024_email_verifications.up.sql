@@ -0,0 +1,14 @@
+-- ссылки подтверждения email: одноразовые, как и ссылки сброса пароля, хранятся как selector
+-- и хэш verifier и не зависят от ключей подписи JWT
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    selector TEXT NOT NULL UNIQUE,
+    verifier_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS email_verifications_user_idx ON email_verifications (user_id) WHERE used_at IS NULL;
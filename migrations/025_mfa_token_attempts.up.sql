@@ -0,0 +1,6 @@
+-- неверные коды второго шага считаются на каждый mfa_token: после нескольких неудач токен
+-- сгорает. Строка появляется при первой неудаче или при обмене, used отличает одно от другого;
+-- уже записанные строки — обменянные токены
+ALTER TABLE mfa_token_uses ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE mfa_token_uses ADD COLUMN IF NOT EXISTS used BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE mfa_token_uses ALTER COLUMN used SET DEFAULT false;
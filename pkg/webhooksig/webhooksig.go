@@ -0,0 +1,252 @@
+// Package webhooksig подписывает и проверяет webhook-и auth-service.
+//
+// Каждая доставка несёт заголовки
+//
+//	X-Delivery-ID: <uuid события, одинаковый для всех повторов>
+//	X-Event-Type:  <тип события>
+//	X-Signature:   t=<unix time>,h=<имена заголовков через пробел>,v2=<hex HMAC-SHA256>
+//
+// Заголовки, которые описывают событие, подписываются вместе с телом: X-Event-Type,
+// X-Old-IP у смены IP и ce-* у binary режима CloudEvents. v2 считается секретом подписчика
+// от "v2\n<t>\n<delivery id>\n", строк "<имя>:<значение>\n" для каждого заголовка из h,
+// пустой строки и тела. Запрос с такими заголовками, которые не вошли в подпись, не проходит
+// проверку, поэтому подменить тип или атрибуты события нельзя.
+//
+// Получатель проверяет подпись и возраст t через VerifyRequest или VerifyWithHeaders, а
+// повторы отсекает по X-Delivery-ID, например через ReplayGuard.
+//
+// Подпись v1 — t=<unix time>,v1=<hex HMAC-SHA256> от "<t>.<delivery id>.<тело запроса>" —
+// покрывает только тело и принимается лишь для запросов без подписываемых заголовков.
+package webhooksig
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader  = "X-Signature"
+	DeliveryIDHeader = "X-Delivery-ID"
+	EventTypeHeader  = "X-Event-Type"
+	OldIPHeader      = "X-Old-IP"
+
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrMissingSignature = errors.New("webhooksig: нет подписи")
+	ErrInvalidHeader    = errors.New("webhooksig: неверный формат X-Signature")
+	ErrSignatureExpired = errors.New("webhooksig: подпись устарела")
+	ErrInvalidSignature = errors.New("webhooksig: подпись не совпадает")
+	ErrReplayed         = errors.New("webhooksig: доставка уже обработана")
+	ErrUnsignedHeader   = errors.New("webhooksig: заголовок события не входит в подпись")
+)
+
+// signedHeaderPrefix — заголовки с этим префиксом обязаны входить в подпись.
+const signedHeaderPrefix = "ce-"
+
+// signedHeaders — заголовки события без префикса ce-, которые тоже обязаны входить в подпись.
+var signedHeaders = []string{"x-event-type", "x-old-ip"}
+
+// Sign возвращает значение заголовка X-Signature.
+func Sign(secret []byte, timestamp time.Time, deliveryID string, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac(secret, t, deliveryID, body))
+}
+
+// SignWithHeaders возвращает значение X-Signature, которое покрывает и заголовки names.
+func SignWithHeaders(secret []byte, timestamp time.Time, deliveryID string, header http.Header, names []string, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	names = canonicalNames(names)
+
+	return "t=" + t + ",h=" + strings.Join(names, " ") + ",v2=" + hex.EncodeToString(macWithHeaders(secret, t, deliveryID, header, names, body))
+}
+
+// SignedHeaderNames возвращает имена заголовков события из header, которые нужно подписать.
+func SignedHeaderNames(header http.Header) []string {
+	var names []string
+	for name := range header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, signedHeaderPrefix) || slices.Contains(signedHeaders, lower) {
+			names = append(names, name)
+		}
+	}
+
+	return canonicalNames(names)
+}
+
+// Verify проверяет X-Signature по одному телу. Подходит любая из v1 подписей, поэтому при
+// ротации секрета отправитель может временно подписывать двумя ключами. Доставки
+// auth-service подписаны вместе с заголовками и проверяются через VerifyWithHeaders
+// или VerifyRequest.
+func Verify(secret []byte, signatureHeader, deliveryID string, body []byte, tolerance time.Duration) error {
+	return VerifyWithHeaders(secret, signatureHeader, deliveryID, nil, body, tolerance)
+}
+
+// VerifyWithHeaders проверяет X-Signature вместе с заголовками запроса. Если в header есть
+// заголовки события (см. SignedHeaderNames), все они должны входить в подпись v2; подписи v1
+// для такого запроса мало.
+func VerifyWithHeaders(secret []byte, signatureHeader, deliveryID string, header http.Header, body []byte, tolerance time.Duration) error {
+	if signatureHeader == "" {
+		return ErrMissingSignature
+	}
+
+	var t string
+	var names []string
+	var v1, v2 [][]byte
+
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidHeader
+		}
+
+		switch key {
+		case "t":
+			t = value
+		case "h":
+			names = canonicalNames(strings.Fields(value))
+		case "v1", "v2":
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrInvalidHeader
+			}
+			if key == "v1" {
+				v1 = append(v1, sig)
+			} else {
+				v2 = append(v2, sig)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(v1)+len(v2) == 0 {
+		return ErrInvalidHeader
+	}
+
+	age := time.Since(time.Unix(unix, 0))
+	if tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return ErrSignatureExpired
+	}
+
+	required := SignedHeaderNames(header)
+	for _, name := range required {
+		if len(v2) == 0 || !slices.Contains(names, name) {
+			return ErrUnsignedHeader
+		}
+	}
+
+	if len(v2) > 0 {
+		expected := macWithHeaders(secret, t, deliveryID, header, names, body)
+		for _, sig := range v2 {
+			if hmac.Equal(sig, expected) {
+				return nil
+			}
+		}
+	}
+
+	if len(required) == 0 {
+		expected := mac(secret, t, deliveryID, body)
+		for _, sig := range v1 {
+			if hmac.Equal(sig, expected) {
+				return nil
+			}
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// VerifyRequest читает тело запроса, проверяет подпись вместе с заголовками события и
+// возвращает тело и delivery id. После вызова r.Body можно читать повторно.
+func VerifyRequest(r *http.Request, secret []byte, tolerance time.Duration) ([]byte, string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	deliveryID := r.Header.Get(DeliveryIDHeader)
+	if err := VerifyWithHeaders(secret, r.Header.Get(SignatureHeader), deliveryID, r.Header, body, tolerance); err != nil {
+		return nil, "", err
+	}
+
+	return body, deliveryID, nil
+}
+
+func mac(secret []byte, t, deliveryID string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write([]byte(deliveryID))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
+
+func macWithHeaders(secret []byte, t, deliveryID string, header http.Header, names []string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte("v2\n" + t + "\n" + deliveryID + "\n"))
+	for _, name := range names {
+		h.Write([]byte(name + ":" + strings.Join(header.Values(name), ",") + "\n"))
+	}
+	h.Write([]byte("\n"))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
+
+// canonicalNames приводит имена заголовков к нижнему регистру, сортирует и убирает повторы:
+// порядок в h не должен влиять на подпись.
+func canonicalNames(names []string) []string {
+	canonical := make([]string, 0, len(names))
+	for _, name := range names {
+		canonical = append(canonical, strings.ToLower(name))
+	}
+	slices.Sort(canonical)
+
+	return slices.Compact(canonical)
+}
+
+// ReplayGuard помнит delivery id в течение ttl (обычно равного tolerance) в памяти процесса.
+type ReplayGuard struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+func NewReplayGuard(ttl time.Duration) *ReplayGuard {
+	return &ReplayGuard{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// Check возвращает ErrReplayed, если deliveryID уже встречался за последние ttl.
+func (g *ReplayGuard) Check(deliveryID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for id, expiresAt := range g.seen {
+		if now.After(expiresAt) {
+			delete(g.seen, id)
+		}
+	}
+
+	if _, ok := g.seen[deliveryID]; ok {
+		return ErrReplayed
+	}
+
+	g.seen[deliveryID] = now.Add(g.ttl)
+
+	return nil
+}
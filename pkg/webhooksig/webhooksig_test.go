@@ -0,0 +1,196 @@
+package webhooksig
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	secret     = []byte("whsec_test")
+	deliveryID = "5f0c6c1e-8a4b-4d3a-9b1e-2f6a7c8d9e0f"
+	body       = []byte(`{"type":"password_changed"}`)
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	valid := Sign(secret, now, deliveryID, body)
+
+	tests := []struct {
+		name       string
+		header     string
+		deliveryID string
+		body       []byte
+		tolerance  time.Duration
+		want       error
+	}{
+		{"valid", valid, deliveryID, body, time.Minute, nil},
+		{"without tolerance", Sign(secret, now.Add(-time.Hour), deliveryID, body), deliveryID, body, 0, nil},
+		{"rotated secret", Sign([]byte("old"), now, deliveryID, body) + "," + strings.Split(valid, ",")[1], deliveryID, body, time.Minute, nil},
+		{"missing", "", deliveryID, body, time.Minute, ErrMissingSignature},
+		{"no signature", strings.Split(valid, ",")[0], deliveryID, body, time.Minute, ErrInvalidHeader},
+		{"bad timestamp", "t=abc,v1=00", deliveryID, body, time.Minute, ErrInvalidHeader},
+		{"bad hex", strings.Split(valid, ",")[0] + ",v1=zz", deliveryID, body, time.Minute, ErrInvalidHeader},
+		{"no equals sign", "garbage", deliveryID, body, time.Minute, ErrInvalidHeader},
+		{"expired", Sign(secret, now.Add(-10*time.Minute), deliveryID, body), deliveryID, body, time.Minute, ErrSignatureExpired},
+		{"from the future", Sign(secret, now.Add(10*time.Minute), deliveryID, body), deliveryID, body, time.Minute, ErrSignatureExpired},
+		{"other secret", Sign([]byte("other"), now, deliveryID, body), deliveryID, body, time.Minute, ErrInvalidSignature},
+		{"other delivery", valid, "another-delivery", body, time.Minute, ErrInvalidSignature},
+		{"tampered body", valid, deliveryID, []byte(`{"type":"login"}`), time.Minute, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(secret, tt.header, tt.deliveryID, tt.body, tt.tolerance); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func cloudEventsHeader() http.Header {
+	header := http.Header{}
+	header.Set(EventTypeHeader, "security.password_changed")
+	header.Set("ce-specversion", "1.0")
+	header.Set("ce-id", deliveryID)
+	header.Set("ce-type", "com.example.password_changed")
+	header.Set("ce-subject", "0b9d2c4e-1f3a-4b5c-8d7e-6f5a4b3c2d1e")
+
+	return header
+}
+
+func TestVerifyWithHeaders(t *testing.T) {
+	now := time.Now()
+	header := cloudEventsHeader()
+	valid := SignWithHeaders(secret, now, deliveryID, header, SignedHeaderNames(header), body)
+
+	tests := []struct {
+		name   string
+		header func() http.Header
+		sig    string
+		want   error
+	}{
+		{"valid", cloudEventsHeader, valid, nil},
+		{"header name case", func() http.Header {
+			h := http.Header{}
+			for name, values := range cloudEventsHeader() {
+				h.Set(strings.ToUpper(name), values[0])
+			}
+			return h
+		}, valid, nil},
+		{"tampered header", func() http.Header {
+			h := cloudEventsHeader()
+			h.Set("ce-subject", "attacker")
+			return h
+		}, valid, ErrInvalidSignature},
+		{"duplicated header value", func() http.Header {
+			h := cloudEventsHeader()
+			h.Add("ce-type", "com.example.login")
+			return h
+		}, valid, ErrInvalidSignature},
+		{"removed header", func() http.Header {
+			h := cloudEventsHeader()
+			h.Del("ce-subject")
+			return h
+		}, valid, ErrInvalidSignature},
+		{"unsigned header added", func() http.Header {
+			h := cloudEventsHeader()
+			h.Set("ce-dataschema", "https://attacker.example")
+			return h
+		}, valid, ErrUnsignedHeader},
+		{"header dropped from h", cloudEventsHeader, strings.Replace(valid, "ce-subject", "", 1), ErrUnsignedHeader},
+		{"v1 downgrade", cloudEventsHeader, Sign(secret, now, deliveryID, body), ErrUnsignedHeader},
+		{"other secret", cloudEventsHeader, SignWithHeaders([]byte("other"), now, deliveryID, header, SignedHeaderNames(header), body), ErrInvalidSignature},
+		{"tampered event type", func() http.Header {
+			h := cloudEventsHeader()
+			h.Set(EventTypeHeader, "security.login")
+			return h
+		}, valid, ErrInvalidSignature},
+		{"unsigned old ip", func() http.Header {
+			h := cloudEventsHeader()
+			h.Set(OldIPHeader, "203.0.113.7")
+			return h
+		}, valid, ErrUnsignedHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyWithHeaders(secret, tt.sig, deliveryID, tt.header(), body, time.Minute); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyWithHeaders() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// без заголовков подпись v2 не проверить
+	if err := Verify(secret, valid, deliveryID, body, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	header := cloudEventsHeader()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(body)))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set(DeliveryIDHeader, deliveryID)
+	req.Header.Set(SignatureHeader, SignWithHeaders(secret, time.Now(), deliveryID, header, SignedHeaderNames(header), body))
+
+	got, gotID, err := VerifyRequest(req, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("VerifyRequest() error = %v", err)
+	}
+	if string(got) != string(body) || gotID != deliveryID {
+		t.Errorf("VerifyRequest() = %q, %q", got, gotID)
+	}
+
+	// тело можно прочитать повторно
+	again := make([]byte, len(body))
+	if n, _ := req.Body.Read(again); n != len(body) {
+		t.Errorf("body read again: %d bytes, want %d", n, len(body))
+	}
+}
+
+func TestSignedHeaderNames(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Ce-Type", "x")
+	header.Set("ce-id", "y")
+	header.Set("X-Delivery-ID", "z")
+	header.Set(EventTypeHeader, "security.ip_change")
+	header.Set(OldIPHeader, "203.0.113.7")
+
+	want := "ce-id ce-type x-event-type x-old-ip"
+	if got := strings.Join(SignedHeaderNames(header), " "); got != want {
+		t.Errorf("SignedHeaderNames() = %q, want %q", got, want)
+	}
+}
+
+// Тело с подписью v1 не защищает тип события: такой запрос не проходит проверку.
+func TestVerifyWithHeadersRejectsV1ForEventType(t *testing.T) {
+	header := http.Header{}
+	header.Set(EventTypeHeader, "security.password_changed")
+
+	err := VerifyWithHeaders(secret, Sign(secret, time.Now(), deliveryID, body), deliveryID, header, body, time.Minute)
+	if !errors.Is(err, ErrUnsignedHeader) {
+		t.Errorf("VerifyWithHeaders() = %v, want %v", err, ErrUnsignedHeader)
+	}
+}
+
+func TestReplayGuard(t *testing.T) {
+	guard := NewReplayGuard(time.Minute)
+
+	if err := guard.Check(deliveryID); err != nil {
+		t.Fatalf("first Check() = %v", err)
+	}
+	if err := guard.Check(deliveryID); !errors.Is(err, ErrReplayed) {
+		t.Errorf("second Check() = %v, want %v", err, ErrReplayed)
+	}
+	if err := guard.Check("another-delivery"); err != nil {
+		t.Errorf("Check() for another delivery = %v", err)
+	}
+}